@@ -26,16 +26,23 @@ func main() {
 		}
 	}
 
-	// Parse file path and optional line number
-	var filepath string
-	var line int
+	opts := app.Options{Version: version}
+	var files []string
+	for _, arg := range args {
+		if arg == "--no-session" {
+			opts.NoSession = true
+			continue
+		}
+		files = append(files, arg)
+	}
 
-	if len(args) > 0 {
-		filepath, line = parseFileArg(args[0])
+	// Parse file path and optional line number
+	if len(files) > 0 {
+		opts.File, opts.Line = parseFileArg(files[0])
 	}
 
 	// Run the editor
-	if err := app.RunWithVersion(filepath, line, version); err != nil {
+	if err := app.RunWithOptions(opts); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -62,7 +69,7 @@ func printHelp() {
 	help := `vex - A modern terminal text editor
 
 USAGE:
-    vex [FILE][:LINE]
+    vex [OPTIONS] [FILE][:LINE]
 
 ARGUMENTS:
     FILE        File to open (optional)
@@ -71,9 +78,12 @@ ARGUMENTS:
 OPTIONS:
     -h, --help      Show this help message
     -v, --version   Show version information
+    --no-session    Neither restore nor save the session and untitled
+                    tabs of this directory
 
 EXAMPLES:
-    vex                 Open editor with empty buffer
+    vex                 Open editor, restoring the last session
+    vex --no-session    Open editor with empty buffer
     vex file.go         Open file.go
     vex file.go:42      Open file.go at line 42
 
@@ -83,34 +93,51 @@ KEYBINDINGS:
         Ctrl+N          New file
         Ctrl+O          Open file
         Ctrl+W          Close file
+        Alt+R/Alt+C     Reload/compare with file on disk
         Ctrl+Q          Quit
 
     Editing:
         Ctrl+Z          Undo
         Ctrl+Y          Redo
+        Alt+Z/Alt+Y     Older/newer undo state
         Ctrl+X          Cut
         Ctrl+C          Copy
         Ctrl+V          Paste
         Ctrl+A          Select all
         Ctrl+D          Duplicate line
         Ctrl+L          Delete line
+        Ctrl+K Ctrl+L   Select line
         Alt+Up/Down     Move line up/down
+        Tab/Shift+Tab   Indent/outdent selected lines
+        Ctrl+Alt+Up/Down Add cursor above/below
+        Alt+D           Add next occurrence
+        Ctrl+Space      Show completions
 
     Navigation:
         Ctrl+G          Go to line
         Ctrl+Home       Go to start of file
         Ctrl+End        Go to end of file
         Ctrl+Left/Right Move by word
+        F12             Go to definition
+        Shift+F12       Find references (also Alt+F12)
+        Ctrl+R          Go to symbol in file
+        Alt+Left/Right  Go back/forward
 
     Search:
         Ctrl+F          Find
         Ctrl+H          Find and replace
         F3              Find next
         Shift+F3        Find previous
+        Alt+F           Find in files
+        Alt+H           Replace in files
 
     View:
         Ctrl+B          Toggle sidebar
-        Ctrl+P          Command palette
+        Ctrl+P          Quick open (go to file)
+        F1              Command palette
+        Ctrl+K I        Show hover information
+        Ctrl+K M        Show problems
+        Ctrl+K U        Show undo tree
 
 For more information, visit: https://github.com/DDZ-DO/vex
 `
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,7 +13,12 @@ import (
 	"github.com/DDZ-DO/vex/internal/config"
 	"github.com/DDZ-DO/vex/internal/editor"
 	"github.com/DDZ-DO/vex/internal/keybindings"
+	"github.com/DDZ-DO/vex/internal/lint"
+	"github.com/DDZ-DO/vex/internal/swap"
+	"github.com/DDZ-DO/vex/internal/syntax"
+	"github.com/DDZ-DO/vex/internal/tags"
 	"github.com/DDZ-DO/vex/internal/ui"
+	"github.com/DDZ-DO/vex/internal/workspace"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"golang.design/x/clipboard"
@@ -25,6 +32,7 @@ const (
 	FocusSidebar
 	FocusCommandPalette
 	FocusSearchBar
+	FocusResults
 )
 
 // App is the root model that orchestrates all components.
@@ -37,20 +45,59 @@ type App struct {
 	sidebar        *ui.Sidebar
 	commandPalette *ui.CommandPalette
 	searchBar      *ui.SearchBar
+	resultsPanel   *ui.ResultsPanel
+	completion     *ui.CompletionPopup
 
 	// Configuration
 	config      *config.Config
 	keyBindings *keybindings.KeyBindings
 
 	// State
-	focus           FocusArea
-	width           int
-	height          int
-	quitting        bool
-	pendingQuit     bool // True when waiting for quit confirmation
-	pendingCloseTab bool // True when waiting for close tab confirmation
-	message         string
-	messageTime     time.Time
+	focus            FocusArea
+	width            int
+	height           int
+	quitting         bool
+	pendingQuit      bool // True when waiting for quit confirmation
+	pendingCloseTab  bool // True when waiting for close tab confirmation
+	pendingOverwrite bool // True when waiting to save over a file changed on disk
+	message          string
+	messageTime      time.Time
+
+	// Running find in files
+	findID        int
+	findRoot      string
+	findResults   <-chan workspace.FileResult
+	findCancel    context.CancelFunc
+	findTruncated bool
+
+	// Running file index of quick open
+	indexID     int
+	indexRoot   string
+	indexCancel context.CancelFunc
+
+	// Linters by language ID and the latest run of each scope
+	linters  map[string]lint.Linter
+	lintRuns map[string]int
+
+	// Open completion popup: its language server request and candidates
+	completionSession   int
+	completionPending   bool
+	languageCompletions []ui.CompletionItem
+
+	// Tags file of the workspace, reloaded when it changes
+	tags tags.Cache
+
+	// Swap files of modified tabs, and the swap files listed for recovery
+	// by their ID (nil unless listed)
+	swaps    map[*editor.TabState]*tabSwap
+	recovery map[string]*swap.File
+
+	// Files changed on disk: the change last reported per tab, the modified
+	// tab waiting for a reload decision and the tab compared with its file
+	// in the results panel (nil unless listed)
+	diskSeen        map[*editor.TabState]string
+	pendingExternal *editor.TabState
+	compare         *editor.TabState
 
 	// Clipboard
 	clipboardInit bool
@@ -58,7 +105,7 @@ type App struct {
 
 // New creates a new App instance.
 func New() *App {
-	cfg, _ := config.Load()
+	cfg, cfgErr := config.Load()
 
 	app := &App{
 		editor:         editor.NewEditor(),
@@ -68,9 +115,13 @@ func New() *App {
 		sidebar:        ui.NewSidebar(),
 		commandPalette: ui.NewCommandPalette(),
 		searchBar:      ui.NewSearchBar(),
+		resultsPanel:   ui.NewResultsPanel(),
+		completion:     ui.NewCompletionPopup(),
 		config:         cfg,
 		keyBindings:    keybindings.NewKeyBindings(),
 		focus:          FocusEditor,
+		swaps:          make(map[*editor.TabState]*tabSwap),
+		diskSeen:       make(map[*editor.TabState]string),
 	}
 
 	// Initialize clipboard with panic recovery for headless systems
@@ -95,14 +146,65 @@ func New() *App {
 		}
 	}()
 
-	// Set initial sidebar visibility from config
-	if !cfg.ShowSidebar {
-		app.sidebar.Hide()
+	app.applyConfig()
+	app.loadKeyBindings()
+	app.loadLanguageServers()
+	app.loadLinters()
+	app.loadFormatters()
+	if cfgErr != nil {
+		app.showMessage("Config: "+cfgErr.Error(), ui.MessageError)
 	}
 
 	return app
 }
 
+// loadKeyBindings merges the user's keybindings.toml over the defaults and
+// refreshes the bindings shown in the command palette.
+func (a *App) loadKeyBindings() {
+	conflicts, errs := a.keyBindings.LoadUserBindings()
+
+	a.commandPalette.UpdateKeybindings(func(id string) string {
+		return a.keyBindings.GetBindingForAction(keybindings.Action(id))
+	})
+
+	switch {
+	case len(errs) > 0:
+		msg := "Keybindings: " + errs[0].Error()
+		if len(errs) > 1 {
+			msg += fmt.Sprintf(" (+%d more)", len(errs)-1)
+		}
+		a.showMessage(msg, ui.MessageError)
+	case len(conflicts) > 0:
+		msg := "Keybindings: " + conflicts[0].String()
+		if len(conflicts) > 1 {
+			msg += fmt.Sprintf(" (+%d more)", len(conflicts)-1)
+		}
+		a.showMessage(msg, ui.MessageWarning)
+	}
+}
+
+// applyConfig pushes the loaded configuration into the components.
+func (a *App) applyConfig() {
+	cfg := a.config
+
+	a.editor.SetTabWidth(cfg.TabWidth)
+	a.editor.SetInsertSpaces(cfg.InsertSpaces)
+	a.editor.SetShowLineNumbers(cfg.LineNumbers)
+	a.editor.SetWordWrap(cfg.WordWrap)
+	a.editor.SetTheme(syntax.ThemeByName(cfg.Theme))
+	a.editor.SetSaveOptions(cfg.TrimTrailingWhitespace, cfg.InsertFinalNewline)
+	undoDir := ""
+	if cfg.PersistentUndo {
+		undoDir, _ = config.UndoDir()
+	}
+	a.editor.TabManager().SetUndoDir(undoDir)
+
+	a.sidebar.SetWidth(cfg.SidebarWidth)
+	if !cfg.ShowSidebar {
+		a.sidebar.Hide()
+	}
+}
+
 // LoadFile loads a file into the editor.
 func (a *App) LoadFile(path string) error {
 	err := a.editor.LoadFile(path)
@@ -129,32 +231,126 @@ func (a *App) GoToLine(line int) {
 func (a *App) Init() tea.Cmd {
 	return tea.Batch(
 		tea.EnterAltScreen,
+		waitForLSPEvent(a.editor.LanguageServers().Events()),
+		swapTick(),
+		diskCheckTick(),
 	)
 }
 
 // Update implements tea.Model.
-func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (a *App) Update(msg tea.Msg) (_ tea.Model, cmd tea.Cmd) {
+	// Send edits, opened and closed files to the language servers, and
+	// format and lint saved files
+	defer func() {
+		a.editor.SyncDocuments()
+		if formatCmd := a.formatSavedFiles(); formatCmd != nil {
+			cmd = tea.Batch(cmd, formatCmd)
+		}
+		if lintCmd := a.lintSavedFiles(); lintCmd != nil {
+			cmd = tea.Batch(cmd, lintCmd)
+		}
+	}()
+
 	// Clear old messages
 	if a.message != "" && time.Since(a.messageTime) > 3*time.Second {
 		a.message = ""
 		a.statusBar.ClearMessage()
 	}
 
+	// Function keys Bubble Tea does not know, such as Shift+F12
+	if key, ok := keybindings.KeyFromMsg(msg); ok {
+		msg = key
+	}
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		a.handleResize(msg.Width, msg.Height)
 		return a, nil
 
 	case tea.KeyMsg:
-		return a.handleKeyPress(msg)
+		if handled, keyCmd := a.handleCompletionKey(msg); handled {
+			return a, keyCmd
+		}
+		session := a.completionSession
+		model, keyCmd := a.handleKeyPress(msg)
+		if a.completionSession == session {
+			// The key did not open or close the popup itself
+			keyCmd = tea.Batch(keyCmd, a.updateCompletion(msg))
+		}
+		return model, keyCmd
 
 	case tea.MouseMsg:
+		if msg.Action == tea.MouseActionPress && a.completion.IsVisible() {
+			a.closeCompletion()
+		}
 		return a.handleMouse(msg)
+
+	case findResultMsg:
+		return a, a.handleFindResult(msg)
+
+	case fileIndexMsg:
+		a.handleFileIndex(msg)
+		return a, nil
+
+	case lspEventMsg:
+		return a, a.handleLSPEvent(msg)
+
+	case hoverMsg:
+		a.handleHover(msg)
+		return a, nil
+
+	case definitionMsg:
+		a.handleDefinition(msg)
+		return a, nil
+
+	case completionMsg:
+		a.handleCompletion(msg)
+		return a, nil
+
+	case lintMsg:
+		a.handleLint(msg)
+		return a, nil
+
+	case formatMsg:
+		a.handleFormat(msg)
+		return a, nil
+
+	case swapTickMsg:
+		a.updateSwaps()
+		return a, swapTick()
+
+	case diskCheckMsg:
+		a.checkDisk()
+		return a, diskCheckTick()
+
+	case chordTimeoutMsg:
+		if a.keyBindings.ExpirePending() {
+			a.showMessage("Tastenkombination abgebrochen", ui.MessageInfo)
+		}
+		return a, nil
 	}
 
 	return a, nil
 }
 
+// chordTimeoutMsg is sent when a pending chord prefix may have timed out.
+type chordTimeoutMsg struct{}
+
+// handleChordState handles the chord sentinels returned by KeyBindings.Lookup.
+// Returns true if the key was consumed by the chord.
+func (a *App) handleChordState(action keybindings.Action) (bool, tea.Cmd) {
+	switch action {
+	case keybindings.ActionChordPending:
+		return true, tea.Tick(keybindings.ChordTimeout, func(time.Time) tea.Msg {
+			return chordTimeoutMsg{}
+		})
+	case keybindings.ActionChordAbort:
+		a.showMessage("Keine Aktion für diese Tastenkombination", ui.MessageWarning)
+		return true, nil
+	}
+	return false, nil
+}
+
 // handleResize handles window resize events.
 func (a *App) handleResize(width, height int) {
 	a.width = width
@@ -163,18 +359,24 @@ func (a *App) handleResize(width, height int) {
 	// Calculate component sizes
 	sidebarWidth := a.sidebar.Width() // Returns 0 when hidden
 	editorWidth := width - sidebarWidth
-	tabBarHeight := a.tabBar.Height()
-	editorHeight := height - 2 - tabBarHeight // title bar + status bar + tab bar
+	editorHeight := a.mainHeight() // Without title, tab and status bars
 
 	// Update component sizes
 	a.titleBar.SetWidth(width)
 	a.tabBar.SetWidth(width)
 	a.statusBar.SetWidth(width)
 	// Only update sidebar height, preserve width (SetSize only for height)
-	a.sidebar.SetHeight(height - 2 - tabBarHeight) // Exclude title, status, and tab bar
+	a.sidebar.SetHeight(editorHeight)
 	a.editor.SetSize(editorWidth, editorHeight)
 	a.commandPalette.SetSize(width, height)
 	a.searchBar.SetWidth(width)
+	a.resultsPanel.SetWidth(width)
+}
+
+// mainHeight returns the height of the sidebar and editor area, excluding
+// the title, tab and status bars and the results panel.
+func (a *App) mainHeight() int {
+	return a.height - 2 - a.tabBar.Height() - a.resultsPanel.Height()
 }
 
 // handleKeyPress handles keyboard input.
@@ -197,6 +399,30 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// Handle pending decision about a file changed on disk
+	if a.pendingExternal != nil && a.handleExternalKey(msg) {
+		return a, nil
+	}
+
+	// Handle pending overwrite confirmation
+	if a.pendingOverwrite {
+		switch a.keyBindings.LookupKey(msg) {
+		case keybindings.ActionSave:
+			a.overwrite()
+			return a, nil
+		case keybindings.ActionCompareWithDisk:
+			a.pendingOverwrite = false
+			a.compareWithDisk(a.editor.TabManager().ActiveTab())
+			return a, nil
+		default:
+			a.pendingOverwrite = false
+			a.showMessage("Speichern abgebrochen", ui.MessageInfo)
+			if msg.Type == tea.KeyEsc {
+				return a, nil
+			}
+		}
+	}
+
 	// Handle pending close tab confirmation
 	if a.pendingCloseTab {
 		switch msg.Type {
@@ -224,6 +450,11 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	// Handle Escape first - closes overlays and cancels pending actions
 	if msg.Type == tea.KeyEsc {
+		if a.keyBindings.IsPending() {
+			a.keyBindings.CancelPending()
+			a.showMessage("Tastenkombination abgebrochen", ui.MessageInfo)
+			return a, nil
+		}
 		if a.commandPalette.IsVisible() {
 			a.commandPalette.Hide()
 			a.focus = FocusEditor
@@ -235,7 +466,18 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			a.handleResize(a.width, a.height)
 			return a, nil
 		}
-		// Clear selection
+		if a.focus == FocusResults {
+			// Stop a running search first, then close the panel
+			if !a.cancelFind() {
+				a.closeResults()
+			}
+			return a, nil
+		}
+		// Drop extra cursors first, then the selection
+		if a.editor.HasMultipleCarets() {
+			a.editor.CollapseCarets()
+			return a, nil
+		}
 		a.editor.Selection().Clear()
 		return a, nil
 	}
@@ -255,8 +497,16 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return a.handleSidebarKey(msg)
 	}
 
+	// Handle results panel focus
+	if a.focus == FocusResults {
+		return a.handleResultsKey(msg)
+	}
+
 	// Look up keybinding
 	action := a.keyBindings.Lookup(msg)
+	if handled, cmd := a.handleChordState(action); handled {
+		return a, cmd
+	}
 
 	switch action {
 	// File operations
@@ -393,23 +643,19 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	// Search
 	case keybindings.ActionFind:
-		a.searchBar.Show()
-		a.focus = FocusSearchBar
-		a.handleResize(a.width, a.height)
+		a.showSearch(false)
 		return a, nil
 	case keybindings.ActionReplace:
-		a.searchBar.ShowReplace()
-		a.focus = FocusSearchBar
-		a.handleResize(a.width, a.height)
+		a.showSearch(true)
 		return a, nil
 	case keybindings.ActionFindNext:
-		if a.searchBar.SearchText() != "" {
-			a.editor.Find(a.searchBar.SearchText(), a.searchBar.IsCaseSensitive())
+		if search := a.searchQuery(); search != nil {
+			a.editor.Find(search)
 		}
 		return a, nil
 	case keybindings.ActionFindPrevious:
-		if a.searchBar.SearchText() != "" {
-			a.editor.FindPrevious(a.searchBar.SearchText(), a.searchBar.IsCaseSensitive())
+		if search := a.searchQuery(); search != nil {
+			a.editor.FindPrevious(search)
 		}
 		return a, nil
 
@@ -423,13 +669,13 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		a.handleResize(a.width, a.height)
 		return a, nil
 	case keybindings.ActionCommandPalette:
-		a.commandPalette.Show()
-		a.focus = FocusCommandPalette
+		a.showCommandPalette()
 		return a, nil
 	case keybindings.ActionFocusExplorer:
 		// Toggle focus between editor and explorer
 		if a.focus == FocusEditor {
 			// Show sidebar if hidden, then focus it
+			a.sidebar.SetMode(ui.SidebarExplorer)
 			if !a.sidebar.IsVisible() {
 				a.sidebar.Toggle()
 				a.handleResize(a.width, a.height)
@@ -455,6 +701,13 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case keybindings.ActionDelete:
 		a.editor.Delete()
 		return a, nil
+
+	case keybindings.ActionNone:
+		// Not bound, handled as text input below
+
+	default:
+		// Actions without a dedicated key handler run as palette commands
+		return a.executeCommand(string(action))
 	}
 
 	// Handle regular character input
@@ -471,18 +724,6 @@ func (a *App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return a, nil
 	}
 
-	// Handle Alt+Arrow for line movement
-	if msg.Alt {
-		switch msg.Type {
-		case tea.KeyUp:
-			a.editor.MoveLineUp()
-			return a, nil
-		case tea.KeyDown:
-			a.editor.MoveLineDown()
-			return a, nil
-		}
-	}
-
 	return a, nil
 }
 
@@ -494,6 +735,21 @@ func (a *App) handleCommandPaletteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case tea.KeyDown:
 		a.commandPalette.MoveDown()
 	case tea.KeyEnter:
+		if a.commandPalette.IsSymbolMode() {
+			if symbol, ok := a.commandPalette.SelectSymbol(); ok {
+				a.focus = FocusEditor
+				a.openSymbol(symbol)
+			}
+			return a, nil
+		}
+		if a.commandPalette.IsFileMode() {
+			root := a.commandPalette.FileRoot()
+			if file, ok := a.commandPalette.SelectFile(); ok {
+				a.focus = FocusEditor
+				a.openQuickOpenFile(root, file)
+			}
+			return a, nil
+		}
 		cmd := a.commandPalette.Select()
 		a.focus = FocusEditor
 		if cmd != nil {
@@ -513,8 +769,88 @@ func (a *App) handleCommandPaletteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return a, nil
 }
 
+// searchQuery compiles the search bar input. An invalid pattern is shown
+// inline in the search bar and returns nil, as does an empty search.
+func (a *App) searchQuery() *editor.Search {
+	text := a.searchBar.SearchText()
+	if text == "" {
+		a.searchBar.SetError("")
+		return nil
+	}
+
+	search, err := editor.NewSearch(text, editor.SearchOptions{
+		CaseSensitive: a.searchBar.IsCaseSensitive(),
+		WholeWord:     a.searchBar.IsWholeWord(),
+		Regex:         a.searchBar.IsRegex(),
+	})
+	if err != nil {
+		a.searchBar.SetError(strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+		return nil
+	}
+	a.searchBar.SetError("")
+	return search
+}
+
+// showSearch opens the search bar in find or replace mode and highlights
+// the matches of the previous search text.
+func (a *App) showSearch(replace bool) {
+	if replace {
+		a.searchBar.ShowReplace()
+	} else {
+		a.searchBar.Show()
+	}
+	a.focus = FocusSearchBar
+	a.handleResize(a.width, a.height)
+	a.editor.SetSearchHighlight(a.searchQuery())
+}
+
+// updateSearch recompiles the search after its text or options changed,
+// highlights the matches and selects the nearest one.
+func (a *App) updateSearch() {
+	search := a.searchQuery()
+	a.editor.SetSearchHighlight(search)
+	if search != nil {
+		a.editor.FindIncremental(search)
+	}
+}
+
 // handleSearchBarKey handles key input when search bar is focused.
 func (a *App) handleSearchBarKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	mode := a.searchBar.Mode()
+	searching := mode == ui.SearchModeFind || mode == ui.SearchModeReplace
+
+	// Search options: Alt+C case, Alt+W whole word, Alt+R regex
+	inFiles := mode == ui.SearchModeFindInFiles || mode == ui.SearchModeReplaceInFiles
+	if (searching || inFiles) && msg.Alt && msg.Type == tea.KeyRunes {
+		switch string(msg.Runes) {
+		case "c":
+			a.searchBar.ToggleCaseSensitive()
+		case "w":
+			a.searchBar.ToggleWholeWord()
+		case "r":
+			a.searchBar.ToggleRegex()
+		default:
+			return a, nil
+		}
+		if searching {
+			a.updateSearch()
+		} else {
+			// Only revalidate the pattern; the search starts on Enter
+			a.searchQuery()
+		}
+		return a, nil
+	}
+
+	// F3 / Shift+F3 keep navigating while the search bar is open
+	if searching {
+		switch a.keyBindings.LookupKey(msg) {
+		case keybindings.ActionFindNext:
+			return a.executeCommand(string(keybindings.ActionFindNext))
+		case keybindings.ActionFindPrevious:
+			return a.executeCommand(string(keybindings.ActionFindPrevious))
+		}
+	}
+
 	switch msg.Type {
 	case tea.KeyEnter:
 		switch a.searchBar.Mode() {
@@ -551,19 +887,38 @@ func (a *App) handleSearchBarKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			a.searchBar.Hide()
 			a.focus = FocusEditor
 			a.handleResize(a.width, a.height)
+		case ui.SearchModeFindInFiles:
+			return a, a.findInFiles(false)
+		case ui.SearchModeReplaceInFiles:
+			return a, a.findInFiles(true)
 		default:
-			// Find next
-			text := a.searchBar.SearchText()
-			if text != "" {
-				a.editor.Find(text, a.searchBar.IsCaseSensitive())
+			search := a.searchQuery()
+			if search == nil {
+				break
+			}
+			switch {
+			case mode == ui.SearchModeReplace && msg.Alt:
+				count := a.editor.ReplaceAll(search, a.searchBar.ReplaceText())
+				a.showMessage(fmt.Sprintf("%d ersetzt", count), ui.MessageInfo)
+			case a.searchBar.IsReplaceFocused():
+				a.editor.Replace(search, a.searchBar.ReplaceText())
+			default:
+				// Find next
+				a.editor.Find(search)
 			}
 		}
 	case tea.KeyTab:
 		a.searchBar.Tab()
 	case tea.KeyBackspace:
 		a.searchBar.Backspace()
+		if searching && !a.searchBar.IsReplaceFocused() {
+			a.updateSearch()
+		}
 	case tea.KeyDelete:
 		a.searchBar.Delete()
+		if searching && !a.searchBar.IsReplaceFocused() {
+			a.updateSearch()
+		}
 	case tea.KeyLeft:
 		a.searchBar.MoveLeft()
 	case tea.KeyRight:
@@ -571,15 +926,14 @@ func (a *App) handleSearchBarKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case tea.KeyRunes:
 		a.searchBar.Input(string(msg.Runes))
 		// Live search (only for find/replace modes)
-		mode := a.searchBar.Mode()
-		if mode == ui.SearchModeFind || mode == ui.SearchModeReplace {
-			text := a.searchBar.SearchText()
-			if text != "" {
-				a.editor.Find(text, a.searchBar.IsCaseSensitive())
-			}
+		if searching && !a.searchBar.IsReplaceFocused() {
+			a.updateSearch()
 		}
 	case tea.KeySpace:
 		a.searchBar.Input(" ")
+		if searching && !a.searchBar.IsReplaceFocused() {
+			a.updateSearch()
+		}
 	}
 	return a, nil
 }
@@ -588,6 +942,9 @@ func (a *App) handleSearchBarKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (a *App) handleSidebarKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Check for global shortcuts first
 	action := a.keyBindings.Lookup(msg)
+	if handled, cmd := a.handleChordState(action); handled {
+		return a, cmd
+	}
 	switch action {
 	case keybindings.ActionToggleSidebar:
 		a.sidebar.Toggle()
@@ -599,14 +956,26 @@ func (a *App) handleSidebarKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case keybindings.ActionQuit:
 		return a.quit()
 	case keybindings.ActionCommandPalette:
-		a.commandPalette.Show()
-		a.focus = FocusCommandPalette
+		a.showCommandPalette()
 		return a, nil
+	case keybindings.ActionQuickOpen:
+		return a, a.quickOpen()
 	case keybindings.ActionFocusExplorer:
-		// Toggle back to editor
+		// From the Problems or Undo Tree view switch to the explorer, else
+		// back to editor
+		if a.sidebar.Mode() != ui.SidebarExplorer {
+			a.sidebar.SetMode(ui.SidebarExplorer)
+			return a, nil
+		}
 		a.focus = FocusEditor
 		a.showMessage("Fokus: Editor (von Sidebar)", ui.MessageInfo)
 		return a, nil
+	case keybindings.ActionShowProblems:
+		a.toggleProblems()
+		return a, nil
+	case keybindings.ActionShowUndoTree:
+		a.toggleUndoTree()
+		return a, nil
 	}
 
 	// Sidebar-specific keys
@@ -616,6 +985,18 @@ func (a *App) handleSidebarKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case tea.KeyDown:
 		a.sidebar.MoveDown()
 	case tea.KeyEnter:
+		switch a.sidebar.Mode() {
+		case ui.SidebarProblems:
+			if p, ok := a.sidebar.SelectedProblem(); ok {
+				a.openProblem(p)
+			}
+			return a, nil
+		case ui.SidebarUndoTree:
+			if s, ok := a.sidebar.SelectedUndoState(); ok {
+				a.goToUndoState(s)
+			}
+			return a, nil
+		}
 		path := a.sidebar.Enter()
 		if path != "" {
 			if err := a.editor.LoadFile(path); err != nil {
@@ -655,9 +1036,37 @@ func (a *App) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 			return a, nil // Click on title bar or tab bar, ignore
 		}
 
+		// Check if click is in the results panel below the editor
+		if panelY := adjustedY - a.mainHeight(); panelY >= 0 && panelY < a.resultsPanel.Height() {
+			switch msg.Button {
+			case tea.MouseButtonWheelUp:
+				a.resultsPanel.ScrollUp(3)
+			case tea.MouseButtonWheelDown:
+				a.resultsPanel.ScrollDown(3)
+			default:
+				a.focus = FocusResults
+				if path, match, ok := a.resultsPanel.HandleClick(panelY); ok {
+					a.openSelectedResult(path, match)
+				}
+			}
+			return a, nil
+		}
+
 		// Check if click is in sidebar
 		if a.sidebar.IsVisible() && msg.X < a.sidebar.Width() {
 			a.focus = FocusSidebar
+			switch a.sidebar.Mode() {
+			case ui.SidebarProblems:
+				if p, ok := a.sidebar.ClickProblem(adjustedY); ok {
+					a.openProblem(p)
+				}
+				return a, nil
+			case ui.SidebarUndoTree:
+				if s, ok := a.sidebar.ClickUndoState(adjustedY); ok {
+					a.goToUndoState(s)
+				}
+				return a, nil
+			}
 			path := a.sidebar.HandleClick(adjustedY)
 			if path != "" {
 				if err := a.editor.LoadFile(path); err != nil {
@@ -673,7 +1082,12 @@ func (a *App) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 		editorX := msg.X - a.sidebar.Width()
 		editorY := adjustedY
 		shift := msg.Ctrl // Bubble Tea doesn't have Shift detection in mouse, use Ctrl as workaround
-		a.editor.HandleClick(editorX, editorY, shift)
+		if msg.Alt {
+			// Alt+Click adds a cursor
+			a.editor.AddCaretAt(editorX, editorY)
+		} else {
+			a.editor.HandleClick(editorX, editorY, shift)
+		}
 
 	case tea.MouseActionMotion:
 		if msg.Button == tea.MouseButtonLeft {
@@ -714,6 +1128,17 @@ func (a *App) executeCommand(id string) (tea.Model, tea.Cmd) {
 	switch id {
 	case "file.save":
 		return a.save()
+	case "file.saveAll":
+		return a.saveAll()
+	case "file.reloadFromDisk":
+		if tab := a.diskTab(); tab != nil {
+			a.reloadTab(tab)
+			a.showMessage(tab.Name()+" neu geladen", ui.MessageInfo)
+		}
+	case "file.compareWithDisk":
+		if tab := a.diskTab(); tab != nil {
+			a.compareWithDisk(tab)
+		}
 	case "file.new":
 		a.editor.NewFile()
 		a.showMessage("New file", ui.MessageInfo)
@@ -723,6 +1148,10 @@ func (a *App) executeCommand(id string) (tea.Model, tea.Cmd) {
 		a.editor.Undo()
 	case "edit.redo":
 		a.editor.Redo()
+	case "edit.undoOlder":
+		a.stepUndoTree(true)
+	case "edit.undoNewer":
+		a.stepUndoTree(false)
 	case "edit.cut":
 		text := a.editor.Cut()
 		a.copyToClipboard(text)
@@ -734,6 +1163,20 @@ func (a *App) executeCommand(id string) (tea.Model, tea.Cmd) {
 		a.editor.Paste(text)
 	case "edit.selectAll":
 		a.editor.SelectAll()
+	case "select.line":
+		a.editor.SelectLine()
+	case "select.addCursorAbove":
+		a.editor.AddCursorAbove()
+	case "select.addCursorBelow":
+		a.editor.AddCursorBelow()
+	case "select.addNextOccurrence":
+		if !a.editor.AddNextOccurrence() {
+			a.showMessage("Kein weiteres Vorkommen", ui.MessageInfo)
+		}
+	case "select.allOccurrences":
+		if n := a.editor.SelectAllOccurrences(); n > 1 {
+			a.showMessage(fmt.Sprintf("%d Vorkommen ausgewählt", n), ui.MessageInfo)
+		}
 	case "edit.duplicateLine":
 		a.editor.DuplicateLine()
 	case "edit.deleteLine":
@@ -742,21 +1185,31 @@ func (a *App) executeCommand(id string) (tea.Model, tea.Cmd) {
 		a.editor.MoveLineUp()
 	case "edit.moveLineDown":
 		a.editor.MoveLineDown()
+	case "edit.indentLines":
+		a.editor.IndentLines()
+	case "edit.outdentLines":
+		a.editor.OutdentLines()
+	case "edit.triggerSuggest":
+		return a, a.openCompletion(true)
 	case "search.find":
-		a.searchBar.Show()
+		a.showSearch(false)
+	case "search.replace":
+		a.showSearch(true)
+	case "search.findInFiles":
+		a.searchBar.ShowFindInFiles()
 		a.focus = FocusSearchBar
 		a.handleResize(a.width, a.height)
-	case "search.replace":
-		a.searchBar.ShowReplace()
+	case "search.replaceInFiles":
+		a.searchBar.ShowReplaceInFiles()
 		a.focus = FocusSearchBar
 		a.handleResize(a.width, a.height)
 	case "search.findNext":
-		if a.searchBar.SearchText() != "" {
-			a.editor.Find(a.searchBar.SearchText(), a.searchBar.IsCaseSensitive())
+		if search := a.searchQuery(); search != nil {
+			a.editor.Find(search)
 		}
 	case "search.findPrevious":
-		if a.searchBar.SearchText() != "" {
-			a.editor.FindPrevious(a.searchBar.SearchText(), a.searchBar.IsCaseSensitive())
+		if search := a.searchQuery(); search != nil {
+			a.editor.FindPrevious(search)
 		}
 	case "nav.goToLine":
 		a.searchBar.ShowGoToLine()
@@ -766,8 +1219,7 @@ func (a *App) executeCommand(id string) (tea.Model, tea.Cmd) {
 		a.sidebar.Toggle()
 		a.handleResize(a.width, a.height)
 	case "view.commandPalette":
-		a.commandPalette.Show()
-		a.focus = FocusCommandPalette
+		a.showCommandPalette()
 	case "file.saveAs":
 		a.searchBar.ShowSaveAs(a.editor.Filepath())
 		a.focus = FocusSearchBar
@@ -776,17 +1228,46 @@ func (a *App) executeCommand(id string) (tea.Model, tea.Cmd) {
 		a.searchBar.ShowOpen()
 		a.focus = FocusSearchBar
 		a.handleResize(a.width, a.height)
+	case "file.quickOpen":
+		return a, a.quickOpen()
+	case "nav.goToDefinition":
+		return a, a.goToDefinition()
+	case "nav.goToSymbol":
+		a.goToSymbol()
+	case "nav.findReferences":
+		return a, a.findReferences()
+	case "nav.goBack":
+		a.navigate(a.editor.GoBack)
+	case "nav.goForward":
+		a.navigate(a.editor.GoForward)
+	case "view.showHover":
+		return a, a.showHover()
+	case "view.problems":
+		a.toggleProblems()
+	case "view.undoTree":
+		a.toggleUndoTree()
 	case "file.close":
 		a.editor.NewFile()
 		a.showMessage("Datei geschlossen", ui.MessageInfo)
-	case "nav.goToStart":
+	case "nav.moveBufferStart":
 		a.editor.MoveCursor("bufferStart", false)
-	case "nav.goToEnd":
+	case "nav.moveBufferEnd":
 		a.editor.MoveCursor("bufferEnd", false)
 	}
 	return a, nil
 }
 
+// navigate goes back or forward in the navigation history.
+func (a *App) navigate(move func() bool) {
+	if !move() {
+		a.showMessage("Keine weitere Position", ui.MessageInfo)
+		return
+	}
+	a.highlightDirty()
+	a.focus = FocusEditor
+	a.handleResize(a.width, a.height)
+}
+
 // save saves the current file.
 func (a *App) save() (tea.Model, tea.Cmd) {
 	if a.editor.Filepath() == "" {
@@ -794,9 +1275,16 @@ func (a *App) save() (tea.Model, tea.Cmd) {
 		return a, nil
 	}
 
-	if err := a.editor.Save(); err != nil {
+	err := a.editor.Save()
+	switch {
+	case errors.Is(err, editor.ErrChangedOnDisk):
+		a.pendingOverwrite = true
+		a.showMessage(filepath.Base(a.editor.Filepath())+" wurde extern geändert! "+
+			a.keyHint(keybindings.ActionSave, "Überschreiben")+
+			a.keyHint(keybindings.ActionCompareWithDisk, "Vergleichen")+"Esc: Abbrechen", ui.MessageWarning)
+	case err != nil:
 		a.showMessage("Error saving: "+err.Error(), ui.MessageError)
-	} else {
+	default:
 		a.showMessage("Saved "+filepath.Base(a.editor.Filepath()), ui.MessageInfo)
 	}
 	return a, nil
@@ -804,7 +1292,7 @@ func (a *App) save() (tea.Model, tea.Cmd) {
 
 // saveAll saves all modified tabs.
 func (a *App) saveAll() (tea.Model, tea.Cmd) {
-	if err := a.editor.TabManager().SaveAll(); err != nil {
+	if err := a.editor.SaveAll(); err != nil {
 		a.showMessage("Fehler beim Speichern: "+err.Error(), ui.MessageError)
 	} else {
 		a.showMessage("Alle Dateien gespeichert", ui.MessageInfo)
@@ -888,8 +1376,14 @@ func (a *App) updateOpenEditors() {
 
 // quit attempts to quit the application.
 func (a *App) quit() (tea.Model, tea.Cmd) {
-	// Check if any tab has unsaved changes
-	if !a.editor.TabManager().IsModified() {
+	// Check if any file has unsaved changes; untitled tabs are kept
+	modifiedCount := 0
+	for _, tab := range a.editor.TabManager().GetModifiedTabs() {
+		if tab.Filepath() != "" {
+			modifiedCount++
+		}
+	}
+	if modifiedCount == 0 {
 		a.quitting = true
 		return a, tea.Quit
 	}
@@ -903,7 +1397,6 @@ func (a *App) quit() (tea.Model, tea.Cmd) {
 
 	// First Ctrl+Q with unsaved changes - show warning
 	a.pendingQuit = true
-	modifiedCount := len(a.editor.TabManager().GetModifiedTabs())
 	a.showMessage(fmt.Sprintf("%d ungespeicherte Tab(s)! Ctrl+S: Speichern & Beenden | Ctrl+Q: Verwerfen | Esc: Abbrechen", modifiedCount), ui.MessageWarning)
 	return a, nil
 }
@@ -921,6 +1414,9 @@ func (a *App) saveAndQuit() (tea.Model, tea.Cmd) {
 		a.pendingQuit = false
 		return a, nil
 	}
+	// The formatter has to finish before vex exits; a failed one leaves
+	// the file as saved
+	a.editor.FormatNow()
 
 	a.quitting = true
 	return a, tea.Quit
@@ -976,6 +1472,16 @@ func (a *App) View() string {
 	// Update sidebar modified indicators and open editors section
 	a.sidebar.SetModifiedFiles(a.editor.TabManager().GetModifiedPaths())
 	a.updateOpenEditors()
+	a.updateProblems()
+	a.updateUndoTree()
+
+	// Search matches are highlighted only while the find bar is open
+	mode := a.searchBar.Mode()
+	if a.searchBar.IsVisible() && (mode == ui.SearchModeFind || mode == ui.SearchModeReplace) {
+		a.searchBar.SetMatchInfo(a.editor.SearchMatchInfo())
+	} else {
+		a.editor.SetSearchHighlight(nil)
+	}
 
 	// Main content area (sidebar + editor)
 	var mainContent string
@@ -1011,6 +1517,11 @@ func (a *App) View() string {
 	}
 	sections = append(sections, mainContent)
 
+	// Results panel (if visible)
+	if a.resultsPanel.IsVisible() {
+		sections = append(sections, a.resultsPanel.View())
+	}
+
 	// Search bar (if visible)
 	if a.searchBar.IsVisible() {
 		sections = append(sections, a.searchBar.View())
@@ -1021,11 +1532,20 @@ func (a *App) View() string {
 	a.statusBar.SetLanguage(a.editor.Language())
 	a.statusBar.SetEncoding(a.editor.Encoding())
 	a.statusBar.SetLineEnding(a.editor.LineEnding())
+	indent := a.editor.Indent()
+	a.statusBar.SetTabWidth(indent.Width)
+	a.statusBar.SetInsertSpaces(!indent.Tabs)
+	a.statusBar.SetPendingKeys(a.keyBindings.Pending())
 	sections = append(sections, a.statusBar.View())
 
 	// Main view
 	view := strings.Join(sections, "\n")
 
+	// Overlay the completion popup at the cursor
+	if a.completion.IsVisible() {
+		view = a.completionView(view)
+	}
+
 	// Overlay command palette if visible
 	if a.commandPalette.IsVisible() {
 		paletteView := a.commandPalette.View()
@@ -1071,6 +1591,68 @@ func (a *App) overlayView(base, overlay string) string {
 	return strings.Join(result, "\n")
 }
 
+// placeView overlays a smaller view on top of the main view with its top
+// left corner at column x of row y. Unlike overlayView, the base stays
+// visible left and right of the overlay.
+func placeView(base, overlay string, x, y int) string {
+	baseLines := strings.Split(base, "\n")
+	for i, line := range strings.Split(overlay, "\n") {
+		if row := y + i; row >= 0 && row < len(baseLines) {
+			baseLines[row] = spliceLine(baseLines[row], line, x)
+		}
+	}
+	return strings.Join(baseLines, "\n")
+}
+
+// spliceLine replaces the cells of line from column x on with overlay.
+// Escape sequences hidden by the overlay are repeated after it, so the
+// rest of the line keeps its style.
+func spliceLine(line, overlay string, x int) string {
+	end := x + lipgloss.Width(overlay)
+	var left, replay, right strings.Builder
+	col := 0
+	inEscape := false
+	for _, r := range line {
+		if r == '\x1b' {
+			inEscape = true
+		}
+		if inEscape {
+			if r != '\x1b' && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+				inEscape = false
+			}
+			switch {
+			case col < x:
+				left.WriteRune(r)
+				replay.WriteRune(r)
+			case col < end:
+				replay.WriteRune(r)
+			default:
+				right.WriteRune(r)
+			}
+			continue
+		}
+
+		w := lipgloss.Width(string(r))
+		switch {
+		case col+w <= x:
+			left.WriteRune(r)
+		case col >= end:
+			right.WriteRune(r)
+		case col < x:
+			// Wide character cut by the overlay's left edge
+			left.WriteString(strings.Repeat(" ", x-col))
+		case col+w > end:
+			// Wide character cut by the right edge
+			right.WriteString(strings.Repeat(" ", col+w-end))
+		}
+		col += w
+	}
+	if col < x {
+		left.WriteString(strings.Repeat(" ", x-col))
+	}
+	return left.String() + "\x1b[0m" + overlay + replay.String() + right.String()
+}
+
 // stripAnsi removes ANSI escape codes from a string for comparison.
 func stripAnsi(s string) string {
 	var result strings.Builder
@@ -1098,11 +1680,33 @@ func Run(filepath string, line int) error {
 
 // RunWithVersion runs the application with version info displayed in status bar.
 func RunWithVersion(filepath string, line int, version string) error {
+	return RunWithOptions(Options{File: filepath, Line: line, Version: version})
+}
+
+// Options controls how the application starts.
+type Options struct {
+	File    string // File to open, or "" for the working directory
+	Line    int    // Line to go to in File (1-indexed, 0 for none)
+	Version string // Version shown in the status bar
+
+	// NoSession skips the session of the working directory: it is neither
+	// restored on start nor saved on quit.
+	NoSession bool
+}
+
+// RunWithOptions runs the application.
+func RunWithOptions(opts Options) error {
 	app := New()
+	filepath, line := opts.File, opts.Line
+
+	// The session of the working directory is only used when vex starts
+	// without a file, so opening a single file leaves it alone
+	useSession := filepath == "" && !opts.NoSession
+	cwd, _ := os.Getwd()
 
 	// Set version in status bar
-	if version != "" {
-		app.statusBar.SetVersion(version)
+	if opts.Version != "" {
+		app.statusBar.SetVersion(opts.Version)
 	}
 
 	// Load file if specified
@@ -1131,9 +1735,12 @@ func RunWithVersion(filepath string, line int, version string) error {
 		}
 	} else {
 		// Load current directory into sidebar
-		cwd, _ := os.Getwd()
 		app.sidebar.LoadDirectory(cwd)
+		if useSession {
+			app.restoreSession(cwd)
+		}
 	}
+	app.recoverSwaps(useSession)
 
 	p := tea.NewProgram(
 		app,
@@ -1142,5 +1749,20 @@ func RunWithVersion(filepath string, line int, version string) error {
 	)
 
 	_, err := p.Run()
+	app.editor.LanguageServers().Shutdown()
+	if undoErr := app.editor.TabManager().SaveUndoFiles(); undoErr != nil {
+		fmt.Fprintf(os.Stderr, "Error saving undo history: %v\n", undoErr)
+	}
+	if err == nil {
+		// After a failure the swap files are kept for recovery
+		if swapErr := app.closeSwaps(); swapErr != nil {
+			fmt.Fprintf(os.Stderr, "Error saving untitled tabs: %v\n", swapErr)
+		}
+	}
+	if useSession && cwd != "" {
+		if saveErr := app.saveSession(cwd); saveErr != nil {
+			fmt.Fprintf(os.Stderr, "Error saving session: %v\n", saveErr)
+		}
+	}
 	return err
 }
@@ -0,0 +1,191 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DDZ-DO/vex/internal/editor"
+	"github.com/DDZ-DO/vex/internal/lsp"
+	"github.com/DDZ-DO/vex/internal/ui"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// minCompletionPrefix is how many word characters must be typed before the
+// completion popup opens on its own.
+const minCompletionPrefix = 2
+
+// completionMsg delivers the completions of a language server.
+type completionMsg struct {
+	session int
+	items   []lsp.CompletionItem
+}
+
+// openCompletion opens the completion popup for the word before the
+// cursor. Typing opens it once the word is long enough; manual opens it
+// for any word, including none. A language server handling the file is
+// asked for further candidates.
+func (a *App) openCompletion(manual bool) tea.Cmd {
+	if !manual && utf8.RuneCountInString(a.editor.WordBeforeCursor()) < minCompletionPrefix {
+		return nil
+	}
+	prefix, items := a.editor.Completions()
+
+	a.completionSession++
+	a.languageCompletions = nil
+	a.completionPending = false
+	var cmd tea.Cmd
+	if servers := a.editor.LanguageServers(); servers != nil {
+		if path, pos, ok := a.editor.DocumentPosition(); ok && servers.Handles(path) {
+			a.completionPending = true
+			session := a.completionSession
+			cmd = func() tea.Msg {
+				ctx, cancel := context.WithTimeout(context.Background(), lspRequestTimeout)
+				defer cancel()
+				items, _ := servers.Completion(ctx, path, pos) // Word candidates remain on errors
+				return completionMsg{session: session, items: items}
+			}
+		}
+	}
+
+	a.completion.Show()
+	a.setCompletionItems(prefix, items)
+	if manual && !a.completion.IsVisible() {
+		a.showMessage("Keine Vorschläge", ui.MessageInfo)
+	}
+	return cmd
+}
+
+// refreshCompletion filters the popup by the word now before the cursor.
+func (a *App) refreshCompletion() {
+	prefix, items := a.editor.Completions()
+	if prefix == "" {
+		a.closeCompletion()
+		return
+	}
+	a.setCompletionItems(prefix, items)
+}
+
+// setCompletionItems shows the language server's candidates followed by
+// the editor's. The popup closes when nothing matches and no language
+// server is still asked.
+func (a *App) setCompletionItems(prefix string, items []editor.Completion) {
+	seen := map[string]bool{prefix: true}
+	var merged []ui.CompletionItem
+	for _, item := range a.languageCompletions {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			merged = append(merged, item)
+		}
+	}
+	for _, item := range items {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			merged = append(merged, ui.CompletionItem{Label: item.Label, Detail: item.Detail, Text: item.Text})
+		}
+	}
+
+	a.completion.SetItems(merged, prefix)
+	if a.completion.Count() == 0 && !a.completionPending {
+		a.closeCompletion()
+	}
+}
+
+// handleCompletion adds a language server's candidates to the popup.
+func (a *App) handleCompletion(msg completionMsg) {
+	if msg.session != a.completionSession || !a.completion.IsVisible() {
+		return
+	}
+	a.completionPending = false
+	a.languageCompletions = make([]ui.CompletionItem, len(msg.items))
+	for i, item := range msg.items {
+		a.languageCompletions[i] = ui.CompletionItem{Label: item.Label, Detail: item.Detail, Text: item.InsertText}
+	}
+	a.refreshCompletion()
+}
+
+// closeCompletion hides the popup and ignores pending language server
+// results.
+func (a *App) closeCompletion() {
+	a.completion.Hide()
+	a.completionSession++
+	a.completionPending = false
+	a.languageCompletions = nil
+}
+
+// handleCompletionKey handles the keys of a visible popup: Up and Down
+// select, Tab and Enter accept and Esc closes it. Returns false for other
+// keys, which edit as usual.
+func (a *App) handleCompletionKey(msg tea.KeyMsg) (bool, tea.Cmd) {
+	if !a.completion.IsVisible() || a.completion.Count() == 0 ||
+		a.focus != FocusEditor || a.keyBindings.IsPending() {
+		return false, nil
+	}
+
+	switch msg.Type {
+	case tea.KeyUp:
+		a.completion.MoveUp()
+	case tea.KeyDown:
+		a.completion.MoveDown()
+	case tea.KeyTab, tea.KeyEnter:
+		if item, ok := a.completion.Selected(); ok {
+			a.editor.ApplyCompletion(item.Text)
+		}
+		a.closeCompletion()
+	case tea.KeyEsc:
+		a.closeCompletion()
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// updateCompletion runs after a key edited the buffer or moved the cursor:
+// typing a word refilters the popup or opens it, anything else closes it.
+func (a *App) updateCompletion(msg tea.KeyMsg) tea.Cmd {
+	typing := false
+	if a.focus == FocusEditor && !a.commandPalette.IsVisible() && !a.searchBar.IsVisible() {
+		switch msg.Type {
+		case tea.KeyRunes:
+			// The typed runes end the word before the cursor
+			typing = !msg.Alt && !msg.Paste && strings.HasSuffix(a.editor.WordBeforeCursor(), string(msg.Runes))
+		case tea.KeyBackspace:
+			typing = a.completion.IsVisible()
+		}
+	}
+
+	switch {
+	case !typing:
+		if a.completion.IsVisible() {
+			a.closeCompletion()
+		}
+		return nil
+	case a.completion.IsVisible():
+		a.refreshCompletion()
+		return nil
+	}
+	return a.openCompletion(false)
+}
+
+// completionView overlays the popup on view below the word at the cursor,
+// or above it if there is no room below.
+func (a *App) completionView(view string) string {
+	popup := a.completion.View()
+	x, y, ok := a.editor.CursorScreenPosition()
+	if popup == "" || !ok {
+		return view
+	}
+
+	top := 1 + a.tabBar.Height() // Title and tab bar
+	height := a.completion.Height()
+	row := top + y + 1
+	if y+1+height > a.mainHeight() && y >= height {
+		row = top + y - height
+	}
+
+	// Align the labels with the word: border and padding come first
+	col := a.sidebar.Width() + x - 2
+	col = max(min(col, a.width-lipgloss.Width(popup)), 0)
+	return placeView(view, popup, col, row)
+}
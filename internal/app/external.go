@@ -0,0 +1,189 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DDZ-DO/vex/internal/editor"
+	"github.com/DDZ-DO/vex/internal/keybindings"
+	"github.com/DDZ-DO/vex/internal/linediff"
+	"github.com/DDZ-DO/vex/internal/ui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// diskCheckInterval is how often open files are checked for changes by
+// other programs.
+const diskCheckInterval = time.Second
+
+// deletedHash stands for a deleted file in App.diskSeen.
+const deletedHash = "-"
+
+// diskCheckMsg is sent every diskCheckInterval to check the open files.
+type diskCheckMsg struct{}
+
+// diskCheckTick schedules the next check of the open files.
+func diskCheckTick() tea.Cmd {
+	return tea.Tick(diskCheckInterval, func(time.Time) tea.Msg {
+		return diskCheckMsg{}
+	})
+}
+
+// checkDisk looks for open files changed by other programs. Unmodified
+// tabs are reloaded; for a modified tab the user is asked whether to reload
+// it, keep the changes or compare both. Each change is reported once.
+func (a *App) checkDisk() {
+	open := make(map[*editor.TabState]bool)
+	for _, tab := range a.editor.TabManager().Tabs() {
+		open[tab] = true
+		change, state := tab.Buffer().CheckDisk()
+		switch change {
+		case editor.DiskUnchanged:
+			delete(a.diskSeen, tab)
+		case editor.DiskDeleted:
+			if a.diskSeen[tab] != deletedHash {
+				a.diskSeen[tab] = deletedHash
+				a.showMessage(tab.Name()+" wurde extern gelöscht", ui.MessageWarning)
+			}
+		case editor.DiskChanged:
+			if !tab.Modified() {
+				a.reloadTab(tab)
+				a.showMessage(tab.Name()+" wurde extern geändert und neu geladen", ui.MessageInfo)
+				continue
+			}
+			if a.diskSeen[tab] == state.Hash || a.pendingExternal != nil {
+				continue
+			}
+			a.diskSeen[tab] = state.Hash
+			a.pendingExternal = tab
+			a.showMessage(tab.Name()+" wurde extern geändert! "+
+				a.keyHint(keybindings.ActionReloadFromDisk, "Neu laden")+
+				a.keyHint(keybindings.ActionCompareWithDisk, "Vergleichen")+"Esc: Meine behalten", ui.MessageWarning)
+		}
+	}
+
+	for tab := range a.diskSeen {
+		if !open[tab] {
+			delete(a.diskSeen, tab)
+		}
+	}
+	if a.pendingExternal != nil && !open[a.pendingExternal] {
+		a.pendingExternal = nil
+	}
+}
+
+// handleExternalKey answers the question about a modified tab whose file
+// changed on disk. Any other key postpones the decision; saving the tab
+// then warns before overwriting the file.
+func (a *App) handleExternalKey(msg tea.KeyMsg) bool {
+	tab := a.pendingExternal
+	a.pendingExternal = nil
+	switch a.keyBindings.LookupKey(msg) {
+	case keybindings.ActionReloadFromDisk:
+		a.reloadTab(tab)
+		a.showMessage(tab.Name()+" neu geladen", ui.MessageInfo)
+		return true
+	case keybindings.ActionCompareWithDisk:
+		a.compareWithDisk(tab)
+		return true
+	}
+	switch msg.Type {
+	case tea.KeyEsc:
+		if change, state := tab.Buffer().CheckDisk(); change == editor.DiskChanged {
+			tab.KeepChanges(state)
+		}
+		a.showMessage("Eigene Änderungen behalten", ui.MessageInfo)
+		return true
+	}
+	return false
+}
+
+// keyHint returns the key of an action and what it does in a question,
+// such as "Alt+R: Neu laden | ". Actions without a single key are left out.
+func (a *App) keyHint(action keybindings.Action, label string) string {
+	key := a.keyBindings.GetBindingForAction(action)
+	if key == "" || strings.Contains(key, " ") {
+		return ""
+	}
+	return key + ": " + label + " | "
+}
+
+// diskTab returns the active tab if it has a file, for the commands that
+// reload or compare it.
+func (a *App) diskTab() *editor.TabState {
+	tab := a.editor.TabManager().ActiveTab()
+	if tab == nil || tab.Filepath() == "" {
+		a.showMessage("Keine Datei auf der Festplatte", ui.MessageWarning)
+		return nil
+	}
+	return tab
+}
+
+// reloadTab replaces the text of a tab with its file on disk.
+func (a *App) reloadTab(tab *editor.TabState) {
+	if err := a.editor.ReloadTab(tab); err != nil {
+		a.showMessage("Fehler beim Neuladen: "+err.Error(), ui.MessageError)
+		return
+	}
+	delete(a.diskSeen, tab)
+	a.highlightDirty()
+}
+
+// overwrite saves the active tab over a file changed on disk.
+func (a *App) overwrite() {
+	a.pendingOverwrite = false
+	if err := a.editor.Overwrite(); err != nil {
+		a.showMessage("Fehler beim Speichern: "+err.Error(), ui.MessageError)
+		return
+	}
+	delete(a.diskSeen, a.editor.TabManager().ActiveTab())
+	a.showMessage("Gespeichert (überschrieben): "+a.editor.TabManager().ActiveTab().Name(), ui.MessageInfo)
+}
+
+// compareWithDisk lists the lines of a tab that differ from its file on
+// disk in the results panel. Alt+Enter reloads the file.
+func (a *App) compareWithDisk(tab *editor.TabState) {
+	text, err := tab.DiskText()
+	if err != nil {
+		a.showMessage("Fehler beim Vergleichen: "+err.Error(), ui.MessageError)
+		return
+	}
+	disk := strings.Split(text, "\n")
+	changes := linediff.Diff(strings.Split(tab.Buffer().Content(), "\n"), disk)
+
+	a.cancelFind()
+	a.recovery = nil
+	a.compare = tab
+	cwd, _ := os.Getwd()
+	a.resultsPanel.Clear("COMPARE WITH DISK", cwd, ui.ResultsCompare)
+
+	matches := make([]ui.ResultMatch, len(changes))
+	for i, c := range changes {
+		matches[i] = ui.ResultMatch{
+			Line:        c.Line,
+			End:         utf8.RuneCountInString(c.Old),
+			Text:        c.Old,
+			Replacement: c.New,
+		}
+	}
+	a.resultsPanel.AddFile(ui.ResultFile{Path: tab.Filepath(), Matches: matches})
+	a.resultsPanel.SetStatus(fmt.Sprintf("%d geänderte Zeile(n): eigene → Datei", len(changes)))
+	a.resultsPanel.Show()
+	a.focus = FocusResults
+	a.handleResize(a.width, a.height)
+	a.showMessage("Alt+Enter: Datei neu laden, Esc: Schließen", ui.MessageInfo)
+}
+
+// reloadCompared reloads the tab compared in the results panel if it is
+// included.
+func (a *App) reloadCompared() {
+	tab := a.compare
+	reload := len(a.resultsPanel.Included()) > 0
+	a.closeResults()
+	if reload {
+		a.reloadTab(tab)
+		a.showMessage(tab.Name()+" neu geladen", ui.MessageInfo)
+	}
+}
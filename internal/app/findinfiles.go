@@ -0,0 +1,334 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/DDZ-DO/vex/internal/editor"
+	"github.com/DDZ-DO/vex/internal/keybindings"
+	"github.com/DDZ-DO/vex/internal/ui"
+	"github.com/DDZ-DO/vex/internal/workspace"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxFindResults caps the number of matches a find in files reports.
+const maxFindResults = 2000
+
+// findResultMsg delivers the next file of a running find in files.
+type findResultMsg struct {
+	id     int // Search the result belongs to; stale results are dropped
+	result workspace.FileResult
+	done   bool
+}
+
+// waitForFindResult waits for the next result of a find in files.
+func waitForFindResult(id int, results <-chan workspace.FileResult) tea.Cmd {
+	return func() tea.Msg {
+		result, ok := <-results
+		return findResultMsg{id: id, result: result, done: !ok}
+	}
+}
+
+// findInFiles starts searching the directory loaded in the sidebar for the
+// search bar text. Results stream into the results panel; with replace set
+// they form a replace preview.
+func (a *App) findInFiles(replace bool) tea.Cmd {
+	search := a.searchQuery()
+	if search == nil {
+		return nil
+	}
+
+	title := "SEARCH: " + a.searchBar.SearchText()
+	if replace {
+		title = "REPLACE: " + a.searchBar.SearchText() + " → " + a.searchBar.ReplaceText()
+	}
+	return a.startFind(search, title, replace)
+}
+
+// startFind searches the workspace and streams the results into the
+// results panel under title.
+func (a *App) startFind(search *editor.Search, title string, replace bool) tea.Cmd {
+	root, err := a.workspaceRoot()
+	if err != nil {
+		a.showMessage("Fehler: "+err.Error(), ui.MessageError)
+		return nil
+	}
+
+	a.cancelFind()
+	a.recovery = nil
+	a.compare = nil
+	a.findRoot = root
+	ctx, cancel := context.WithCancel(context.Background())
+	a.findCancel = cancel
+	a.findID++
+	a.findTruncated = false
+	a.findResults = workspace.FindInFiles(ctx, root, search, workspace.FindOptions{
+		Limit:       maxFindResults,
+		Buffers:     a.openBuffers(),
+		Replace:     replace,
+		Replacement: a.searchBar.ReplaceText(),
+	})
+
+	mode := ui.ResultsSearch
+	if replace {
+		mode = ui.ResultsReplace
+	}
+	a.resultsPanel.Clear(title, root, mode)
+	a.resultsPanel.SetStatus("Suche läuft...")
+	a.resultsPanel.Show()
+	a.searchBar.Hide()
+	a.focus = FocusResults
+	a.handleResize(a.width, a.height)
+
+	return waitForFindResult(a.findID, a.findResults)
+}
+
+// handleFindResult adds a streamed result to the results panel and waits for
+// the next one.
+func (a *App) handleFindResult(msg findResultMsg) tea.Cmd {
+	if msg.id != a.findID {
+		return nil
+	}
+
+	if msg.done {
+		if a.findCancel != nil {
+			a.findCancel()
+			a.findCancel = nil
+		}
+		a.resultsPanel.SetStatus(a.findStatus())
+		return nil
+	}
+
+	file := ui.ResultFile{Path: msg.result.Path}
+	file.Matches = toResultMatches(msg.result.Matches)
+	a.resultsPanel.AddFile(file)
+	if msg.result.Truncated {
+		a.findTruncated = true
+	}
+	a.resultsPanel.SetStatus(a.findStatus() + " - Suche läuft...")
+
+	return waitForFindResult(a.findID, a.findResults)
+}
+
+// openBuffers returns the text of every open file by absolute path, so
+// unsaved changes are searched instead of the files on disk.
+func (a *App) openBuffers() map[string]string {
+	buffers := make(map[string]string)
+	for _, tab := range a.editor.TabManager().Tabs() {
+		if tab.Filepath() == "" {
+			continue
+		}
+		path, err := filepath.Abs(tab.Filepath())
+		if err != nil {
+			continue
+		}
+		buffers[path] = tab.Buffer().Content()
+	}
+	return buffers
+}
+
+// toResultMatches converts search matches for the results panel.
+func toResultMatches(matches []workspace.LineMatch) []ui.ResultMatch {
+	results := make([]ui.ResultMatch, 0, len(matches))
+	for _, m := range matches {
+		results = append(results, ui.ResultMatch{
+			Line:        m.Line,
+			Start:       m.Start,
+			End:         m.End,
+			Text:        m.Text,
+			Replacement: m.Replacement,
+		})
+	}
+	return results
+}
+
+// fromResultMatches converts results panel matches back to search matches.
+func fromResultMatches(results []ui.ResultMatch) []workspace.LineMatch {
+	matches := make([]workspace.LineMatch, 0, len(results))
+	for _, r := range results {
+		matches = append(matches, workspace.LineMatch{
+			Line:        r.Line,
+			Start:       r.Start,
+			End:         r.End,
+			Text:        r.Text,
+			Replacement: r.Replacement,
+		})
+	}
+	return matches
+}
+
+// replaceInFiles applies the included matches of the replace preview.
+// Open files are edited in their buffer as one undo step per tab; other
+// files are rewritten on disk. The panel then lists the changed files.
+func (a *App) replaceInFiles() {
+	if a.findCancel != nil {
+		a.showMessage("Suche läuft noch", ui.MessageWarning)
+		return
+	}
+	files := a.resultsPanel.Included()
+	if len(files) == 0 {
+		a.showMessage("Nichts zum Ersetzen ausgewählt", ui.MessageWarning)
+		return
+	}
+
+	var summary []ui.ResultFile
+	replaced, changed, failed := 0, 0, 0
+	for _, file := range files {
+		matches := fromResultMatches(file.Matches)
+
+		var err error
+		if i := a.editor.TabManager().FindTabByPath(file.Path); i >= 0 {
+			err = replaceInTab(a.editor.TabManager().Tabs()[i], matches)
+		} else {
+			err = workspace.ReplaceInFile(file.Path, matches)
+		}
+
+		result := ui.ResultFile{Path: file.Path}
+		if err != nil {
+			failed++
+			result.Note = "übersprungen: " + err.Error()
+		} else {
+			replaced += len(matches)
+			changed++
+			result.Matches = toResultMatches(workspace.Replaced(matches))
+		}
+		summary = append(summary, result)
+	}
+
+	status := fmt.Sprintf("%d Ersetzungen in %d Dateien", replaced, changed)
+	if failed > 0 {
+		status += fmt.Sprintf(", %d übersprungen", failed)
+	}
+
+	a.resultsPanel.Clear("REPLACED: "+a.searchBar.SearchText()+" → "+a.searchBar.ReplaceText(), a.findRoot, ui.ResultsSearch)
+	for _, file := range summary {
+		a.resultsPanel.AddFile(file)
+	}
+	a.resultsPanel.SetStatus(status)
+	a.highlightDirty()
+
+	msgType := ui.MessageInfo
+	if failed > 0 {
+		msgType = ui.MessageWarning
+	}
+	a.showMessage(status, msgType)
+}
+
+// replaceInTab replaces matches in an open tab as a single undo step. The
+// matched lines must still read as they did in the preview.
+func replaceInTab(tab *editor.TabState, matches []workspace.LineMatch) error {
+	buf := tab.Buffer()
+	edits := make([]editor.LineEdit, 0, len(matches))
+	for _, m := range matches {
+		if m.Line >= buf.LineCount() || buf.Line(m.Line) != m.Text {
+			return workspace.ErrFileChanged
+		}
+		edits = append(edits, editor.LineEdit{Line: m.Line, Start: m.Start, End: m.End, Text: m.Replacement})
+	}
+	tab.ApplyLineEdits(edits)
+	return nil
+}
+
+// findStatus describes the results found so far.
+func (a *App) findStatus() string {
+	matches, files := a.resultsPanel.MatchCount()
+	status := fmt.Sprintf("%d Treffer in %d Dateien", matches, files)
+	if a.findTruncated {
+		status += fmt.Sprintf(" (Limit von %d erreicht)", maxFindResults)
+	}
+	return status
+}
+
+// cancelFind stops a running find in files. Returns true if one was running.
+func (a *App) cancelFind() bool {
+	if a.findCancel == nil {
+		return false
+	}
+	a.findCancel()
+	a.findCancel = nil
+	a.findID++ // Drop results still in flight
+	a.resultsPanel.SetStatus(a.findStatus() + " (abgebrochen)")
+	return true
+}
+
+// handleResultsKey handles key input when the results panel is focused.
+func (a *App) handleResultsKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Check for global shortcuts first
+	action := a.keyBindings.Lookup(msg)
+	if handled, cmd := a.handleChordState(action); handled {
+		return a, cmd
+	}
+	switch action {
+	case keybindings.ActionQuit:
+		return a.quit()
+	case keybindings.ActionCommandPalette:
+		a.showCommandPalette()
+		return a, nil
+	case keybindings.ActionQuickOpen:
+		return a, a.quickOpen()
+	case keybindings.ActionFindInFiles, keybindings.ActionReplaceInFiles:
+		return a.executeCommand(string(action))
+	}
+
+	switch msg.Type {
+	case tea.KeySpace:
+		a.resultsPanel.ToggleSelected()
+	case tea.KeyUp:
+		a.resultsPanel.MoveUp()
+	case tea.KeyDown:
+		a.resultsPanel.MoveDown()
+	case tea.KeyEnter:
+		if msg.Alt {
+			switch a.resultsPanel.Mode() {
+			case ui.ResultsReplace:
+				a.replaceInFiles()
+			case ui.ResultsRecovery:
+				a.recoverFiles()
+			case ui.ResultsCompare:
+				a.reloadCompared()
+			}
+			break
+		}
+		if path, match, ok := a.resultsPanel.Enter(); ok {
+			a.openSelectedResult(path, match)
+		}
+	case tea.KeyTab:
+		a.focus = FocusEditor
+	}
+	return a, nil
+}
+
+// openSelectedResult opens the selected result of the results panel. A
+// recovered file is opened as it is on disk.
+func (a *App) openSelectedResult(path string, match ui.ResultMatch) {
+	if f := a.recovery[a.resultsPanel.SelectedID()]; f != nil {
+		if f.Path == "" {
+			return
+		}
+		path = f.Path
+	}
+	a.openResult(path, match)
+}
+
+// openResult opens a file at a result and focuses the editor.
+func (a *App) openResult(path string, match ui.ResultMatch) {
+	if err := a.editor.LoadFile(path); err != nil {
+		a.showMessage("Fehler beim Öffnen: "+err.Error(), ui.MessageError)
+		return
+	}
+	a.editor.GoToMatch(match.Line, match.Start, match.End)
+	a.highlightDirty()
+	a.focus = FocusEditor
+	a.handleResize(a.width, a.height)
+}
+
+// closeResults hides the results panel, cancelling a running search.
+func (a *App) closeResults() {
+	a.cancelFind()
+	a.recovery = nil
+	a.compare = nil
+	a.resultsPanel.Hide()
+	a.focus = FocusEditor
+	a.handleResize(a.width, a.height)
+}
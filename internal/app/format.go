@@ -0,0 +1,54 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/DDZ-DO/vex/internal/editor"
+	"github.com/DDZ-DO/vex/internal/format"
+	"github.com/DDZ-DO/vex/internal/ui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// formatMsg delivers the output of a formatter run on a saved file.
+type formatMsg struct {
+	result editor.FormatResult
+}
+
+// loadFormatters reads the formatters run on save from formatters.toml,
+// if format_on_save is set.
+func (a *App) loadFormatters() {
+	if !a.config.FormatOnSave {
+		a.editor.SetFormatters(nil)
+		return
+	}
+	formatters, errs := format.LoadFormatters()
+	a.editor.SetFormatters(formatters)
+
+	if len(errs) > 0 {
+		msg := "Formatter: " + errs[0].Error()
+		if len(errs) > 1 {
+			msg += fmt.Sprintf(" (+%d more)", len(errs)-1)
+		}
+		a.showMessage(msg, ui.MessageError)
+	}
+}
+
+// formatSavedFiles runs the formatters queued by the saves of an update.
+func (a *App) formatSavedFiles() tea.Cmd {
+	var cmds []tea.Cmd
+	for _, job := range a.editor.FormatJobs() {
+		cmds = append(cmds, func() tea.Msg {
+			return formatMsg{result: job.Run()}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// handleFormat applies the output of a formatter to its tab.
+func (a *App) handleFormat(msg formatMsg) {
+	if err := a.editor.ApplyFormat(msg.result); err != nil {
+		a.showMessage("Nicht formatiert: "+err.Error(), ui.MessageWarning)
+		return
+	}
+	a.highlightDirty()
+}
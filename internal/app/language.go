@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DDZ-DO/vex/internal/lsp"
+	"github.com/DDZ-DO/vex/internal/ui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// lspRequestTimeout bounds how long a hover or definition request may take.
+const lspRequestTimeout = 5 * time.Second
+
+// lspEventMsg delivers an event from the language servers.
+type lspEventMsg lsp.Event
+
+// hoverMsg delivers the result of a hover request.
+type hoverMsg struct {
+	text string
+	err  error
+}
+
+// definitionMsg delivers the result of a definition request for word, the
+// identifier at the cursor.
+type definitionMsg struct {
+	word      string
+	locations []lsp.Location
+	err       error
+}
+
+// waitForLSPEvent waits for the next language server event.
+func waitForLSPEvent(events <-chan lsp.Event) tea.Cmd {
+	return func() tea.Msg {
+		return lspEventMsg(<-events)
+	}
+}
+
+// loadLanguageServers connects the editor to the language servers from
+// languageservers.toml and the defaults.
+func (a *App) loadLanguageServers() {
+	servers, errs := lsp.LoadServers()
+	a.editor.SetLanguageServers(lsp.NewManager(servers))
+
+	if len(errs) > 0 {
+		msg := "Language Server: " + errs[0].Error()
+		if len(errs) > 1 {
+			msg += fmt.Sprintf(" (+%d more)", len(errs)-1)
+		}
+		a.showMessage(msg, ui.MessageError)
+	}
+}
+
+// handleLSPEvent shows language server messages and waits for the next
+// event. Changed diagnostics only need the redraw that follows any message.
+func (a *App) handleLSPEvent(msg lspEventMsg) tea.Cmd {
+	if msg.Message != "" {
+		msgType := ui.MessageInfo
+		if msg.Error {
+			msgType = ui.MessageError
+		}
+		a.showMessage(msg.Message, msgType)
+	}
+	return waitForLSPEvent(a.editor.LanguageServers().Events())
+}
+
+// showHover requests the hover text at the cursor.
+func (a *App) showHover() tea.Cmd {
+	servers := a.editor.LanguageServers()
+	path, pos, ok := a.editor.DocumentPosition()
+	if servers == nil || !ok || !servers.Handles(path) {
+		a.showMessage("Kein Language Server für diese Datei", ui.MessageWarning)
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), lspRequestTimeout)
+		defer cancel()
+		text, err := servers.Hover(ctx, path, pos)
+		return hoverMsg{text: text, err: err}
+	}
+}
+
+// handleHover shows hover text in the status bar, joined into one line.
+func (a *App) handleHover(msg hoverMsg) {
+	switch {
+	case msg.err != nil:
+		a.showMessage("Hover: "+lspErrorText(msg.err), ui.MessageError)
+	case strings.TrimSpace(msg.text) == "":
+		a.showMessage("Keine Informationen", ui.MessageInfo)
+	default:
+		// Drop Markdown code fences such as "```go"
+		var lines []string
+		for _, line := range strings.Split(msg.text, "\n") {
+			if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+				lines = append(lines, line)
+			}
+		}
+		a.showMessage(strings.Join(strings.Fields(strings.Join(lines, " ")), " "), ui.MessageInfo)
+	}
+}
+
+// goToDefinition requests the definition of the symbol at the cursor. Files
+// without a language server look it up in the tags file and the symbols of
+// the file right away.
+func (a *App) goToDefinition() tea.Cmd {
+	word := a.editor.WordAtCursor()
+	servers := a.editor.LanguageServers()
+	path, pos, ok := a.editor.DocumentPosition()
+	if servers == nil || !ok || !servers.Handles(path) {
+		a.handleDefinition(definitionMsg{word: word})
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), lspRequestTimeout)
+		defer cancel()
+		locations, err := servers.Definition(ctx, path, pos)
+		return definitionMsg{word: word, locations: locations, err: err}
+	}
+}
+
+// handleDefinition jumps to the first definition found. If the language
+// server found none, the tags file and the symbols of the file are asked.
+func (a *App) handleDefinition(msg definitionMsg) {
+	if msg.err != nil || len(msg.locations) == 0 {
+		switch {
+		case a.goToSymbolDefinition(msg.word):
+		case msg.err != nil:
+			a.showMessage("Definition: "+lspErrorText(msg.err), ui.MessageError)
+		default:
+			a.showMessage("Keine Definition gefunden", ui.MessageInfo)
+		}
+		return
+	}
+	loc := msg.locations[0]
+	if err := a.editor.GoToLocation(loc); err != nil {
+		a.showMessage("Fehler beim Öffnen: "+err.Error(), ui.MessageError)
+		return
+	}
+	a.highlightDirty()
+	a.focus = FocusEditor
+	a.handleResize(a.width, a.height)
+	if len(msg.locations) > 1 {
+		a.showMessage(fmt.Sprintf("%s (%d Definitionen)", filepath.Base(loc.Path), len(msg.locations)), ui.MessageInfo)
+	}
+}
+
+// lspErrorText describes a failed request for the status bar.
+func lspErrorText(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Zeitüberschreitung"
+	case errors.Is(err, lsp.ErrNoServer):
+		return "Language Server nicht verfügbar"
+	}
+	return err.Error()
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/DDZ-DO/vex/internal/editor"
+	"github.com/DDZ-DO/vex/internal/lint"
+	"github.com/DDZ-DO/vex/internal/ui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// lintTimeout bounds how long a linter may run.
+const lintTimeout = 30 * time.Second
+
+// lintMsg delivers the problems found by a linter run.
+type lintMsg struct {
+	scope    string
+	run      int
+	linter   string
+	problems []lint.Problem
+	err      error
+}
+
+// loadLinters reads the linters from linters.toml.
+func (a *App) loadLinters() {
+	linters, errs := lint.LoadLinters()
+	a.linters = linters
+	a.lintRuns = make(map[string]int)
+
+	if len(errs) > 0 {
+		msg := "Linter: " + errs[0].Error()
+		if len(errs) > 1 {
+			msg += fmt.Sprintf(" (+%d more)", len(errs)-1)
+		}
+		a.showMessage(msg, ui.MessageError)
+	}
+}
+
+// lintSavedFiles runs the linter of every file saved since the last call.
+func (a *App) lintSavedFiles() tea.Cmd {
+	var cmds []tea.Cmd
+	for _, path := range a.editor.SavedFiles() {
+		linter, ok := lint.ForFile(a.linters, path)
+		if !ok {
+			continue
+		}
+
+		// A newer run of the same scope supersedes a running one
+		scope := linter.Scope(path)
+		a.lintRuns[scope]++
+		run := a.lintRuns[scope]
+		cmds = append(cmds, func() tea.Msg {
+			ctx, cancel := context.WithTimeout(context.Background(), lintTimeout)
+			defer cancel()
+			problems, err := linter.Run(ctx, path)
+			return lintMsg{scope: scope, run: run, linter: linter.Name(), problems: problems, err: err}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// handleLint stores the problems of a finished linter run.
+func (a *App) handleLint(msg lintMsg) {
+	if msg.run != a.lintRuns[msg.scope] {
+		return
+	}
+	if msg.err != nil {
+		a.showMessage(fmt.Sprintf("Linter %s: %v", msg.linter, msg.err), ui.MessageError)
+		return
+	}
+	a.editor.SetLintProblems(msg.scope, msg.problems)
+}
+
+// updateProblems shows the diagnostics of all files in the Problems view
+// and the status bar.
+func (a *App) updateProblems() {
+	diags := a.editor.Diagnostics()
+	problems := make([]ui.ProblemInfo, len(diags))
+	errors, warnings := 0, 0
+	for i, d := range diags {
+		switch d.Severity {
+		case lint.SeverityError:
+			errors++
+		case lint.SeverityWarning:
+			warnings++
+		}
+		problems[i] = ui.ProblemInfo{
+			Path:     d.Path,
+			Line:     d.Line,
+			Column:   d.Column,
+			Severity: severityMessageType(d.Severity),
+			Message:  d.Message,
+			Source:   d.Source,
+		}
+	}
+	a.sidebar.SetProblems(problems)
+	a.statusBar.SetProblemCounts(errors, warnings)
+
+	if d, ok := a.editor.CursorDiagnostic(); ok {
+		a.statusBar.SetDiagnostic(d.Message, severityMessageType(d.Severity))
+	} else {
+		a.statusBar.SetDiagnostic("", ui.MessageNone)
+	}
+}
+
+// severityMessageType maps a problem severity to the status bar color.
+func severityMessageType(severity lint.Severity) ui.MessageType {
+	switch severity {
+	case lint.SeverityError:
+		return ui.MessageError
+	case lint.SeverityWarning:
+		return ui.MessageWarning
+	}
+	return ui.MessageInfo
+}
+
+// toggleProblems shows the Problems view in the sidebar and focuses it, or
+// switches back to the explorer if it is already focused.
+func (a *App) toggleProblems() {
+	if a.focus == FocusSidebar && a.sidebar.IsVisible() && a.sidebar.Mode() == ui.SidebarProblems {
+		a.sidebar.SetMode(ui.SidebarExplorer)
+		a.focus = FocusEditor
+		return
+	}
+	a.sidebar.SetMode(ui.SidebarProblems)
+	if !a.sidebar.IsVisible() {
+		a.sidebar.Show()
+		a.handleResize(a.width, a.height)
+	}
+	a.focus = FocusSidebar
+}
+
+// openProblem jumps to a problem from the Problems view.
+func (a *App) openProblem(p ui.ProblemInfo) {
+	d := editor.Diagnostic{Path: p.Path, Line: p.Line, Column: p.Column}
+	if err := a.editor.GoToDiagnostic(d); err != nil {
+		a.showMessage("Fehler beim Öffnen: "+err.Error(), ui.MessageError)
+		return
+	}
+	a.highlightDirty()
+	a.focus = FocusEditor
+	a.handleResize(a.width, a.height)
+}
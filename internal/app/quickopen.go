@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DDZ-DO/vex/internal/ui"
+	"github.com/DDZ-DO/vex/internal/workspace"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxIndexedFiles caps the number of files quick open indexes.
+const maxIndexedFiles = 50000
+
+// fileIndexMsg delivers the file index built for quick open.
+type fileIndexMsg struct {
+	id        int
+	root      string
+	files     []string
+	truncated bool
+	err       error
+}
+
+// indexFiles lists the files below root in the background. A walk of the
+// same root still running is reused; one of another root is cancelled.
+func (a *App) indexFiles(root string) tea.Cmd {
+	if a.indexCancel != nil {
+		if a.indexRoot == root {
+			return nil
+		}
+		a.indexCancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.indexID++
+	a.indexRoot = root
+	a.indexCancel = cancel
+
+	id := a.indexID
+	return func() tea.Msg {
+		files, truncated, err := workspace.IndexFiles(ctx, root, maxIndexedFiles)
+		return fileIndexMsg{id: id, root: root, files: files, truncated: truncated, err: err}
+	}
+}
+
+// workspaceRoot returns the directory loaded in the sidebar, or the working
+// directory if none is loaded.
+func (a *App) workspaceRoot() (string, error) {
+	if root := a.sidebar.RootPath(); root != "" {
+		return root, nil
+	}
+	return os.Getwd()
+}
+
+// quickOpen shows the file picker and refreshes its index. The palette
+// keeps the index of the root until the new one arrives. Files open in tabs
+// are ranked by how recently they were active.
+func (a *App) quickOpen() tea.Cmd {
+	root, err := a.workspaceRoot()
+	if err != nil {
+		a.showMessage("Fehler: "+err.Error(), ui.MessageError)
+		return nil
+	}
+
+	var recent []string
+	for _, tab := range a.editor.TabManager().RecentTabs() {
+		if tab.Filepath() == "" {
+			continue
+		}
+		path, err := filepath.Abs(tab.Filepath())
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && filepath.IsLocal(rel) {
+			recent = append(recent, filepath.ToSlash(rel))
+		}
+	}
+
+	a.commandPalette.SetRecentFiles(recent)
+	a.commandPalette.ShowQuickOpen(root)
+	a.focus = FocusCommandPalette
+	return a.indexFiles(root)
+}
+
+// handleFileIndex passes a finished file index to quick open.
+func (a *App) handleFileIndex(msg fileIndexMsg) {
+	if msg.id != a.indexID {
+		return
+	}
+	a.indexCancel()
+	a.indexCancel = nil
+
+	if msg.err != nil {
+		a.showMessage("Fehler beim Indizieren: "+msg.err.Error(), ui.MessageError)
+	} else if msg.truncated {
+		a.showMessage(fmt.Sprintf("Quick Open: nur die ersten %d Dateien indiziert", maxIndexedFiles), ui.MessageWarning)
+	}
+	a.commandPalette.SetFiles(msg.root, msg.files)
+}
+
+// openQuickOpenFile opens a file picked in quick open, at its line if the
+// query gave one.
+func (a *App) openQuickOpenFile(root string, file ui.FileMatch) {
+	path := filepath.Join(root, filepath.FromSlash(file.Path))
+	if err := a.editor.LoadFile(path); err != nil {
+		a.showMessage("Fehler beim Öffnen: "+err.Error(), ui.MessageError)
+		return
+	}
+	if file.Line > 0 {
+		a.editor.GoToLine(file.Line)
+	}
+	a.highlightDirty()
+	a.showMessage("Geöffnet: "+filepath.Base(path), ui.MessageInfo)
+}
@@ -0,0 +1,275 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DDZ-DO/vex/internal/editor"
+	"github.com/DDZ-DO/vex/internal/linediff"
+	"github.com/DDZ-DO/vex/internal/swap"
+	"github.com/DDZ-DO/vex/internal/ui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxRecoveryChanges caps the changed lines listed per recovered file.
+const maxRecoveryChanges = 1000
+
+// swapTickMsg is sent every swap.Interval to update the swap files.
+type swapTickMsg struct{}
+
+// tabSwap is the swap file of a modified tab.
+type tabSwap struct {
+	id      string
+	version int // Buffer version last written
+}
+
+// swapTick schedules the next swap file update.
+func swapTick() tea.Cmd {
+	return tea.Tick(swap.Interval, func(time.Time) tea.Msg {
+		return swapTickMsg{}
+	})
+}
+
+// updateSwaps writes the swap files of modified tabs whose text changed
+// since the last write, and removes those of saved and closed tabs.
+func (a *App) updateSwaps() {
+	open := make(map[*editor.TabState]bool)
+	for _, tab := range a.editor.TabManager().Tabs() {
+		open[tab] = true
+		s := a.swaps[tab]
+		if !tab.Modified() {
+			if s != nil {
+				swap.Remove(s.id)
+				delete(a.swaps, tab)
+			}
+			continue
+		}
+
+		if s == nil {
+			s = &tabSwap{id: swap.NewID(), version: -1}
+			a.swaps[tab] = s
+		}
+		if s.version == tab.Buffer().Version() {
+			continue
+		}
+		if err := writeSwap(tab, s.id, false); err != nil {
+			a.showMessage("Swap-Datei: "+err.Error(), ui.MessageError)
+			continue
+		}
+		s.version = tab.Buffer().Version()
+	}
+
+	for tab, s := range a.swaps {
+		if !open[tab] {
+			swap.Remove(s.id)
+			delete(a.swaps, tab)
+		}
+	}
+}
+
+// writeSwap writes the content of a tab to the swap file with the given
+// ID.
+func writeSwap(tab *editor.TabState, id string, hotExit bool) error {
+	var path string
+	if tab.Filepath() != "" {
+		abs, err := filepath.Abs(tab.Filepath())
+		if err != nil {
+			return err
+		}
+		path = abs
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	disk := tab.Buffer().Disk()
+	return swap.Write(&swap.File{
+		ID:          id,
+		Path:        path,
+		Dir:         dir,
+		HotExit:     hotExit,
+		BaseModTime: disk.ModTime,
+		BaseSize:    disk.Size,
+		BaseHash:    disk.Hash,
+		Content:     tab.Buffer().Content(),
+	})
+}
+
+// closeSwaps runs on a normal quit: the text of modified untitled tabs is
+// kept to be reopened in this directory, all other swap files are removed.
+func (a *App) closeSwaps() error {
+	var firstErr error
+	for _, tab := range a.editor.TabManager().Tabs() {
+		if tab.Filepath() != "" || !tab.Modified() || tab.Buffer().Length() == 0 {
+			continue
+		}
+		id := swap.NewID()
+		if s := a.swaps[tab]; s != nil {
+			id = s.id
+			delete(a.swaps, tab)
+		}
+		if err := writeSwap(tab, id, true); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for tab, s := range a.swaps {
+		swap.Remove(s.id)
+		delete(a.swaps, tab)
+	}
+	return firstErr
+}
+
+// recoverSwaps looks for swap files left behind by vex processes that are
+// gone. Untitled tabs kept on quit in this directory are reopened if
+// reopenUntitled is set; swap files of processes that crashed in this
+// directory are listed in the results panel with their changes against the
+// files on disk. Those of other directories wait for a launch there.
+func (a *App) recoverSwaps(reopenUntitled bool) {
+	files, err := swap.List()
+	if err != nil {
+		a.showMessage("Swap-Dateien: "+err.Error(), ui.MessageError)
+		return
+	}
+	cwd, _ := os.Getwd()
+
+	active := a.editor.TabManager().ActiveIndex()
+	reopened := false
+	var orphans []*swap.File
+	for _, f := range files {
+		if f.Dir != cwd || !f.Orphaned() {
+			continue
+		}
+		if !f.HotExit {
+			orphans = append(orphans, f)
+			continue
+		}
+		if reopenUntitled && f.Path == "" {
+			a.editor.RecoverUntitled(f.Content)
+			swap.Remove(f.ID)
+			reopened = true
+		}
+	}
+	if reopened {
+		a.editor.TabManager().SwitchTab(active)
+		a.highlightDirty()
+	}
+
+	if len(orphans) > 0 {
+		a.showRecovery(orphans, cwd)
+	}
+}
+
+// showRecovery lists the changes of swap files against the files on disk.
+// Swap files without changes are removed. Several swap files of one file
+// are told apart by the time they were written.
+func (a *App) showRecovery(files []*swap.File, root string) {
+	a.compare = nil
+	a.recovery = make(map[string]*swap.File)
+	a.resultsPanel.Clear("RECOVER UNSAVED CHANGES", root, ui.ResultsRecovery)
+
+	perPath := make(map[string]int)
+	for _, f := range files {
+		perPath[f.Path]++
+	}
+
+	untitled := 0
+	for _, f := range files {
+		name := f.Path
+		var disk []string
+		var note string
+		if f.Path != "" && perPath[f.Path] > 1 {
+			name += f.Written.Local().Format(" (15:04:05)")
+		}
+		if f.Path == "" {
+			untitled++
+			name = fmt.Sprintf("Untitled %d", untitled)
+		} else {
+			data, err := os.ReadFile(f.Path)
+			switch {
+			case os.IsNotExist(err):
+				note = "(Datei gelöscht)"
+			case err != nil:
+				note = "(" + err.Error() + ")"
+			default:
+				disk = strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+				if state, err := editor.FileDiskState(f.Path, data); err == nil && f.BaseChanged(state.Hash) {
+					note = "(seit der Sicherung geändert)"
+				}
+			}
+		}
+
+		changes := linediff.Diff(disk, strings.Split(f.Content, "\n"))
+		if len(changes) == 0 {
+			swap.Remove(f.ID)
+			continue
+		}
+		if len(changes) > maxRecoveryChanges {
+			changes = changes[:maxRecoveryChanges]
+			note = strings.TrimSpace(note + " (gekürzt)")
+		}
+		matches := make([]ui.ResultMatch, len(changes))
+		for i, c := range changes {
+			matches[i] = ui.ResultMatch{
+				Line:        c.Line,
+				End:         utf8.RuneCountInString(c.Old),
+				Text:        c.Old,
+				Replacement: c.New,
+			}
+		}
+
+		a.recovery[f.ID] = f
+		a.resultsPanel.AddFile(ui.ResultFile{Path: name, ID: f.ID, Matches: matches, Note: note})
+	}
+
+	if len(a.recovery) == 0 {
+		a.recovery = nil
+		return
+	}
+	a.resultsPanel.SetStatus(fmt.Sprintf("%d Datei(en) mit ungesicherten Änderungen", len(a.recovery)))
+	a.resultsPanel.Show()
+	a.focus = FocusResults
+	a.showMessage("Ungesicherte Änderungen gefunden - Alt+Enter: Wiederherstellen, Esc: Später", ui.MessageWarning)
+}
+
+// recoverFiles opens the included files of the recovery list with their
+// recovered text. The swap files of excluded files are discarded.
+func (a *App) recoverFiles() {
+	included := make(map[string]bool)
+	recovered := 0
+	var recoverErr error
+	for _, file := range a.resultsPanel.Included() {
+		included[file.ID] = true
+		f := a.recovery[file.ID]
+		if f.Path == "" {
+			a.editor.RecoverUntitled(f.Content)
+		} else if err := a.editor.RecoverFile(f.Path, f.Content); err != nil {
+			// Keep the swap file for the next launch
+			recoverErr = err
+			continue
+		}
+		swap.Remove(f.ID)
+		recovered++
+	}
+	for id, f := range a.recovery {
+		if !included[id] {
+			swap.Remove(f.ID)
+		}
+	}
+
+	a.recovery = nil
+	a.resultsPanel.Hide()
+	a.focus = FocusEditor
+	a.highlightDirty()
+	a.handleResize(a.width, a.height)
+	if recoverErr != nil {
+		a.showMessage("Fehler beim Wiederherstellen: "+recoverErr.Error(), ui.MessageError)
+		return
+	}
+	a.showMessage(fmt.Sprintf("%d Datei(en) wiederhergestellt", recovered), ui.MessageInfo)
+}
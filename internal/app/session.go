@@ -0,0 +1,78 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/DDZ-DO/vex/internal/editor"
+	"github.com/DDZ-DO/vex/internal/session"
+	"github.com/DDZ-DO/vex/internal/ui"
+)
+
+// restoreSession reopens the tabs and expands the sidebar folders saved for
+// a working directory.
+func (a *App) restoreSession(dir string) {
+	s, err := session.Load(dir)
+	if err != nil {
+		a.showMessage("Sitzung: "+err.Error(), ui.MessageError)
+		return
+	}
+	if s == nil {
+		return
+	}
+
+	a.sidebar.SetExpandedPaths(s.Expanded)
+
+	views := make([]editor.TabView, len(s.Tabs))
+	for i, t := range s.Tabs {
+		views[i] = editor.TabView{
+			Path:    t.Path,
+			Cursor:  editor.Position{Line: t.Cursor.Line, Column: t.Cursor.Column},
+			ScrollX: t.ScrollX,
+			ScrollY: t.ScrollY,
+		}
+		if t.Selection != nil {
+			views[i].Selection = &editor.Selection{
+				Active: true,
+				Start:  editor.Position{Line: t.Selection.Start.Line, Column: t.Selection.Start.Column},
+				End:    editor.Position{Line: t.Selection.End.Line, Column: t.Selection.End.Column},
+			}
+		}
+	}
+	if restored := a.editor.RestoreTabs(views, s.Active); restored < len(views) {
+		a.showMessage(fmt.Sprintf("Sitzung: %d von %d Dateien nicht gefunden", len(views)-restored, len(views)), ui.MessageWarning)
+	}
+	a.highlightDirty()
+}
+
+// saveSession saves the open file tabs and the expanded sidebar folders for
+// a working directory. Untitled tabs are not saved.
+func (a *App) saveSession(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	views, active := a.editor.TabViews()
+	s := &session.Session{
+		Dir:      abs,
+		Tabs:     make([]session.Tab, len(views)),
+		Active:   max(active, 0),
+		Expanded: a.sidebar.ExpandedPaths(),
+	}
+	for i, v := range views {
+		s.Tabs[i] = session.Tab{
+			Path:    v.Path,
+			Cursor:  session.Position{Line: v.Cursor.Line, Column: v.Cursor.Column},
+			ScrollX: v.ScrollX,
+			ScrollY: v.ScrollY,
+		}
+		if v.Selection != nil {
+			s.Tabs[i].Selection = &session.Selection{
+				Start: session.Position{Line: v.Selection.Start.Line, Column: v.Selection.Start.Column},
+				End:   session.Position{Line: v.Selection.End.Line, Column: v.Selection.End.Column},
+			}
+		}
+	}
+	return s.Save()
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/DDZ-DO/vex/internal/editor"
+	"github.com/DDZ-DO/vex/internal/syntax"
+	"github.com/DDZ-DO/vex/internal/tags"
+	"github.com/DDZ-DO/vex/internal/ui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tagsFile returns the tags file of the workspace, or nil if there is none.
+func (a *App) tagsFile() *tags.File {
+	root, err := a.workspaceRoot()
+	if err != nil {
+		return nil
+	}
+	file, err := a.tags.Get(root)
+	if err != nil {
+		a.showMessage("Tags: "+err.Error(), ui.MessageError)
+		return nil
+	}
+	return file
+}
+
+// loadSymbols passes the symbols of the active file to the palette's "@"
+// mode.
+func (a *App) loadSymbols() {
+	symbols := a.editor.Symbols(a.tagsFile())
+	items := make([]ui.SymbolItem, len(symbols))
+	for i, s := range symbols {
+		items[i] = ui.SymbolItem{Name: s.Name, Kind: s.Kind, Line: s.Line, Column: s.Column}
+	}
+	a.commandPalette.SetSymbols(items)
+}
+
+// showCommandPalette shows the palette with its commands. Typing "@"
+// switches to the symbols of the active file.
+func (a *App) showCommandPalette() {
+	a.loadSymbols()
+	a.commandPalette.Show()
+	a.focus = FocusCommandPalette
+}
+
+// goToSymbol shows the symbols of the active file in the palette.
+func (a *App) goToSymbol() {
+	a.loadSymbols()
+	a.commandPalette.ShowSymbols()
+	a.focus = FocusCommandPalette
+}
+
+// openSymbol selects the name of a symbol picked in the palette.
+func (a *App) openSymbol(s ui.SymbolItem) {
+	a.editor.GoToSymbol(syntax.Symbol{Name: s.Name, Kind: s.Kind, Line: s.Line, Column: s.Column})
+}
+
+// goToSymbolDefinition jumps to the definition of word listed in the tags
+// file, or else defined in the active file. Returns false if neither has
+// one.
+func (a *App) goToSymbolDefinition(word string) bool {
+	if word == "" {
+		return false
+	}
+
+	if file := a.tagsFile(); file != nil {
+		if found := file.Lookup(word); len(found) > 0 {
+			// A definition in the active file wins
+			tag := found[0]
+			if path, err := filepath.Abs(a.editor.Filepath()); a.editor.Filepath() != "" && err == nil {
+				for _, t := range found {
+					if t.Path == path {
+						tag = t
+						break
+					}
+				}
+			}
+			if err := a.editor.GoToTag(tag); err != nil {
+				a.showMessage("Fehler beim Öffnen: "+err.Error(), ui.MessageError)
+				return true
+			}
+			a.highlightDirty()
+			a.focus = FocusEditor
+			a.handleResize(a.width, a.height)
+			if len(found) > 1 {
+				a.showMessage(fmt.Sprintf("%s (%d Definitionen)", filepath.Base(tag.Path), len(found)), ui.MessageInfo)
+			}
+			return true
+		}
+	}
+
+	for _, s := range a.editor.Symbols(nil) {
+		if s.Name == word {
+			a.editor.GoToSymbol(s)
+			a.focus = FocusEditor
+			return true
+		}
+	}
+	return false
+}
+
+// findReferences searches the workspace for the identifier at the cursor
+// as a whole, case-sensitive word.
+func (a *App) findReferences() tea.Cmd {
+	word := a.editor.WordAtCursor()
+	if word == "" {
+		a.showMessage("Kein Bezeichner am Cursor", ui.MessageWarning)
+		return nil
+	}
+	search, err := editor.NewSearch(word, editor.SearchOptions{CaseSensitive: true, WholeWord: true})
+	if err != nil {
+		a.showMessage("Fehler: "+err.Error(), ui.MessageError)
+		return nil
+	}
+	return a.startFind(search, "REFERENCES: "+word, false)
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"github.com/DDZ-DO/vex/internal/ui"
+)
+
+// updateUndoTree shows the undo tree of the active tab in the Undo Tree
+// view while it is open.
+func (a *App) updateUndoTree() {
+	if !a.sidebar.IsVisible() || a.sidebar.Mode() != ui.SidebarUndoTree {
+		return
+	}
+	states := a.editor.UndoStates()
+	infos := make([]ui.UndoStateInfo, len(states))
+	for i, s := range states {
+		infos[i] = ui.UndoStateInfo{
+			Seq:     s.Seq,
+			Parent:  s.Parent,
+			Time:    s.Time,
+			Label:   s.Label,
+			Current: s.Current,
+			Saved:   s.Saved,
+		}
+	}
+	a.sidebar.SetUndoStates(infos)
+}
+
+// toggleUndoTree shows the Undo Tree view in the sidebar and focuses it,
+// or switches back to the explorer if it is already focused.
+func (a *App) toggleUndoTree() {
+	if a.focus == FocusSidebar && a.sidebar.IsVisible() && a.sidebar.Mode() == ui.SidebarUndoTree {
+		a.sidebar.SetMode(ui.SidebarExplorer)
+		a.focus = FocusEditor
+		return
+	}
+	a.sidebar.SetMode(ui.SidebarUndoTree)
+	if !a.sidebar.IsVisible() {
+		a.sidebar.Show()
+		a.handleResize(a.width, a.height)
+	}
+	a.focus = FocusSidebar
+	a.updateUndoTree()
+}
+
+// goToUndoState restores the text of a state from the Undo Tree view. The
+// focus stays in the view so further states can be tried.
+func (a *App) goToUndoState(s ui.UndoStateInfo) {
+	if !a.editor.GoToUndoState(s.Seq) {
+		return
+	}
+	a.highlightDirty()
+	a.updateUndoTree()
+}
+
+// stepUndoTree moves to the chronologically older or newer state of the
+// undo tree, across branches.
+func (a *App) stepUndoTree(older bool) {
+	var moved bool
+	if older {
+		moved = a.editor.UndoOlder()
+	} else {
+		moved = a.editor.UndoNewer()
+	}
+	if !moved {
+		a.showMessage("Kein weiterer Zustand", ui.MessageInfo)
+		return
+	}
+	a.highlightDirty()
+}
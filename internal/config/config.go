@@ -1,10 +1,25 @@
 package config
 
 import (
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/DDZ-DO/vex/internal/atomicfile"
+)
+
+// Limits for validated settings.
+const (
+	MinTabWidth     = 1
+	MaxTabWidth     = 16
+	MinSidebarWidth = 15
+	MaxSidebarWidth = 60
 )
 
+// Themes lists the available syntax highlighting themes.
+var Themes = []string{"default", "monokai"}
+
 // Config holds the editor configuration.
 type Config struct {
 	// Editor settings
@@ -22,6 +37,8 @@ type Config struct {
 	AutoSave               bool `toml:"auto_save"`
 	TrimTrailingWhitespace bool `toml:"trim_trailing_whitespace"`
 	InsertFinalNewline     bool `toml:"insert_final_newline"`
+	FormatOnSave           bool `toml:"format_on_save"`
+	PersistentUndo         bool `toml:"persistent_undo"`
 }
 
 // DefaultConfig returns the default configuration.
@@ -39,6 +56,8 @@ func DefaultConfig() *Config {
 		AutoSave:               false,
 		TrimTrailingWhitespace: false,
 		InsertFinalNewline:     true,
+		FormatOnSave:           false,
+		PersistentUndo:         false,
 	}
 }
 
@@ -67,7 +86,75 @@ func ConfigPath() (string, error) {
 	return filepath.Join(dir, "config.toml"), nil
 }
 
+// KeybindingsPath returns the path to the user keybindings file.
+func KeybindingsPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "keybindings.toml"), nil
+}
+
+// LanguageServersPath returns the path to the language server config file.
+func LanguageServersPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "languageservers.toml"), nil
+}
+
+// LintersPath returns the path to the linter config file.
+func LintersPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "linters.toml"), nil
+}
+
+// FormattersPath returns the path to the formatter config file.
+func FormattersPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "formatters.toml"), nil
+}
+
+// SessionDir returns the directory holding the saved sessions, one file
+// per working directory.
+func SessionDir() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions"), nil
+}
+
+// SwapDir returns the directory holding the swap files of unsaved buffers.
+func SwapDir() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "swap"), nil
+}
+
+// UndoDir returns the directory holding the undo files kept across
+// sessions.
+func UndoDir() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "undo"), nil
+}
+
 // Load loads the configuration from the config file.
+// Missing files yield the defaults. Invalid lines are skipped so that the
+// remaining settings still apply; the first problem is returned as a
+// *ParseError carrying the offending line number.
 func Load() (*Config, error) {
 	cfg := DefaultConfig()
 
@@ -76,14 +163,45 @@ func Load() (*Config, error) {
 		return cfg, nil // Return default on error
 	}
 
-	// Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+	file, err := os.Open(configPath)
+	if os.IsNotExist(err) {
 		return cfg, nil // Return default if file doesn't exist
 	}
+	if err != nil {
+		return cfg, err
+	}
+	defer file.Close()
+
+	errs := decodeTOML(file, filepath.Base(configPath), cfg)
+	if len(errs) == 0 {
+		return cfg, nil
+	}
+	if len(errs) > 1 {
+		return cfg, fmt.Errorf("%w (+%d more)", errs[0], len(errs)-1)
+	}
+	return cfg, errs[0]
+}
 
-	// For now, just return default config
-	// TODO: Implement TOML parsing when needed
-	return cfg, nil
+// validateTOML checks a single decoded setting.
+func (c *Config) validateTOML(key string) error {
+	switch key {
+	case "tab_width":
+		if c.TabWidth < MinTabWidth || c.TabWidth > MaxTabWidth {
+			return fmt.Errorf("must be between %d and %d", MinTabWidth, MaxTabWidth)
+		}
+	case "sidebar_width":
+		if c.SidebarWidth < MinSidebarWidth || c.SidebarWidth > MaxSidebarWidth {
+			return fmt.Errorf("must be between %d and %d", MinSidebarWidth, MaxSidebarWidth)
+		}
+	case "theme":
+		for _, name := range Themes {
+			if c.Theme == name {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown theme %q", c.Theme)
+	}
+	return nil
 }
 
 // Save saves the configuration to the config file.
@@ -99,8 +217,13 @@ func (c *Config) Save() error {
 		return err
 	}
 
-	// TODO: Implement TOML serialization when needed
-	return nil
+	var buf bytes.Buffer
+	buf.WriteString("# vex configuration\n")
+	if err := encodeTOML(&buf, c); err != nil {
+		return err
+	}
+
+	return atomicfile.Write(configPath, buf.Bytes(), 0644)
 }
 
 // EnsureConfigDir creates the config directory if it doesn't exist.
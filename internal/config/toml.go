@@ -0,0 +1,272 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ParseError describes an invalid line in a config file.
+type ParseError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// tomlValidator is implemented by structs that check decoded values.
+type tomlValidator interface {
+	validateTOML(key string) error
+}
+
+// Entry is a single key/value pair read from a TOML file.
+type Entry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// ReadStringTable reads a flat TOML document whose values are all strings,
+// such as keybindings.toml. Keys may be quoted. Entries are returned in file
+// order; invalid lines are skipped and reported.
+func ReadStringTable(r io.Reader, file string) ([]Entry, []error) {
+	var entries []Entry
+	errs := scanTOML(r, file, func(line int, key, raw string) error {
+		value, err := parseTOMLString(raw)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, Entry{Key: key, Value: value, Line: line})
+		return nil
+	})
+	return entries, errs
+}
+
+// decodeTOML reads flat TOML key/value pairs into the struct pointed to by v.
+// Fields are matched by their `toml` tag. Only the subset of TOML needed for
+// vex's configuration is supported: comments, strings, integers and booleans.
+// Every invalid line is skipped and reported; valid lines are still applied.
+// If v implements tomlValidator, each value is checked after it is decoded
+// and rejected values are rolled back.
+func decodeTOML(r io.Reader, file string, v any) []error {
+	fields := tomlFields(v)
+	validator, _ := v.(tomlValidator)
+
+	return scanTOML(r, file, func(line int, key, raw string) error {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("unknown key %q", key)
+		}
+
+		old := reflect.ValueOf(field.Interface())
+		if err := setTOMLValue(field, raw); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		if validator != nil {
+			if err := validator.validateTOML(key); err != nil {
+				field.Set(old)
+				return fmt.Errorf("%s: %v", key, err)
+			}
+		}
+		return nil
+	})
+}
+
+// scanTOML calls fn for every key = value line of a flat TOML document.
+// Comments, blank lines and duplicate keys are handled here; errors returned
+// by fn are wrapped in a *ParseError carrying the line number.
+func scanTOML(r io.Reader, file string, fn func(line int, key, raw string) error) []error {
+	seen := make(map[string]bool)
+	var errs []error
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(stripTOMLComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		fail := func(msg string) {
+			errs = append(errs, &ParseError{File: file, Line: lineNum, Msg: msg})
+		}
+
+		if strings.HasPrefix(line, "[") {
+			fail("tables are not supported: " + line)
+			continue
+		}
+
+		key, raw, err := splitTOMLPair(line)
+		if err != nil {
+			fail(err.Error())
+			continue
+		}
+		if seen[key] {
+			fail(fmt.Sprintf("duplicate key %q", key))
+			continue
+		}
+		seen[key] = true
+
+		if err := fn(lineNum, key, raw); err != nil {
+			fail(err.Error())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// splitTOMLPair splits a line into its (unquoted) key and raw value.
+func splitTOMLPair(line string) (key, raw string, err error) {
+	rest := line
+	if line[0] == '"' || line[0] == '\'' {
+		end := strings.IndexByte(line[1:], line[0])
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated key")
+		}
+		key, err = parseTOMLString(line[:end+2])
+		if err != nil {
+			return "", "", err
+		}
+		rest = line[end+2:]
+	} else {
+		idx := strings.IndexByte(line, '=')
+		if idx < 0 {
+			return "", "", fmt.Errorf("expected key = value")
+		}
+		key = strings.TrimSpace(line[:idx])
+		rest = line[idx:]
+	}
+
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "=") || key == "" {
+		return "", "", fmt.Errorf("expected key = value")
+	}
+	return key, strings.TrimSpace(rest[1:]), nil
+}
+
+// encodeTOML writes every tagged field of the struct pointed to by v.
+func encodeTOML(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		key := rt.Field(i).Tag.Get("toml")
+		if key == "" {
+			continue
+		}
+
+		var value string
+		field := rv.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			value = strconv.Quote(field.String())
+		case reflect.Int:
+			value = strconv.FormatInt(field.Int(), 10)
+		case reflect.Bool:
+			value = strconv.FormatBool(field.Bool())
+		default:
+			return fmt.Errorf("unsupported config field type %s", field.Kind())
+		}
+
+		if _, err := fmt.Fprintf(w, "%s = %s\n", key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tomlFields maps toml tags to the settable struct fields of v.
+func tomlFields(v any) map[string]reflect.Value {
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < rt.NumField(); i++ {
+		if key := rt.Field(i).Tag.Get("toml"); key != "" {
+			fields[key] = rv.Field(i)
+		}
+	}
+	return fields
+}
+
+// setTOMLValue parses raw according to the field's kind and stores it.
+func setTOMLValue(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		s, err := parseTOMLString(raw)
+		if err != nil {
+			return err
+		}
+		field.SetString(s)
+	case reflect.Int:
+		n, err := strconv.ParseInt(strings.ReplaceAll(raw, "_", ""), 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %s", raw)
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		switch raw {
+		case "true":
+			field.SetBool(true)
+		case "false":
+			field.SetBool(false)
+		default:
+			return fmt.Errorf("expected true or false, got %s", raw)
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", field.Kind())
+	}
+	return nil
+}
+
+// parseTOMLString parses a basic ("...") or literal ('...') TOML string.
+func parseTOMLString(raw string) (string, error) {
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' {
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return s, nil
+	}
+	return "", fmt.Errorf("expected a quoted string, got %s", raw)
+}
+
+// stripTOMLComment removes a trailing # comment that is not inside a string.
+func stripTOMLComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
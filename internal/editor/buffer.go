@@ -1,49 +1,81 @@
 package editor
 
 import (
-	"bufio"
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"os"
+	"path/filepath"
 	"strings"
-	"unicode/utf8"
+	"time"
+
+	"github.com/DDZ-DO/vex/internal/atomicfile"
 )
 
 const (
-	initialGapSize = 1024
 	LineEndingLF   = "\n"
 	LineEndingCRLF = "\r\n"
+	LineEndingCR   = "\r"
 )
 
-// Buffer implements a Gap Buffer for efficient text editing operations.
-// The gap buffer maintains a gap (empty space) in the middle of the data
-// that moves to the cursor position, making insertions and deletions O(1)
-// at the cursor location.
+// Buffer stores document text in a balanced rope (see rope.go).
+// Inserts and deletes only touch the nodes on one root-to-leaf path, and the
+// line index is kept in the rope's cached newline counts, so the cost of an
+// edit grows with O(log n) instead of the size of the file.
 type Buffer struct {
-	data       []rune
-	gapStart   int
-	gapEnd     int
-	lines      []int // Cache of line start positions (byte offsets into content)
+	root       *ropeNode
 	modified   bool
 	filepath   string
 	encoding   string
 	lineEnding string
+
+	// editListeners are called after every change with the offset of the
+	// change and the number of runes removed and inserted there
+	editListeners []editListener
+	nextListener  int
+
+	// version counts changes and saves counts writes, so observers such as
+	// language servers can tell whether they are up to date
+	version int
+	saves   int
+
+	disk DiskState
+}
+
+// DiskState describes the file as it was when the buffer last loaded or
+// saved it.
+type DiskState struct {
+	ModTime time.Time
+	Size    int64
+	Hash    string // Hex SHA-256 of the file content
+}
+
+// FileDiskState returns the disk state of a file with the given content.
+func FileDiskState(path string, content []byte) (DiskState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return DiskState{}, err
+	}
+	sum := sha256.Sum256(content)
+	return DiskState{ModTime: info.ModTime(), Size: info.Size(), Hash: hex.EncodeToString(sum[:])}, nil
 }
 
 // NewBuffer creates a new empty buffer.
 func NewBuffer() *Buffer {
 	b := &Buffer{
-		data:       make([]rune, initialGapSize),
-		gapStart:   0,
-		gapEnd:     initialGapSize,
-		lines:      []int{0},
-		encoding:   "UTF-8",
+		encoding:   EncodingUTF8,
 		lineEnding: LineEndingLF,
 	}
 	return b
 }
 
-// NewBufferFromFile creates a buffer with content loaded from a file.
+// NewBufferFromFile creates a buffer with content loaded from a UTF-8 file.
 func NewBufferFromFile(filepath string) (*Buffer, error) {
+	return newBufferFromFile(filepath, EncodingUTF8)
+}
+
+// newBufferFromFile creates a buffer with content loaded from a file in
+// the given encoding.
+func newBufferFromFile(filepath, encoding string) (*Buffer, error) {
 	content, err := os.ReadFile(filepath)
 	if err != nil {
 		return nil, err
@@ -51,18 +83,35 @@ func NewBufferFromFile(filepath string) (*Buffer, error) {
 
 	b := NewBuffer()
 	b.filepath = filepath
-	b.detectLineEnding(content)
-	b.SetContent(string(content))
+	b.encoding = encoding
+	b.SetContent(b.readText(content))
 	b.modified = false
+	b.disk, _ = FileDiskState(filepath, content)
 
 	return b, nil
 }
 
-// detectLineEnding detects whether the file uses LF or CRLF line endings.
-func (b *Buffer) detectLineEnding(content []byte) {
-	if bytes.Contains(content, []byte("\r\n")) {
+// readText decodes file content in the buffer's encoding and detects its
+// line ending. CR line endings are converted to LF; SetContent converts
+// CRLF.
+func (b *Buffer) readText(content []byte) string {
+	text := decodeText(content, b.encoding)
+	b.detectLineEnding(text)
+	if b.lineEnding == LineEndingCR {
+		text = strings.ReplaceAll(text, "\r", "\n")
+	}
+	return text
+}
+
+// detectLineEnding detects whether the text uses LF, CRLF or CR line
+// endings.
+func (b *Buffer) detectLineEnding(text string) {
+	switch {
+	case strings.Contains(text, "\r\n"):
 		b.lineEnding = LineEndingCRLF
-	} else {
+	case strings.Contains(text, "\r") && !strings.Contains(text, "\n"):
+		b.lineEnding = LineEndingCR
+	default:
 		b.lineEnding = LineEndingLF
 	}
 }
@@ -71,83 +120,63 @@ func (b *Buffer) detectLineEnding(content []byte) {
 func (b *Buffer) SetContent(content string) {
 	// Normalize line endings to LF internally
 	content = strings.ReplaceAll(content, "\r\n", "\n")
-	runes := []rune(content)
-
-	b.data = make([]rune, len(runes)+initialGapSize)
-	copy(b.data, runes)
-	b.gapStart = len(runes)
-	b.gapEnd = len(b.data)
-	b.rebuildLineIndex()
+	removed := b.Length()
+	b.root = buildRope([]rune(content))
 	b.modified = true
+	b.notifyEdit(0, removed, b.Length())
+}
+
+// editListener is a function registered with AddEditListener.
+type editListener struct {
+	id int
+	fn func(offset, removed, inserted int)
+}
+
+// AddEditListener adds a function called after every change to the buffer.
+// It returns a function that removes it again.
+func (b *Buffer) AddEditListener(fn func(offset, removed, inserted int)) (remove func()) {
+	b.nextListener++
+	id := b.nextListener
+	b.editListeners = append(b.editListeners, editListener{id: id, fn: fn})
+	return func() {
+		for i, l := range b.editListeners {
+			if l.id == id {
+				// Copy, so a notification in progress is not disturbed
+				b.editListeners = append(b.editListeners[:i:i], b.editListeners[i+1:]...)
+				return
+			}
+		}
+	}
 }
 
-// Content returns the full buffer content as a string.
-func (b *Buffer) Content() string {
-	result := make([]rune, b.Length())
-	copy(result, b.data[:b.gapStart])
-	copy(result[b.gapStart:], b.data[b.gapEnd:])
-	return string(result)
+// notifyEdit reports a change to the edit listeners.
+func (b *Buffer) notifyEdit(offset, removed, inserted int) {
+	b.version++
+	for _, l := range b.editListeners {
+		l.fn(offset, removed, inserted)
+	}
 }
 
-// Length returns the number of runes in the buffer (excluding the gap).
-func (b *Buffer) Length() int {
-	return len(b.data) - (b.gapEnd - b.gapStart)
+// Version returns a number that increases with every change to the buffer.
+func (b *Buffer) Version() int {
+	return b.version
 }
 
-// gapSize returns the current size of the gap.
-func (b *Buffer) gapSize() int {
-	return b.gapEnd - b.gapStart
+// SaveCount returns how often the buffer was written to disk.
+func (b *Buffer) SaveCount() int {
+	return b.saves
 }
 
-// moveGapTo moves the gap to the specified position.
-func (b *Buffer) moveGapTo(pos int) {
-	if pos < 0 {
-		pos = 0
-	}
-	if pos > b.Length() {
-		pos = b.Length()
-	}
-
-	if pos < b.gapStart {
-		// Move gap left: shift data from [pos, gapStart) to after gap
-		moveCount := b.gapStart - pos
-		copy(b.data[b.gapEnd-moveCount:b.gapEnd], b.data[pos:b.gapStart])
-		b.gapStart = pos
-		b.gapEnd -= moveCount
-	} else if pos > b.gapStart {
-		// Move gap right: shift data from [gapEnd, gapEnd+moveCount) to before gap
-		moveCount := pos - b.gapStart
-		copy(b.data[b.gapStart:b.gapStart+moveCount], b.data[b.gapEnd:b.gapEnd+moveCount])
-		b.gapStart += moveCount
-		b.gapEnd += moveCount
-	}
+// Content returns the full buffer content as a string.
+func (b *Buffer) Content() string {
+	result := make([]rune, 0, b.Length())
+	result = ropeAppendRange(result, b.root, 0, b.Length())
+	return string(result)
 }
 
-// expandGap ensures the gap has at least minSize capacity.
-func (b *Buffer) expandGap(minSize int) {
-	if b.gapSize() >= minSize {
-		return
-	}
-
-	// Double the gap size or use minSize, whichever is larger
-	newGapSize := b.gapSize() * 2
-	if newGapSize < minSize {
-		newGapSize = minSize
-	}
-	if newGapSize < initialGapSize {
-		newGapSize = initialGapSize
-	}
-
-	additionalSpace := newGapSize - b.gapSize()
-	newData := make([]rune, len(b.data)+additionalSpace)
-
-	// Copy data before gap
-	copy(newData, b.data[:b.gapStart])
-	// Copy data after gap to new position
-	copy(newData[b.gapStart+newGapSize:], b.data[b.gapEnd:])
-
-	b.gapEnd = b.gapStart + newGapSize
-	b.data = newData
+// Length returns the number of runes in the buffer.
+func (b *Buffer) Length() int {
+	return ropeLength(b.root)
 }
 
 // Insert inserts text at the specified position.
@@ -156,14 +185,17 @@ func (b *Buffer) Insert(pos int, text string) {
 		return
 	}
 
-	runes := []rune(text)
-	b.moveGapTo(pos)
-	b.expandGap(len(runes))
+	if pos < 0 {
+		pos = 0
+	}
+	if pos > b.Length() {
+		pos = b.Length()
+	}
 
-	copy(b.data[b.gapStart:], runes)
-	b.gapStart += len(runes)
+	runes := []rune(text)
+	b.root = ropeInsert(b.root, pos, runes)
 	b.modified = true
-	b.rebuildLineIndex()
+	b.notifyEdit(pos, 0, len(runes))
 }
 
 // Delete removes count runes starting at pos.
@@ -176,13 +208,10 @@ func (b *Buffer) Delete(pos, count int) string {
 		count = b.Length() - pos
 	}
 
-	b.moveGapTo(pos)
-
-	// The deleted text is now at gapEnd
-	deleted := string(b.data[b.gapEnd : b.gapEnd+count])
-	b.gapEnd += count
+	deleted := b.Substring(pos, pos+count)
+	b.root = ropeDelete(b.root, pos, pos+count)
 	b.modified = true
-	b.rebuildLineIndex()
+	b.notifyEdit(pos, count, 0)
 
 	return deleted
 }
@@ -192,11 +221,7 @@ func (b *Buffer) RuneAt(pos int) rune {
 	if pos < 0 || pos >= b.Length() {
 		return 0
 	}
-
-	if pos < b.gapStart {
-		return b.data[pos]
-	}
-	return b.data[pos+(b.gapEnd-b.gapStart)]
+	return ropeRuneAt(b.root, pos)
 }
 
 // Substring returns a substring from start to end positions.
@@ -211,77 +236,61 @@ func (b *Buffer) Substring(start, end int) string {
 		return ""
 	}
 
-	result := make([]rune, end-start)
-	for i := start; i < end; i++ {
-		result[i-start] = b.RuneAt(i)
-	}
+	result := make([]rune, 0, end-start)
+	result = ropeAppendRange(result, b.root, start, end)
 	return string(result)
 }
 
-// rebuildLineIndex rebuilds the line start position cache.
-func (b *Buffer) rebuildLineIndex() {
-	b.lines = []int{0}
-	pos := 0
+// lineStart returns the offset of the first rune of the given line.
+// The line must be in [0, LineCount()).
+func (b *Buffer) lineStart(lineNum int) int {
+	if lineNum <= 0 {
+		return 0
+	}
+	return ropeNewlineOffset(b.root, lineNum) + 1
+}
 
-	for i := 0; i < b.Length(); i++ {
-		r := b.RuneAt(i)
-		pos++
-		if r == '\n' {
-			b.lines = append(b.lines, i+1)
-		}
+// lineEnd returns the offset just past the last rune of the given line,
+// excluding its newline.
+func (b *Buffer) lineEnd(lineNum int) int {
+	if lineNum+1 < b.LineCount() {
+		return ropeNewlineOffset(b.root, lineNum+1)
 	}
+	return b.Length()
 }
 
 // LineCount returns the number of lines in the buffer.
 func (b *Buffer) LineCount() int {
-	return len(b.lines)
+	return ropeNewlines(b.root) + 1
 }
 
 // Line returns the content of the specified line (0-indexed).
 func (b *Buffer) Line(lineNum int) string {
-	if lineNum < 0 || lineNum >= len(b.lines) {
+	if lineNum < 0 || lineNum >= b.LineCount() {
 		return ""
 	}
-
-	start := b.lines[lineNum]
-	var end int
-	if lineNum+1 < len(b.lines) {
-		end = b.lines[lineNum+1] - 1 // Exclude newline
-	} else {
-		end = b.Length()
-	}
-
-	if end < start {
-		return ""
-	}
-
-	return b.Substring(start, end)
+	return b.Substring(b.lineStart(lineNum), b.lineEnd(lineNum))
 }
 
 // LineLength returns the length of the specified line (excluding newline).
 func (b *Buffer) LineLength(lineNum int) int {
-	return utf8.RuneCountInString(b.Line(lineNum))
+	if lineNum < 0 || lineNum >= b.LineCount() {
+		return 0
+	}
+	return b.lineEnd(lineNum) - b.lineStart(lineNum)
 }
 
 // PositionToOffset converts a line/column position to a buffer offset.
 func (b *Buffer) PositionToOffset(line, col int) int {
-	if line < 0 || line >= len(b.lines) {
+	if line < 0 || line >= b.LineCount() {
 		return 0
 	}
 
-	offset := b.lines[line] + col
-	maxOffset := b.Length()
+	offset := b.lineStart(line) + col
 
 	// Don't go past end of line
-	if line+1 < len(b.lines) {
-		lineEnd := b.lines[line+1] - 1
-		if offset > lineEnd {
-			offset = lineEnd
-		}
-	}
-
-	if offset > maxOffset {
-		offset = maxOffset
+	if lineEnd := b.lineEnd(line); offset > lineEnd {
+		offset = lineEnd
 	}
 	if offset < 0 {
 		offset = 0
@@ -296,25 +305,12 @@ func (b *Buffer) OffsetToPosition(offset int) (line, col int) {
 		return 0, 0
 	}
 	if offset >= b.Length() {
-		if len(b.lines) == 0 {
-			return 0, 0
-		}
-		lastLine := len(b.lines) - 1
+		lastLine := b.LineCount() - 1
 		return lastLine, b.LineLength(lastLine)
 	}
 
-	// Binary search for the line
-	lo, hi := 0, len(b.lines)-1
-	for lo < hi {
-		mid := (lo + hi + 1) / 2
-		if b.lines[mid] <= offset {
-			lo = mid
-		} else {
-			hi = mid - 1
-		}
-	}
-
-	return lo, offset - b.lines[lo]
+	line = ropeNewlinesBefore(b.root, offset)
+	return line, offset - b.lineStart(line)
 }
 
 // Modified returns whether the buffer has been modified since last save.
@@ -342,55 +338,72 @@ func (b *Buffer) Encoding() string {
 	return b.encoding
 }
 
-// LineEnding returns the line ending style (LF or CRLF).
+// SetEncoding sets the character encoding SaveAs writes.
+func (b *Buffer) SetEncoding(encoding string) {
+	b.encoding = encoding
+}
+
+// LineEnding returns the line ending style (LF, CRLF or CR).
 func (b *Buffer) LineEnding() string {
 	return b.lineEnding
 }
 
-// Save writes the buffer content to the associated file.
+// SetLineEnding sets the line ending SaveAs writes.
+func (b *Buffer) SetLineEnding(lineEnding string) {
+	b.lineEnding = lineEnding
+}
+
+// Save writes the buffer content to the associated file. It returns
+// ErrChangedOnDisk instead if another program changed the file since the
+// buffer loaded or saved it; SaveAs writes regardless.
 func (b *Buffer) Save() error {
 	if b.filepath == "" {
 		return os.ErrInvalid
 	}
+	if change, _ := b.CheckDisk(); change == DiskChanged {
+		return ErrChangedOnDisk
+	}
 	return b.SaveAs(b.filepath)
 }
 
-// SaveAs writes the buffer content to the specified file.
-func (b *Buffer) SaveAs(filepath string) error {
+// SaveAs writes the buffer content to the specified file as it is, in the
+// buffer's encoding and line ending. Changes such as a final newline are
+// made beforehand by the editor's save pipeline. The file is replaced
+// atomically and keeps its permissions; a symlink keeps pointing to it.
+func (b *Buffer) SaveAs(path string) error {
 	content := b.Content()
 
-	// Ensure file ends with newline (POSIX standard)
-	if len(content) > 0 && !strings.HasSuffix(content, "\n") {
-		content += "\n"
-	}
-
 	// Convert line endings if necessary
-	if b.lineEnding == LineEndingCRLF {
-		content = strings.ReplaceAll(content, "\n", "\r\n")
+	if b.lineEnding != LineEndingLF {
+		content = strings.ReplaceAll(content, "\n", b.lineEnding)
 	}
+	data := encodeText(content, b.encoding)
 
-	file, err := os.Create(filepath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := bufio.NewWriter(file)
-	_, err = writer.WriteString(content)
-	if err != nil {
-		return err
+	target := path
+	perm := os.FileMode(0644)
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		target = resolved
+		if info, err := os.Stat(target); err == nil {
+			perm = info.Mode().Perm()
+		}
 	}
-
-	err = writer.Flush()
-	if err != nil {
+	if err := atomicfile.Write(target, data, perm); err != nil {
 		return err
 	}
 
-	b.filepath = filepath
+	b.filepath = path
 	b.modified = false
+	b.saves++
+	b.disk, _ = FileDiskState(path, data)
 	return nil
 }
 
+// Disk returns the state of the file when the buffer last loaded or saved
+// it. It is zero for buffers never read from or written to disk.
+func (b *Buffer) Disk() DiskState {
+	return b.disk
+}
+
 // WordAt returns the word at the given position and its start/end offsets.
 func (b *Buffer) WordAt(pos int) (word string, start, end int) {
 	if pos < 0 || pos >= b.Length() {
@@ -420,85 +433,40 @@ func isWordChar(r rune) bool {
 		r == '_'
 }
 
-// FindNext finds the next occurrence of text starting from pos.
-// Returns -1 if not found.
-func (b *Buffer) FindNext(text string, pos int, caseSensitive bool) int {
-	if text == "" {
-		return -1
-	}
-
-	content := b.Content()
-	searchText := text
-
-	if !caseSensitive {
-		content = strings.ToLower(content)
-		searchText = strings.ToLower(text)
-	}
-
-	if pos < 0 {
-		pos = 0
-	}
-
-	idx := strings.Index(content[pos:], searchText)
-	if idx == -1 {
-		return -1
-	}
-	return pos + idx
+// FindAll returns all matches of a search in the buffer.
+func (b *Buffer) FindAll(search *Search) []Match {
+	return search.Matches(b.Content())
 }
 
-// FindPrevious finds the previous occurrence of text before pos.
-// Returns -1 if not found.
-func (b *Buffer) FindPrevious(text string, pos int, caseSensitive bool) int {
-	if text == "" || pos <= 0 {
-		return -1
-	}
-
-	content := b.Content()
-	searchText := text
-
-	if !caseSensitive {
-		content = strings.ToLower(content)
-		searchText = strings.ToLower(text)
-	}
-
-	if pos > len(content) {
-		pos = len(content)
+// search returns the matches within [start, end). Match offsets are relative
+// to the buffer, but cannot be expanded as replacements.
+func (b *Buffer) search(search *Search, start, end int) []Match {
+	matches := search.Matches(b.Substring(start, end))
+	for i := range matches {
+		matches[i].Start += start
+		matches[i].End += start
+		matches[i].loc = nil
 	}
-
-	idx := strings.LastIndex(content[:pos], searchText)
-	return idx
+	return matches
 }
 
-// Replace replaces the first occurrence of old with new starting from pos.
-// Returns the position after replacement, or -1 if not found.
-func (b *Buffer) Replace(old, new string, pos int, caseSensitive bool) int {
-	found := b.FindNext(old, pos, caseSensitive)
-	if found == -1 {
-		return -1
+// FindNext returns the first match starting at or after pos.
+func (b *Buffer) FindNext(search *Search, pos int) (Match, bool) {
+	for _, m := range b.FindAll(search) {
+		if m.Start >= pos {
+			return m, true
+		}
 	}
-
-	b.Delete(found, len([]rune(old)))
-	b.Insert(found, new)
-	return found + len([]rune(new))
+	return Match{}, false
 }
 
-// ReplaceAll replaces all occurrences of old with new.
-// Returns the number of replacements made.
-func (b *Buffer) ReplaceAll(old, new string, caseSensitive bool) int {
-	count := 0
-	pos := 0
-
-	for {
-		found := b.FindNext(old, pos, caseSensitive)
-		if found == -1 {
-			break
+// FindPrevious returns the last match starting before pos.
+func (b *Buffer) FindPrevious(search *Search, pos int) (Match, bool) {
+	matches := b.FindAll(search)
+	for i := len(matches) - 1; i >= 0; i-- {
+		if matches[i].Start < pos {
+			return matches[i], true
 		}
-
-		b.Delete(found, len([]rune(old)))
-		b.Insert(found, new)
-		pos = found + len([]rune(new))
-		count++
 	}
-
-	return count
+	return Match{}, false
 }
@@ -0,0 +1,54 @@
+package editor
+
+import "sort"
+
+// Caret pairs a cursor with its own selection. A tab always has a primary
+// caret (its Cursor and Selection) and may have secondary carets for
+// multi-cursor editing.
+type Caret struct {
+	Cursor    *Cursor
+	Selection *Selection
+}
+
+// newCaretAt creates a caret at pos without a selection.
+func newCaretAt(pos Position) *Caret {
+	c := &Caret{Cursor: NewCursor(), Selection: NewSelection()}
+	c.Cursor.SetPosition(pos.Line, pos.Column)
+	return c
+}
+
+// clone returns an independent copy of the caret.
+func (c *Caret) clone() *Caret {
+	cursor := *c.Cursor
+	selection := *c.Selection
+	return &Caret{Cursor: &cursor, Selection: &selection}
+}
+
+// hasSelection returns true if the caret selects any text.
+func (c *Caret) hasSelection() bool {
+	return c.Selection.Active && !c.Selection.IsEmpty()
+}
+
+// span returns the range covered by the caret: its selection, or the cursor
+// position when nothing is selected.
+func (c *Caret) span() (start, end Position) {
+	if c.hasSelection() {
+		return c.Selection.Normalized()
+	}
+	pos := c.Cursor.Position()
+	return pos, pos
+}
+
+// positionBefore returns true if a comes before b in the buffer.
+func positionBefore(a, b Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+// sortCarets orders carets by their position in the buffer.
+func sortCarets(carets []*Caret) {
+	sort.SliceStable(carets, func(i, j int) bool {
+		a, _ := carets[i].span()
+		b, _ := carets[j].span()
+		return positionBefore(a, b)
+	})
+}
@@ -0,0 +1,119 @@
+package editor
+
+import (
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// Columns of a Position count runes, while the screen counts cells: wide
+// characters such as CJK and most emoji take two cells, combining marks
+// take none and join the character before them, and a tab reaches the next
+// tab stop. A glyph is one character as drawn, and the cursor only stops
+// between glyphs.
+type glyph struct {
+	start, end int    // Columns of its runes, [start, end)
+	x, width   int    // First cell and number of cells
+	text       string // What to draw in the first cell
+}
+
+// layoutLine splits a line into glyphs, with tab stops every tabWidth
+// cells.
+func layoutLine(line string, tabWidth int) []glyph {
+	glyphs := make([]glyph, 0, len(line))
+	col, x, state := 0, 0, -1
+	for line != "" {
+		var cluster string
+		var width int
+		cluster, line, width, state = uniseg.FirstGraphemeClusterInString(line, state)
+		g := glyph{start: col, end: col + utf8.RuneCountInString(cluster), x: x, width: width, text: cluster}
+		switch {
+		case cluster == "\t":
+			g.width = tabWidth - x%tabWidth
+		case width == 0:
+			// Control characters and stray marks get a cell of their own
+			// so the cursor can reach them
+			g.width, g.text = 1, " "
+		}
+		glyphs = append(glyphs, g)
+		col, x = g.end, x+g.width
+	}
+	return glyphs
+}
+
+// glyphAt returns the index of the glyph containing column col, or
+// len(glyphs) at the end of the line.
+func glyphAt(glyphs []glyph, col int) int {
+	for i, g := range glyphs {
+		if col < g.end {
+			return i
+		}
+	}
+	return len(glyphs)
+}
+
+// lineWidth returns the number of cells the glyphs take.
+func lineWidth(glyphs []glyph) int {
+	if len(glyphs) == 0 {
+		return 0
+	}
+	last := glyphs[len(glyphs)-1]
+	return last.x + last.width
+}
+
+// cellOf returns the first cell of the glyph containing column col. At or
+// beyond the end of the line it counts one cell per column.
+func cellOf(glyphs []glyph, col int) int {
+	if i := glyphAt(glyphs, col); i < len(glyphs) {
+		return glyphs[i].x
+	}
+	end := 0
+	if len(glyphs) > 0 {
+		end = glyphs[len(glyphs)-1].end
+	}
+	return lineWidth(glyphs) + max(col-end, 0)
+}
+
+// columnAtCell returns the column of the glyph drawn at cell x, or the end
+// of the line if x is beyond it.
+func columnAtCell(glyphs []glyph, x int) int {
+	for _, g := range glyphs {
+		if x < g.x+g.width {
+			return g.start
+		}
+	}
+	if len(glyphs) == 0 {
+		return 0
+	}
+	return glyphs[len(glyphs)-1].end
+}
+
+// displayColumn returns the cell at which column col of line is drawn.
+func displayColumn(line string, col, tabWidth int) int {
+	return cellOf(layoutLine(line, tabWidth), col)
+}
+
+// prevGlyphStart returns the column where the glyph before column col
+// starts.
+func prevGlyphStart(line string, col int) int {
+	for _, g := range layoutLine(line, 1) {
+		if g.end >= col {
+			return g.start
+		}
+	}
+	return max(col-1, 0)
+}
+
+// nextGlyphEnd returns the column where the glyph at column col ends.
+func nextGlyphEnd(line string, col int) int {
+	glyphs := layoutLine(line, 1)
+	if i := glyphAt(glyphs, col); i < len(glyphs) {
+		return glyphs[i].end
+	}
+	return col + 1
+}
+
+// layout returns the glyphs of a line of the active buffer.
+func (e *Editor) layout(lineNum int) []glyph {
+	return layoutLine(e.buffer().Line(lineNum), e.TabWidth())
+}
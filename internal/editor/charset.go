@@ -0,0 +1,94 @@
+package editor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+)
+
+// Character encodings a buffer can be read and written in.
+const (
+	EncodingUTF8    = "UTF-8"
+	EncodingUTF8BOM = "UTF-8 BOM"
+	EncodingLatin1  = "ISO-8859-1"
+	EncodingUTF16BE = "UTF-16 BE"
+	EncodingUTF16LE = "UTF-16 LE"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// decodeText converts file content in an encoding to text. A byte order
+// mark is dropped.
+func decodeText(data []byte, encoding string) string {
+	switch encoding {
+	case EncodingLatin1:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	case EncodingUTF16BE, EncodingUTF16LE:
+		var order binary.ByteOrder = binary.BigEndian
+		if encoding == EncodingUTF16LE {
+			order = binary.LittleEndian
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		return strings.TrimPrefix(string(utf16.Decode(units)), "\uFEFF")
+	case EncodingUTF8BOM:
+		return string(bytes.TrimPrefix(data, utf8BOM))
+	}
+	return string(data)
+}
+
+// encodeText converts text to file content in an encoding. UTF-16 starts
+// with a byte order mark; characters Latin-1 cannot represent are written
+// as ?.
+func encodeText(text, encoding string) []byte {
+	switch encoding {
+	case EncodingLatin1:
+		data := make([]byte, 0, len(text))
+		for _, r := range text {
+			if r > 0xFF {
+				r = '?'
+			}
+			data = append(data, byte(r))
+		}
+		return data
+	case EncodingUTF16BE, EncodingUTF16LE:
+		var order binary.ByteOrder = binary.BigEndian
+		if encoding == EncodingUTF16LE {
+			order = binary.LittleEndian
+		}
+		units := utf16.Encode([]rune("\uFEFF" + text))
+		data := make([]byte, 2*len(units))
+		for i, u := range units {
+			order.PutUint16(data[2*i:], u)
+		}
+		return data
+	case EncodingUTF8BOM:
+		return append(append([]byte(nil), utf8BOM...), text...)
+	}
+	return []byte(text)
+}
+
+// encodingFromCharset returns the encoding for an .editorconfig charset,
+// or "" for none.
+func encodingFromCharset(charset string) string {
+	switch charset {
+	case "utf-8":
+		return EncodingUTF8
+	case "utf-8-bom":
+		return EncodingUTF8BOM
+	case "latin1":
+		return EncodingLatin1
+	case "utf-16be":
+		return EncodingUTF16BE
+	case "utf-16le":
+		return EncodingUTF16LE
+	}
+	return ""
+}
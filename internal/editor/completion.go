@@ -0,0 +1,266 @@
+package editor
+
+import (
+	"path/filepath"
+	"slices"
+	"unicode/utf8"
+)
+
+// minWordLength is the length of the shortest word the word provider
+// offers.
+const minWordLength = 2
+
+// Completion is a candidate offered at the cursor.
+type Completion struct {
+	Label  string // Shown in the popup and matched against the prefix
+	Detail string // Kind or signature, may be empty
+	Text   string // Inserted in place of the prefix; Label if empty
+}
+
+// CompletionRequest describes where completions are requested.
+type CompletionRequest struct {
+	Buffer *Buffer // Active buffer
+	Path   string  // Absolute path of the active file, "" if untitled
+	Line   int
+	Column int    // Rune column of the cursor
+	Prefix string // Word before the cursor, may be empty
+	Word   string // Whole word at the cursor, including the prefix
+}
+
+// CompletionProvider supplies completion candidates. Providers are called
+// on the UI goroutine whenever the popup is refreshed, so they must be
+// fast; the candidates are filtered by the popup, not the provider.
+type CompletionProvider interface {
+	Complete(req CompletionRequest) []Completion
+}
+
+// AddCompletionProvider registers a provider. Candidates of earlier
+// providers win over later ones with the same label.
+func (e *Editor) AddCompletionProvider(p CompletionProvider) {
+	e.completionProviders = append(e.completionProviders, p)
+}
+
+// Completions returns the word before the cursor and the candidates of all
+// providers, without duplicates and without the prefix itself.
+func (e *Editor) Completions() (prefix string, items []Completion) {
+	req := e.completionRequest()
+	seen := map[string]bool{req.Prefix: true}
+	for _, p := range e.completionProviders {
+		for _, item := range p.Complete(req) {
+			if item.Text == "" {
+				item.Text = item.Label
+			}
+			if seen[item.Label] {
+				continue
+			}
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+	return req.Prefix, items
+}
+
+// ApplyCompletion replaces the word before every caret with text as a
+// single undo step. A caret's selection is replaced instead.
+func (e *Editor) ApplyCompletion(text string) {
+	buf := e.buffer()
+	e.editCarets(func(_ int, c *Caret) {
+		if c.hasSelection() {
+			e.insertTextAt(c, text)
+			return
+		}
+		offset := c.Cursor.Offset(buf)
+		start := offset - utf8.RuneCountInString(wordBefore(buf.Line(c.Cursor.Line), c.Cursor.Column))
+		e.replaceRange(start, offset, text)
+		line, col := buf.OffsetToPosition(start + utf8.RuneCountInString(text))
+		c.Cursor.MoveTo(line, col, buf)
+	})
+}
+
+// WordBeforeCursor returns the word characters before the cursor.
+func (e *Editor) WordBeforeCursor() string {
+	cursor := e.cursor()
+	return wordBefore(e.buffer().Line(cursor.Line), cursor.Column)
+}
+
+// CursorScreenPosition returns where the word before the cursor starts,
+// relative to the editor's top left corner. ok is false if it is scrolled
+// out of view.
+func (e *Editor) CursorScreenPosition() (x, y int, ok bool) {
+	cursor := e.cursor()
+	col := cursor.Column - utf8.RuneCountInString(e.WordBeforeCursor())
+	return e.screenPosition(Position{Line: cursor.Line, Column: col})
+}
+
+// completionRequest describes the primary cursor's position.
+func (e *Editor) completionRequest() CompletionRequest {
+	cursor := e.cursor()
+	text := e.buffer().Line(cursor.Line)
+	prefix := e.WordBeforeCursor()
+
+	// The rest of the word after the cursor
+	word := prefix
+	for i, r := range []rune(text) {
+		if i < cursor.Column {
+			continue
+		}
+		if !isWordChar(r) {
+			break
+		}
+		word += string(r)
+	}
+
+	req := CompletionRequest{
+		Buffer: e.buffer(),
+		Line:   cursor.Line,
+		Column: cursor.Column,
+		Prefix: prefix,
+		Word:   word,
+	}
+	if e.Filepath() != "" {
+		if path, err := filepath.Abs(e.Filepath()); err == nil {
+			req.Path = path
+		}
+	}
+	return req
+}
+
+// wordBefore returns the word characters before rune column col of text.
+func wordBefore(text string, col int) string {
+	runes := []rune(text)
+	col = min(col, len(runes))
+	start := col
+	for start > 0 && isWordChar(runes[start-1]) {
+		start--
+	}
+	return string(runes[start:col])
+}
+
+// bufferWords are the words of each line of a buffer, with their counts.
+// An edit listener keeps them up to date by collecting only the words of
+// the changed lines again.
+type bufferWords struct {
+	buf            *Buffer
+	lines          [][]string
+	counts         map[string]int
+	removeListener func()
+}
+
+// newBufferWords collects the words of buf and starts following its edits.
+func newBufferWords(buf *Buffer) *bufferWords {
+	w := &bufferWords{buf: buf, counts: make(map[string]int)}
+	w.lines = make([][]string, buf.LineCount())
+	for i := range w.lines {
+		w.lines[i] = lineWords(buf.Line(i))
+		w.count(w.lines[i], 1)
+	}
+	w.removeListener = buf.AddEditListener(w.bufferEdited)
+	return w
+}
+
+// bufferEdited collects the words of the lines touched by an edit again.
+func (w *bufferWords) bufferEdited(offset, removed, inserted int) {
+	// The edit replaced the old lines start..oldEnd by start..end
+	start, _ := w.buf.OffsetToPosition(offset)
+	end, _ := w.buf.OffsetToPosition(offset + inserted)
+	oldEnd := end - (w.buf.LineCount() - len(w.lines))
+
+	changed := make([][]string, end-start+1)
+	for i := range changed {
+		changed[i] = lineWords(w.buf.Line(start + i))
+		w.count(changed[i], 1)
+	}
+	for _, words := range w.lines[start : oldEnd+1] {
+		w.count(words, -1)
+	}
+	w.lines = slices.Replace(w.lines, start, oldEnd+1, changed...)
+}
+
+// count adds n to the counts of words.
+func (w *bufferWords) count(words []string, n int) {
+	for _, word := range words {
+		w.counts[word] += n
+		if w.counts[word] == 0 {
+			delete(w.counts, word)
+		}
+	}
+}
+
+// wordProvider offers the identifiers of all open buffers. The words of a
+// buffer are collected once and then updated line by line as it changes.
+type wordProvider struct {
+	tabs  *TabManager
+	cache map[*Buffer]*bufferWords
+}
+
+// newWordProvider creates the built-in provider for the buffers of tabs.
+func newWordProvider(tabs *TabManager) *wordProvider {
+	return &wordProvider{tabs: tabs, cache: make(map[*Buffer]*bufferWords)}
+}
+
+// Complete implements CompletionProvider. The word being typed is not
+// offered unless it also occurs elsewhere.
+func (p *wordProvider) Complete(req CompletionRequest) []Completion {
+	counts := make(map[string]int)
+	open := make(map[*Buffer]bool)
+	for _, tab := range p.tabs.Tabs() {
+		buf := tab.Buffer()
+		if open[buf] {
+			continue
+		}
+		open[buf] = true
+		for word, n := range p.words(buf).counts {
+			counts[word] += n
+		}
+	}
+	for buf, w := range p.cache {
+		if !open[buf] {
+			w.removeListener()
+			delete(p.cache, buf)
+		}
+	}
+	if open[req.Buffer] {
+		counts[req.Word]--
+	}
+
+	var items []Completion
+	for word, n := range counts {
+		if n > 0 {
+			items = append(items, Completion{Label: word})
+		}
+	}
+	return items
+}
+
+// words returns the words of a buffer, collecting them the first time.
+func (p *wordProvider) words(buf *Buffer) *bufferWords {
+	w := p.cache[buf]
+	if w == nil {
+		w = newBufferWords(buf)
+		p.cache[buf] = w
+	}
+	return w
+}
+
+// lineWords returns the identifiers in a line: runs of word characters
+// that do not start with a digit and are at least minWordLength long.
+func lineWords(text string) []string {
+	var words []string
+	start := -1
+	for i, r := range text + " " {
+		if isWordChar(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			word := text[start:i]
+			if len(word) >= minWordLength && (word[0] < '0' || word[0] > '9') {
+				words = append(words, word)
+			}
+			start = -1
+		}
+	}
+	return words
+}
@@ -8,17 +8,26 @@ type Position struct {
 
 // Cursor manages the cursor position and movement within a buffer.
 type Cursor struct {
-	Line         int
-	Column       int
-	PreferredCol int // Preferred column for vertical movement
+	Line   int
+	Column int
+
+	// Cell kept when moving between lines, as long as the cursor is still
+	// where the last such move left it
+	preferredX    int
+	preferredAt   Position
+	hasPreferredX bool
+
+	// Cell kept when moving by wrapped rows, likewise
+	rowX    int
+	rowXAt  Position
+	hasRowX bool
 }
 
 // NewCursor creates a new cursor at position (0, 0).
 func NewCursor() *Cursor {
 	return &Cursor{
-		Line:         0,
-		Column:       0,
-		PreferredCol: 0,
+		Line:   0,
+		Column: 0,
 	}
 }
 
@@ -31,7 +40,6 @@ func (c *Cursor) Position() Position {
 func (c *Cursor) SetPosition(line, col int) {
 	c.Line = line
 	c.Column = col
-	c.PreferredCol = col
 }
 
 // MoveTo moves the cursor to the specified position, clamping to buffer bounds.
@@ -59,58 +67,73 @@ func (c *Cursor) MoveTo(line, col int, buf *Buffer) {
 
 	c.Line = line
 	c.Column = col
-	c.PreferredCol = col
 }
 
-// MoveLeft moves the cursor one position to the left.
+// MoveLeft moves the cursor one character to the left.
 func (c *Cursor) MoveLeft(buf *Buffer) {
 	if c.Column > 0 {
-		c.Column--
+		c.Column = prevGlyphStart(buf.Line(c.Line), c.Column)
 	} else if c.Line > 0 {
 		c.Line--
 		c.Column = buf.LineLength(c.Line)
 	}
-	c.PreferredCol = c.Column
 }
 
-// MoveRight moves the cursor one position to the right.
+// MoveRight moves the cursor one character to the right.
 func (c *Cursor) MoveRight(buf *Buffer) {
 	lineLen := buf.LineLength(c.Line)
 	if c.Column < lineLen {
-		c.Column++
+		c.Column = nextGlyphEnd(buf.Line(c.Line), c.Column)
 	} else if c.Line < buf.LineCount()-1 {
 		c.Line++
 		c.Column = 0
 	}
-	c.PreferredCol = c.Column
 }
 
-// MoveUp moves the cursor one line up.
-func (c *Cursor) MoveUp(buf *Buffer) {
+// MoveUp moves the cursor one line up, keeping its cell.
+func (c *Cursor) MoveUp(buf *Buffer, tabWidth int) {
 	if c.Line > 0 {
-		c.Line--
-		c.Column = c.clampColumn(c.PreferredCol, buf)
+		c.moveToLine(c.Line-1, buf, tabWidth)
 	}
 }
 
-// MoveDown moves the cursor one line down.
-func (c *Cursor) MoveDown(buf *Buffer) {
+// MoveDown moves the cursor one line down, keeping its cell.
+func (c *Cursor) MoveDown(buf *Buffer, tabWidth int) {
 	if c.Line < buf.LineCount()-1 {
-		c.Line++
-		c.Column = c.clampColumn(c.PreferredCol, buf)
+		c.moveToLine(c.Line+1, buf, tabWidth)
+	}
+}
+
+// PreferredX returns the cell the cursor keeps when moving between lines.
+func (c *Cursor) PreferredX(buf *Buffer, tabWidth int) int {
+	if !c.hasPreferredX || c.preferredAt != c.Position() {
+		c.preferredX = displayColumn(buf.Line(c.Line), c.Column, tabWidth)
 	}
+	return c.preferredX
+}
+
+// moveToLine moves the cursor to the character of a line drawn at its
+// preferred cell.
+func (c *Cursor) moveToLine(line int, buf *Buffer, tabWidth int) {
+	c.MoveToCell(line, c.PreferredX(buf, tabWidth), buf, tabWidth)
+}
+
+// MoveToCell moves the cursor to the character of a line drawn at cell x,
+// and keeps x for further moves between lines.
+func (c *Cursor) MoveToCell(line, x int, buf *Buffer, tabWidth int) {
+	c.Line = line
+	c.Column = columnAtCell(layoutLine(buf.Line(line), tabWidth), x)
+	c.preferredX, c.preferredAt, c.hasPreferredX = x, c.Position(), true
 }
 
 // MoveToLineStart moves the cursor to the beginning of the current line.
 func (c *Cursor) MoveToLineStart() {
 	c.Column = 0
-	c.PreferredCol = 0
 }
 
 // MoveToLineEnd moves the cursor to the end of the current line.
 func (c *Cursor) MoveToLineEnd(buf *Buffer) {
 	c.Column = buf.LineLength(c.Line)
-	c.PreferredCol = c.Column
 }
 
 // MoveToFirstNonWhitespace moves cursor to first non-whitespace character.
@@ -119,13 +142,11 @@ func (c *Cursor) MoveToFirstNonWhitespace(buf *Buffer) {
 	for i, r := range line {
 		if r != ' ' && r != '\t' {
 			c.Column = i
-			c.PreferredCol = i
 			return
 		}
 	}
 	// If all whitespace or empty, go to start
 	c.Column = 0
-	c.PreferredCol = 0
 }
 
 // MoveWordLeft moves the cursor to the beginning of the previous word.
@@ -148,7 +169,6 @@ func (c *Cursor) MoveWordLeft(buf *Buffer) {
 	}
 
 	c.Line, c.Column = buf.OffsetToPosition(offset)
-	c.PreferredCol = c.Column
 }
 
 // MoveWordRight moves the cursor to the beginning of the next word.
@@ -171,14 +191,12 @@ func (c *Cursor) MoveWordRight(buf *Buffer) {
 	}
 
 	c.Line, c.Column = buf.OffsetToPosition(offset)
-	c.PreferredCol = c.Column
 }
 
 // MoveToBufferStart moves the cursor to the beginning of the buffer.
 func (c *Cursor) MoveToBufferStart() {
 	c.Line = 0
 	c.Column = 0
-	c.PreferredCol = 0
 }
 
 // MoveToBufferEnd moves the cursor to the end of the buffer.
@@ -188,7 +206,6 @@ func (c *Cursor) MoveToBufferEnd(buf *Buffer) {
 		c.Line = 0
 	}
 	c.Column = buf.LineLength(c.Line)
-	c.PreferredCol = c.Column
 }
 
 // MoveToLine moves the cursor to the specified line (1-indexed for UI).
@@ -204,38 +221,16 @@ func (c *Cursor) MoveToLine(lineNum int, buf *Buffer) {
 	}
 	c.Line = line
 	c.Column = 0
-	c.PreferredCol = 0
 }
 
 // PageUp moves the cursor up by pageSize lines.
-func (c *Cursor) PageUp(pageSize int, buf *Buffer) {
-	c.Line -= pageSize
-	if c.Line < 0 {
-		c.Line = 0
-	}
-	c.Column = c.clampColumn(c.PreferredCol, buf)
+func (c *Cursor) PageUp(pageSize int, buf *Buffer, tabWidth int) {
+	c.moveToLine(max(c.Line-pageSize, 0), buf, tabWidth)
 }
 
 // PageDown moves the cursor down by pageSize lines.
-func (c *Cursor) PageDown(pageSize int, buf *Buffer) {
-	c.Line += pageSize
-	maxLine := buf.LineCount() - 1
-	if c.Line > maxLine {
-		c.Line = maxLine
-	}
-	c.Column = c.clampColumn(c.PreferredCol, buf)
-}
-
-// clampColumn clamps a column value to be within the current line.
-func (c *Cursor) clampColumn(col int, buf *Buffer) int {
-	lineLen := buf.LineLength(c.Line)
-	if col > lineLen {
-		return lineLen
-	}
-	if col < 0 {
-		return 0
-	}
-	return col
+func (c *Cursor) PageDown(pageSize int, buf *Buffer, tabWidth int) {
+	c.moveToLine(max(min(c.Line+pageSize, buf.LineCount()-1), 0), buf, tabWidth)
 }
 
 // Offset returns the cursor position as a buffer offset.
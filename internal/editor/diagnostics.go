@@ -0,0 +1,270 @@
+package editor
+
+import (
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/DDZ-DO/vex/internal/lint"
+	"github.com/DDZ-DO/vex/internal/lsp"
+)
+
+// Diagnostic is a problem in a file reported by a linter or a language
+// server. Lines and columns are zero-based; columns count runes. The range
+// ends at EndLine, EndColumn (exclusive).
+type Diagnostic struct {
+	Path      string // Absolute file path
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+	Severity  lint.Severity
+	Message   string
+	Source    string
+}
+
+// covers returns the columns of line covered by the diagnostic, or false.
+func (d Diagnostic) covers(line, lineLen int) (start, end int, ok bool) {
+	if line < d.Line || line > d.EndLine {
+		return 0, 0, false
+	}
+	start, end = 0, lineLen
+	if line == d.Line {
+		start = d.Column
+	}
+	if line == d.EndLine {
+		end = d.EndColumn
+	}
+	return start, end, true
+}
+
+// SetLintProblems stores the problems found by a linter run, replacing
+// those of the previous run with the same scope (see lint.Linter.Scope).
+func (e *Editor) SetLintProblems(scope string, problems []lint.Problem) {
+	if len(problems) == 0 {
+		delete(e.lintProblems, scope)
+		return
+	}
+	e.lintProblems[scope] = problems
+}
+
+// SavedFiles returns the absolute paths of the files saved since the last
+// call.
+func (e *Editor) SavedFiles() []string {
+	var saved []string
+	open := make(map[*TabState]bool)
+	for _, tab := range e.tabManager.Tabs() {
+		open[tab] = true
+		count := tab.Buffer().SaveCount()
+		if count == e.saveCounts[tab] {
+			continue
+		}
+		e.saveCounts[tab] = count
+		if path, err := filepath.Abs(tab.Filepath()); err == nil && tab.Filepath() != "" {
+			saved = append(saved, path)
+		}
+	}
+	for tab := range e.saveCounts {
+		if !open[tab] {
+			delete(e.saveCounts, tab)
+		}
+	}
+	return saved
+}
+
+// Diagnostics returns the diagnostics of every file from linters and
+// language servers, sorted by file and position.
+func (e *Editor) Diagnostics() []Diagnostic {
+	return e.collectDiagnostics("")
+}
+
+// activeDiagnostics returns the diagnostics of the active file.
+func (e *Editor) activeDiagnostics() []Diagnostic {
+	if e.Filepath() == "" {
+		return nil
+	}
+	path, err := filepath.Abs(e.Filepath())
+	if err != nil {
+		return nil
+	}
+	return e.collectDiagnostics(path)
+}
+
+// CursorDiagnostic returns the most severe diagnostic on the cursor's line.
+func (e *Editor) CursorDiagnostic() (Diagnostic, bool) {
+	line := e.cursor().Line
+	var best Diagnostic
+	found := false
+	for _, d := range e.activeDiagnostics() {
+		if line < d.Line || line > d.EndLine {
+			continue
+		}
+		if !found || d.Severity < best.Severity {
+			best, found = d, true
+		}
+	}
+	return best, found
+}
+
+// GoToDiagnostic opens the file of a diagnostic and puts the cursor at its
+// start.
+func (e *Editor) GoToDiagnostic(d Diagnostic) error {
+	e.beginJump()
+	defer e.endJump()
+	if err := e.LoadFile(d.Path); err != nil {
+		return err
+	}
+	e.jumpTo(d.Line, d.Column)
+	return nil
+}
+
+// collectDiagnostics converts the diagnostics of one file, or of all files
+// if path is "". Open files supply the line text to convert columns.
+func (e *Editor) collectDiagnostics(path string) []Diagnostic {
+	buffers := make(map[string]*Buffer)
+	for _, tab := range e.tabManager.Tabs() {
+		if tab.Filepath() == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(tab.Filepath()); err == nil {
+			buffers[abs] = tab.Buffer()
+		}
+	}
+
+	type key struct {
+		path    string
+		line    int
+		column  int
+		message string
+	}
+	seen := make(map[key]bool)
+	var diags []Diagnostic
+	add := func(d Diagnostic) {
+		k := key{d.Path, d.Line, d.Column, d.Message}
+		if !seen[k] {
+			seen[k] = true
+			diags = append(diags, d)
+		}
+	}
+
+	// Runs with overlapping scopes may report the same problem
+	for _, problems := range e.lintProblems {
+		for _, p := range problems {
+			if path == "" || p.Path == path {
+				add(lintDiagnostic(p, buffers[p.Path]))
+			}
+		}
+	}
+	if e.lsp != nil {
+		servers := e.lsp.AllDiagnostics()
+		for file, list := range servers {
+			if path != "" && file != path {
+				continue
+			}
+			for _, d := range list {
+				add(languageDiagnostic(file, d, buffers[file]))
+			}
+		}
+	}
+
+	sort.Slice(diags, func(i, j int) bool {
+		a, b := diags[i], diags[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return a.Severity < b.Severity
+	})
+	return diags
+}
+
+// lintDiagnostic converts a linter problem. Linters report a byte column
+// but no range: the word at the column is marked, or the whole line
+// without its indentation if there is no column. buf is the open file, or
+// nil.
+func lintDiagnostic(p lint.Problem, buf *Buffer) Diagnostic {
+	d := Diagnostic{
+		Path:     p.Path,
+		Line:     p.Line - 1,
+		Column:   max(p.Column-1, 0),
+		Severity: p.Severity,
+		Message:  p.Message,
+		Source:   p.Source,
+	}
+	d.EndLine = d.Line
+	if buf == nil || d.Line >= buf.LineCount() {
+		d.EndColumn = d.Column + 1
+		return d
+	}
+
+	text := buf.Line(d.Line)
+	if p.Column == 0 {
+		d.Column = 0
+		for _, r := range text {
+			if r != ' ' && r != '\t' {
+				break
+			}
+			d.Column++
+		}
+		d.EndColumn = utf8.RuneCountInString(text)
+		return d
+	}
+	d.Column = utf8.RuneCountInString(text[:min(p.Column-1, len(text))])
+	d.EndColumn = wordEnd(text, d.Column)
+	return d
+}
+
+// languageDiagnostic converts a language server diagnostic. buf is the
+// open file, or nil.
+func languageDiagnostic(path string, ld lsp.Diagnostic, buf *Buffer) Diagnostic {
+	d := Diagnostic{
+		Path:      path,
+		Line:      ld.Range.Start.Line,
+		Column:    ld.Range.Start.Character,
+		EndLine:   ld.Range.End.Line,
+		EndColumn: ld.Range.End.Character,
+		Message:   ld.Message,
+		Source:    ld.Source,
+	}
+	switch ld.Severity {
+	case lsp.SeverityWarning:
+		d.Severity = lint.SeverityWarning
+	case lsp.SeverityInformation, lsp.SeverityHint:
+		d.Severity = lint.SeverityInfo
+	default:
+		d.Severity = lint.SeverityError
+	}
+
+	if buf != nil && d.Line < buf.LineCount() {
+		text := buf.Line(d.Line)
+		d.Column = lsp.RuneColumn(text, d.Column)
+		if d.EndLine < buf.LineCount() {
+			d.EndColumn = lsp.RuneColumn(buf.Line(d.EndLine), d.EndColumn)
+		}
+		if d.EndLine == d.Line && d.EndColumn <= d.Column {
+			d.EndColumn = wordEnd(text, d.Column)
+		}
+	}
+	return d
+}
+
+// wordEnd returns the end of the word starting at rune column col, or
+// col+1 if there is no word there.
+func wordEnd(text string, col int) int {
+	end := col
+	for i, r := range []rune(text) {
+		if i < col {
+			continue
+		}
+		if !isWordChar(r) {
+			break
+		}
+		end = i + 1
+	}
+	return max(end, col+1)
+}
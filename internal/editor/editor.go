@@ -1,8 +1,12 @@
 package editor
 
 import (
+	"sort"
 	"strings"
 
+	"github.com/DDZ-DO/vex/internal/format"
+	"github.com/DDZ-DO/vex/internal/lint"
+	"github.com/DDZ-DO/vex/internal/lsp"
 	"github.com/DDZ-DO/vex/internal/syntax"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -24,39 +28,90 @@ type Editor struct {
 	height int
 
 	// Settings
-	tabWidth    int
-	showLineNum bool
-	wordWrap    bool
-
-	// Cached highlighted lines
+	tabWidth     int
+	insertSpaces bool
+	showLineNum  bool
+	wordWrap     bool
+	theme        *syntax.Theme
+
+	// Save pipeline settings
+	trimTrailingWhitespace bool
+	insertFinalNewline     bool
+	formatters             map[string]format.Formatter
+	formatJobs             []*FormatJob
+
+	// Cached highlighted lines, starting at line highlightStart
 	highlightedLines []syntax.StyledLine
+	highlightStart   int
 	highlightDirty   bool
 
 	// Line number gutter width
 	gutterWidth int
 
+	// Highlighted search and its matches in the active buffer
+	search  *Search
+	matches *matchTracker
+
+	// Language servers and the files they were sent
+	lsp       *lsp.Manager
+	documents map[*TabState]*languageDoc
+
+	// Linter problems by run scope, and save counts already linted
+	lintProblems map[string][]lint.Problem
+	saveCounts   map[*TabState]int
+
+	// Sources of completion candidates
+	completionProviders []CompletionProvider
+
+	// Locations to jump back and forward to
+	navigation navigationHistory
+
 	// Styles
-	lineNumStyle    lipgloss.Style
-	cursorLineStyle lipgloss.Style
-	selectionStyle  lipgloss.Style
+	lineNumStyle      lipgloss.Style
+	cursorLineStyle   lipgloss.Style
+	selectionStyle    lipgloss.Style
+	matchStyle        lipgloss.Style
+	currentMatchStyle lipgloss.Style
+	overflowStyle     lipgloss.Style
+	severityColors    map[lint.Severity]lipgloss.Color
 }
 
 // NewEditor creates a new editor instance.
 func NewEditor() *Editor {
-	return &Editor{
-		tabManager: NewTabManager(),
+	tabManager := NewTabManager()
+	e := &Editor{
+		tabManager: tabManager,
+
+		tabWidth:     defaultTabWidth,
+		insertSpaces: true,
+		showLineNum:  true,
+		wordWrap:     false,
+		theme:        syntax.DefaultTheme(),
 
-		tabWidth:    defaultTabWidth,
-		showLineNum: true,
-		wordWrap:    false,
+		insertFinalNewline: true,
 
 		highlightDirty: true,
-		gutterWidth:    4,
+		gutterWidth:    5,
 
-		lineNumStyle:    lipgloss.NewStyle().Foreground(lipgloss.Color("241")).PaddingRight(1),
-		cursorLineStyle: lipgloss.NewStyle().Background(lipgloss.Color("236")),
-		selectionStyle:  lipgloss.NewStyle().Background(lipgloss.Color("24")),
+		lintProblems: make(map[string][]lint.Problem),
+		saveCounts:   make(map[*TabState]int),
+
+		completionProviders: []CompletionProvider{newWordProvider(tabManager)},
+
+		lineNumStyle:      lipgloss.NewStyle().Foreground(lipgloss.Color("241")).PaddingRight(1),
+		cursorLineStyle:   lipgloss.NewStyle().Background(lipgloss.Color("236")),
+		selectionStyle:    lipgloss.NewStyle().Background(lipgloss.Color("24")),
+		matchStyle:        lipgloss.NewStyle().Background(lipgloss.Color("58")),
+		currentMatchStyle: lipgloss.NewStyle().Background(lipgloss.Color("130")),
+		overflowStyle:     lipgloss.NewStyle().Background(lipgloss.Color("52")),
+		severityColors: map[lint.Severity]lipgloss.Color{
+			lint.SeverityError:   lipgloss.Color("196"),
+			lint.SeverityWarning: lipgloss.Color("214"),
+			lint.SeverityInfo:    lipgloss.Color("39"),
+		},
 	}
+	tabManager.SetLeaveListener(e.leaveTab)
+	return e
 }
 
 // MarkHighlightDirty marks highlighting as needing refresh (e.g., after tab switch).
@@ -64,6 +119,51 @@ func (e *Editor) MarkHighlightDirty() {
 	e.highlightDirty = true
 }
 
+// SetTabWidth sets the display width of a tab and the indent size.
+func (e *Editor) SetTabWidth(width int) {
+	if width < 1 {
+		width = defaultTabWidth
+	}
+	e.tabWidth = width
+}
+
+// TabWidth returns the tab width of the active tab: the one its
+// .editorconfig sets, or the configured one.
+func (e *Editor) TabWidth() int {
+	if width := e.activeTab().editorConfig.TabWidth; width > 0 {
+		return width
+	}
+	return e.tabWidth
+}
+
+// SetInsertSpaces sets whether Tab inserts spaces or a tab character.
+func (e *Editor) SetInsertSpaces(insertSpaces bool) {
+	e.insertSpaces = insertSpaces
+}
+
+// InsertSpaces returns whether Tab inserts spaces.
+func (e *Editor) InsertSpaces() bool {
+	return e.insertSpaces
+}
+
+// SetShowLineNumbers toggles the line number gutter.
+func (e *Editor) SetShowLineNumbers(show bool) {
+	e.showLineNum = show
+	e.updateGutterWidth()
+}
+
+// SetWordWrap sets whether long lines are wrapped.
+func (e *Editor) SetWordWrap(wrap bool) {
+	e.wordWrap = wrap
+	e.activeTab().scrollRow = 0
+}
+
+// SetTheme sets the syntax highlighting theme for all tabs.
+func (e *Editor) SetTheme(theme *syntax.Theme) {
+	e.theme = theme
+	e.highlightDirty = true
+}
+
 // TabManager returns the tab manager.
 func (e *Editor) TabManager() *TabManager {
 	return e.tabManager
@@ -128,6 +228,8 @@ func (e *Editor) setScrollY(y int) {
 
 // LoadFile loads a file into the editor (opens in new tab or switches to existing).
 func (e *Editor) LoadFile(filepath string) error {
+	e.beginJump()
+	defer e.endJump()
 	_, err := e.tabManager.AddTabFromFile(filepath)
 	if err != nil {
 		return err
@@ -150,6 +252,7 @@ func (e *Editor) SetContent(content string) {
 	tab.Buffer().SetContent(content)
 	tab.Cursor().MoveTo(0, 0, tab.Buffer())
 	tab.Selection().Clear()
+	tab.ClearExtraCarets()
 	tab.History().Clear()
 	tab.SetScrollX(0)
 	tab.SetScrollY(0)
@@ -168,128 +271,157 @@ func (e *Editor) SetSize(width, height int) {
 	e.height = height
 }
 
-// updateGutterWidth calculates the line number gutter width.
+// updateGutterWidth calculates the line number gutter width, including
+// the column for diagnostic markers.
 func (e *Editor) updateGutterWidth() {
+	if !e.showLineNum {
+		e.gutterWidth = 0
+		return
+	}
+
 	lineCount := e.buffer().LineCount()
-	width := 2
+	width := 3
 	for lineCount > 0 {
 		lineCount /= 10
 		width++
 	}
-	if width < 4 {
-		width = 4
+	if width < 5 {
+		width = 5
 	}
 	e.gutterWidth = width
 }
 
-// InsertRune inserts a single rune at the cursor position.
+// InsertRune inserts a single rune at every caret. A closing bracket typed
+// in the indentation of a line moves it to the indentation of the matching
+// opener.
 func (e *Editor) InsertRune(r rune) {
-	// Delete selection if active
-	if e.selection().Active && !e.selection().IsEmpty() {
-		e.deleteSelection()
+	if _, ok := closers[r]; !ok {
+		e.InsertText(string(r))
+		return
 	}
-
-	offset := e.cursor().Offset(e.buffer())
-	text := string(r)
-
-	e.history().RecordInsert(offset, text, e.cursor().Position())
-	e.buffer().Insert(offset, text)
-	e.cursor().MoveRight(e.buffer())
-	e.highlightDirty = true
-	e.ensureCursorVisible()
-	e.updateGutterWidth()
+	e.editCarets(func(_ int, c *Caret) {
+		if !e.dedentAt(c, r) {
+			e.insertTextAt(c, string(r))
+		}
+	})
 }
 
-// InsertText inserts a string at the cursor position.
+// InsertText inserts a string at every caret.
 func (e *Editor) InsertText(text string) {
 	if text == "" {
 		return
 	}
+	e.editCarets(func(_ int, c *Caret) {
+		e.insertTextAt(c, text)
+	})
+}
 
-	// Delete selection if active
-	if e.selection().Active && !e.selection().IsEmpty() {
-		e.deleteSelection()
-	}
+// InsertNewline inserts a newline with auto-indentation, one level deeper
+// after a block opener such as {.
+func (e *Editor) InsertNewline() {
+	e.editCarets(func(_ int, c *Caret) {
+		text, after := e.newlineIndent(c)
+		e.insertTextAt(c, text+after)
+		if after != "" {
+			// Back to the end of the indented line, above the closer
+			line := c.Cursor.Line - 1
+			c.Cursor.MoveTo(line, e.buffer().LineLength(line), e.buffer())
+		}
+	})
+}
 
-	offset := e.cursor().Offset(e.buffer())
-	e.history().RecordInsert(offset, text, e.cursor().Position())
-	e.buffer().Insert(offset, text)
+// InsertTab indents the selected lines if a selection spans lines.
+// Otherwise it inserts one level of indentation at every caret: a tab, or
+// spaces up to the next multiple of the indent width.
+func (e *Editor) InsertTab() {
+	if e.spansLines() {
+		e.IndentLines()
+		return
+	}
+	indent := e.Indent()
+	e.editCarets(func(_ int, c *Caret) {
+		text := "\t"
+		if !indent.Tabs {
+			start, _ := c.span()
+			x := displayColumn(e.buffer().Line(start.Line), start.Column, e.TabWidth())
+			text = strings.Repeat(" ", indent.Width-x%indent.Width)
+		}
+		e.insertTextAt(c, text)
+	})
+}
 
-	// Move cursor to end of inserted text
-	newOffset := offset + len([]rune(text))
-	line, col := e.buffer().OffsetToPosition(newOffset)
-	e.cursor().MoveTo(line, col, e.buffer())
+// Backspace deletes the character before every caret.
+func (e *Editor) Backspace() {
+	e.editCarets(func(_ int, c *Caret) {
+		e.backspaceAt(c)
+	})
+}
 
-	e.highlightDirty = true
-	e.ensureCursorVisible()
-	e.updateGutterWidth()
+// Delete deletes the character at every caret.
+func (e *Editor) Delete() {
+	e.editCarets(func(_ int, c *Caret) {
+		e.deleteAt(c)
+	})
 }
 
-// InsertNewline inserts a newline with auto-indentation.
-func (e *Editor) InsertNewline() {
-	// Get current line indentation
-	currentLine := e.buffer().Line(e.cursor().Line)
-	indent := ""
-	for _, r := range currentLine {
-		if r == ' ' || r == '\t' {
-			indent += string(r)
-		} else {
-			break
-		}
+// insertTextAt replaces the caret's selection (if any) with text.
+func (e *Editor) insertTextAt(c *Caret, text string) {
+	if c.hasSelection() {
+		e.deleteSelectionAt(c)
+	}
+	if text == "" {
+		return
 	}
 
-	e.InsertText("\n" + indent)
-}
+	buf := e.buffer()
+	offset := c.Cursor.Offset(buf)
+	e.history().RecordInsert(offset, text, c.Cursor.Position())
+	buf.Insert(offset, text)
 
-// InsertTab inserts a tab (as spaces or tab character based on settings).
-func (e *Editor) InsertTab() {
-	// Insert spaces instead of tab
-	spaces := strings.Repeat(" ", e.tabWidth)
-	e.InsertText(spaces)
+	// Move cursor to end of inserted text
+	line, col := buf.OffsetToPosition(offset + len([]rune(text)))
+	c.Cursor.MoveTo(line, col, buf)
 }
 
-// Backspace deletes the character before the cursor.
-func (e *Editor) Backspace() {
-	// Delete selection if active
-	if e.selection().Active && !e.selection().IsEmpty() {
-		e.deleteSelection()
+// backspaceAt deletes the caret's selection or the character before it.
+func (e *Editor) backspaceAt(c *Caret) {
+	if c.hasSelection() {
+		e.deleteSelectionAt(c)
 		return
 	}
 
-	offset := e.cursor().Offset(e.buffer())
+	buf := e.buffer()
+	offset := c.Cursor.Offset(buf)
 	if offset == 0 {
 		return
 	}
 
-	deleted := e.buffer().Delete(offset-1, 1)
-	e.history().RecordDelete(offset-1, deleted, e.cursor().Position())
-	e.cursor().MoveLeft(e.buffer())
-	e.highlightDirty = true
-	e.ensureCursorVisible()
-	e.updateGutterWidth()
+	deleted := buf.Delete(offset-1, 1)
+	e.history().RecordDelete(offset-1, deleted, c.Cursor.Position())
+	line, col := buf.OffsetToPosition(offset - 1)
+	c.Cursor.MoveTo(line, col, buf)
 }
 
-// Delete deletes the character at the cursor.
-func (e *Editor) Delete() {
-	// Delete selection if active
-	if e.selection().Active && !e.selection().IsEmpty() {
-		e.deleteSelection()
+// deleteAt deletes the caret's selection or the character at it.
+func (e *Editor) deleteAt(c *Caret) {
+	if c.hasSelection() {
+		e.deleteSelectionAt(c)
 		return
 	}
 
-	offset := e.cursor().Offset(e.buffer())
-	if offset >= e.buffer().Length() {
+	buf := e.buffer()
+	offset := c.Cursor.Offset(buf)
+	if offset >= buf.Length() {
 		return
 	}
 
-	deleted := e.buffer().Delete(offset, 1)
-	e.history().RecordDelete(offset, deleted, e.cursor().Position())
-	e.highlightDirty = true
-	e.updateGutterWidth()
+	deleted := buf.Delete(offset, 1)
+	e.history().RecordDelete(offset, deleted, c.Cursor.Position())
 }
 
 // DeleteLine deletes the current line.
 func (e *Editor) DeleteLine() {
+	e.collapseCarets()
 	line := e.cursor().Line
 	lineStart := e.buffer().PositionToOffset(line, 0)
 	lineLen := e.buffer().LineLength(line)
@@ -315,21 +447,28 @@ func (e *Editor) DeleteLine() {
 
 // deleteSelection deletes the selected text.
 func (e *Editor) deleteSelection() {
-	if !e.selection().Active || e.selection().IsEmpty() {
+	c := e.activeTab().PrimaryCaret()
+	if !c.hasSelection() {
 		return
 	}
 
-	start, _ := e.selection().Normalized()
-	text := e.selection().Text(e.buffer())
-	startOffset := e.buffer().PositionToOffset(start.Line, start.Column)
-
-	e.history().RecordDelete(startOffset, text, e.cursor().Position())
-	e.selection().Delete(e.buffer())
-	e.cursor().MoveTo(start.Line, start.Column, e.buffer())
+	e.deleteSelectionAt(c)
 	e.highlightDirty = true
 	e.updateGutterWidth()
 }
 
+// deleteSelectionAt deletes the text selected by a caret.
+func (e *Editor) deleteSelectionAt(c *Caret) {
+	buf := e.buffer()
+	start, _ := c.Selection.Normalized()
+	text := c.Selection.Text(buf)
+	startOffset := buf.PositionToOffset(start.Line, start.Column)
+
+	e.history().RecordDelete(startOffset, text, c.Cursor.Position())
+	c.Selection.Delete(buf)
+	c.Cursor.MoveTo(start.Line, start.Column, buf)
+}
+
 // Undo undoes the last action.
 func (e *Editor) Undo() {
 	action := e.history().Undo()
@@ -338,8 +477,17 @@ func (e *Editor) Undo() {
 	}
 
 	cursorPos := ApplyUndo(action, e.buffer())
+	e.collapseCarets()
 	e.cursor().MoveTo(cursorPos.Line, cursorPos.Column, e.buffer())
 	e.selection().Clear()
+
+	// Restore the carets of a multi-cursor edit
+	if len(action.Carets) > 1 {
+		for _, pos := range action.Carets[1:] {
+			e.activeTab().AddCaret(newCaretAt(pos))
+		}
+		e.activeTab().MergeCarets()
+	}
 	e.highlightDirty = true
 	e.ensureCursorVisible()
 	e.updateGutterWidth()
@@ -353,6 +501,7 @@ func (e *Editor) Redo() {
 	}
 
 	cursorPos := ApplyRedo(action, e.buffer())
+	e.collapseCarets()
 	e.cursor().MoveTo(cursorPos.Line, cursorPos.Column, e.buffer())
 	e.selection().Clear()
 	e.highlightDirty = true
@@ -361,7 +510,11 @@ func (e *Editor) Redo() {
 }
 
 // Copy returns the selected text (or current line if no selection).
+// With multiple carets, each caret contributes one line.
 func (e *Editor) Copy() string {
+	if e.activeTab().HasMultipleCarets() {
+		return e.copyCarets()
+	}
 	if e.selection().Active && !e.selection().IsEmpty() {
 		return e.selection().Text(e.buffer())
 	}
@@ -371,6 +524,18 @@ func (e *Editor) Copy() string {
 
 // Cut cuts the selected text (or current line if no selection).
 func (e *Editor) Cut() string {
+	if e.activeTab().HasMultipleCarets() {
+		text := e.copyCarets()
+		if e.anyCaretSelection() {
+			e.editCarets(func(_ int, c *Caret) {
+				if c.hasSelection() {
+					e.deleteSelectionAt(c)
+				}
+			})
+			return text
+		}
+		e.collapseCarets()
+	}
 	if e.selection().Active && !e.selection().IsEmpty() {
 		text := e.selection().Text(e.buffer())
 		e.deleteSelection()
@@ -382,13 +547,25 @@ func (e *Editor) Cut() string {
 	return text
 }
 
-// Paste inserts text at cursor position.
+// Paste inserts text at every caret. If the text has one line per caret,
+// each caret receives its own line.
 func (e *Editor) Paste(text string) {
+	tab := e.activeTab()
+	if tab.HasMultipleCarets() {
+		lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+		if len(lines) == len(tab.Carets()) {
+			e.editCarets(func(i int, c *Caret) {
+				e.insertTextAt(c, lines[i])
+			})
+			return
+		}
+	}
 	e.InsertText(text)
 }
 
 // SelectAll selects all text in the buffer.
 func (e *Editor) SelectAll() {
+	e.collapseCarets()
 	e.selection().SelectAll(e.buffer())
 }
 
@@ -399,11 +576,13 @@ func (e *Editor) SelectWord() {
 
 // SelectLine selects the current line.
 func (e *Editor) SelectLine() {
+	e.collapseCarets()
 	e.selection().SelectLine(e.buffer(), e.cursor())
 }
 
 // DuplicateLine duplicates the current line or selection.
 func (e *Editor) DuplicateLine() {
+	e.collapseCarets()
 	if e.selection().Active && !e.selection().IsEmpty() {
 		// Duplicate selection
 		text := e.selection().Text(e.buffer())
@@ -421,7 +600,7 @@ func (e *Editor) DuplicateLine() {
 		}
 		e.buffer().Insert(lineEnd, line+"\n")
 		e.history().RecordInsert(lineEnd, line+"\n", e.cursor().Position())
-		e.cursor().MoveDown(e.buffer())
+		e.cursor().MoveDown(e.buffer(), e.TabWidth())
 	}
 	e.highlightDirty = true
 	e.updateGutterWidth()
@@ -429,6 +608,7 @@ func (e *Editor) DuplicateLine() {
 
 // MoveLineUp moves the current line up.
 func (e *Editor) MoveLineUp() {
+	e.collapseCarets()
 	if e.cursor().Line == 0 {
 		return
 	}
@@ -459,6 +639,7 @@ func (e *Editor) MoveLineUp() {
 
 // MoveLineDown moves the current line down.
 func (e *Editor) MoveLineDown() {
+	e.collapseCarets()
 	if e.cursor().Line >= e.buffer().LineCount()-1 {
 		return
 	}
@@ -487,67 +668,107 @@ func (e *Editor) MoveLineDown() {
 	e.ensureCursorVisible()
 }
 
-// MoveCursor moves the cursor with optional selection extension.
+// MoveCursor moves every caret with optional selection extension.
 func (e *Editor) MoveCursor(direction string, extend bool) {
-	if extend && !e.selection().Active {
-		e.selection().StartAt(e.cursor().Position())
+	tab := e.activeTab()
+	for _, c := range tab.Carets() {
+		e.moveCaret(c, direction, extend)
+	}
+	tab.MergeCarets()
+	e.ensureCursorVisible()
+}
+
+// moveCaret moves a single caret.
+func (e *Editor) moveCaret(c *Caret, direction string, extend bool) {
+	cursor, selection := c.Cursor, c.Selection
+	if extend && !selection.Active {
+		selection.StartAt(cursor.Position())
 	}
 
 	switch direction {
 	case "left":
-		e.cursor().MoveLeft(e.buffer())
+		cursor.MoveLeft(e.buffer())
 	case "right":
-		e.cursor().MoveRight(e.buffer())
+		cursor.MoveRight(e.buffer())
 	case "up":
-		e.cursor().MoveUp(e.buffer())
+		if e.wordWrap {
+			e.moveCaretRow(cursor, -1)
+		} else {
+			cursor.MoveUp(e.buffer(), e.TabWidth())
+		}
 	case "down":
-		e.cursor().MoveDown(e.buffer())
+		if e.wordWrap {
+			e.moveCaretRow(cursor, 1)
+		} else {
+			cursor.MoveDown(e.buffer(), e.TabWidth())
+		}
 	case "wordLeft":
-		e.cursor().MoveWordLeft(e.buffer())
+		cursor.MoveWordLeft(e.buffer())
 	case "wordRight":
-		e.cursor().MoveWordRight(e.buffer())
+		cursor.MoveWordRight(e.buffer())
 	case "lineStart":
-		e.cursor().MoveToLineStart()
+		cursor.MoveToLineStart()
 	case "lineEnd":
-		e.cursor().MoveToLineEnd(e.buffer())
+		cursor.MoveToLineEnd(e.buffer())
 	case "bufferStart":
-		e.cursor().MoveToBufferStart()
+		cursor.MoveToBufferStart()
 	case "bufferEnd":
-		e.cursor().MoveToBufferEnd(e.buffer())
+		cursor.MoveToBufferEnd(e.buffer())
 	}
 
 	if extend {
-		e.selection().ExtendTo(e.cursor().Position())
+		selection.ExtendTo(cursor.Position())
 	} else {
-		e.selection().Clear()
+		selection.Clear()
 	}
-
-	e.ensureCursorVisible()
 }
 
 // GoToLine moves the cursor to a specific line (1-indexed).
 func (e *Editor) GoToLine(line int) {
+	e.beginJump()
+	defer e.endJump()
+	e.collapseCarets()
 	e.cursor().MoveToLine(line, e.buffer())
 	e.selection().Clear()
 	e.ensureCursorVisible()
 }
 
+// GoToMatch selects the columns [start, end) of a line (0-based) with the
+// cursor at the start, e.g. to show a search result.
+func (e *Editor) GoToMatch(line, start, end int) {
+	e.beginJump()
+	defer e.endJump()
+	e.collapseCarets()
+	buf := e.buffer()
+	line = min(max(line, 0), buf.LineCount()-1)
+	e.selectMatch(Match{
+		Start: buf.PositionToOffset(line, start),
+		End:   buf.PositionToOffset(line, end),
+	})
+}
+
 // PageUp moves the view and cursor up by one page.
 func (e *Editor) PageUp() {
-	e.cursor().PageUp(e.height-2, e.buffer())
+	e.collapseCarets()
+	e.cursor().PageUp(e.height-2, e.buffer(), e.TabWidth())
 	e.selection().Clear()
 	e.ensureCursorVisible()
 }
 
 // PageDown moves the view and cursor down by one page.
 func (e *Editor) PageDown() {
-	e.cursor().PageDown(e.height-2, e.buffer())
+	e.collapseCarets()
+	e.cursor().PageDown(e.height-2, e.buffer(), e.TabWidth())
 	e.selection().Clear()
 	e.ensureCursorVisible()
 }
 
 // ensureCursorVisible adjusts scroll to keep cursor in view.
 func (e *Editor) ensureCursorVisible() {
+	if e.wordWrap {
+		e.ensureCaretRowVisible()
+		return
+	}
 	tab := e.activeTab()
 	scrollY := tab.ScrollY()
 	scrollX := tab.ScrollX()
@@ -560,16 +781,19 @@ func (e *Editor) ensureCursorVisible() {
 		scrollY = e.cursor().Line - e.height + 1
 	}
 
-	// Horizontal scroll
-	textWidth := e.width - e.gutterWidth - 1
-	if textWidth < 1 {
-		textWidth = 1
+	// Horizontal scroll, in cells, so the whole character at the cursor
+	// is shown
+	textWidth := e.textWidth()
+	glyphs := e.layout(e.cursor().Line)
+	x, width := cellOf(glyphs, e.cursor().Column), 1
+	if i := glyphAt(glyphs, e.cursor().Column); i < len(glyphs) {
+		width = glyphs[i].width
 	}
-	if e.cursor().Column < scrollX {
-		scrollX = e.cursor().Column
+	if x < scrollX {
+		scrollX = x
 	}
-	if e.cursor().Column >= scrollX+textWidth {
-		scrollX = e.cursor().Column - textWidth + 1
+	if x+width > scrollX+textWidth {
+		scrollX = x + width - textWidth
 	}
 
 	tab.SetScrollX(scrollX)
@@ -578,6 +802,10 @@ func (e *Editor) ensureCursorVisible() {
 
 // Scroll scrolls the view by delta lines.
 func (e *Editor) Scroll(delta int) {
+	if e.wordWrap {
+		e.scrollRows(delta)
+		return
+	}
 	tab := e.activeTab()
 	scrollY := tab.ScrollY() + delta
 	if scrollY < 0 {
@@ -593,9 +821,15 @@ func (e *Editor) Scroll(delta int) {
 	tab.SetScrollY(scrollY)
 }
 
-// HandleClick handles a mouse click at the given position.
-func (e *Editor) HandleClick(x, y int, shift bool) {
-	// Convert screen position to buffer position
+// screenToPosition converts a click inside the editor to a buffer position.
+func (e *Editor) screenToPosition(x, y int) Position {
+	if e.wordWrap {
+		pos, _ := e.stepRows(e.scrollTop(), max(y, 0))
+		rows := e.wrapLine(pos.line, e.textWidth())
+		col := e.rowColumn(pos.line, rows[pos.row], pos.row == len(rows)-1, x-e.gutterWidth)
+		return Position{Line: pos.line, Column: col}
+	}
+
 	line := e.scrollY() + y
 	if line >= e.buffer().LineCount() {
 		line = e.buffer().LineCount() - 1
@@ -604,20 +838,20 @@ func (e *Editor) HandleClick(x, y int, shift bool) {
 		line = 0
 	}
 
-	col := e.scrollX() + x - e.gutterWidth
-	if col < 0 {
-		col = 0
-	}
-	lineLen := e.buffer().LineLength(line)
-	if col > lineLen {
-		col = lineLen
-	}
+	col := columnAtCell(e.layout(line), max(e.scrollX()+x-e.gutterWidth, 0))
+	return Position{Line: line, Column: col}
+}
+
+// HandleClick handles a mouse click at the given position.
+func (e *Editor) HandleClick(x, y int, shift bool) {
+	e.collapseCarets()
+	pos := e.screenToPosition(x, y)
 
 	if shift && !e.selection().Active {
 		e.selection().StartAt(e.cursor().Position())
 	}
 
-	e.cursor().MoveTo(line, col, e.buffer())
+	e.cursor().MoveTo(pos.Line, pos.Column, e.buffer())
 
 	if shift {
 		e.selection().ExtendTo(e.cursor().Position())
@@ -628,28 +862,13 @@ func (e *Editor) HandleClick(x, y int, shift bool) {
 
 // HandleDrag handles mouse drag for selection.
 func (e *Editor) HandleDrag(x, y int) {
+	e.collapseCarets()
 	if !e.selection().Active {
 		e.selection().StartAt(e.cursor().Position())
 	}
 
-	line := e.scrollY() + y
-	if line >= e.buffer().LineCount() {
-		line = e.buffer().LineCount() - 1
-	}
-	if line < 0 {
-		line = 0
-	}
-
-	col := e.scrollX() + x - e.gutterWidth
-	if col < 0 {
-		col = 0
-	}
-	lineLen := e.buffer().LineLength(line)
-	if col > lineLen {
-		col = lineLen
-	}
-
-	e.cursor().MoveTo(line, col, e.buffer())
+	pos := e.screenToPosition(x, y)
+	e.cursor().MoveTo(pos.Line, pos.Column, e.buffer())
 	e.selection().ExtendTo(e.cursor().Position())
 	e.ensureCursorVisible()
 }
@@ -666,20 +885,19 @@ func (e *Editor) TripleClick(x, y int) {
 	e.SelectLine()
 }
 
-// Save saves the buffer to its file.
+// Save runs the save pipeline and saves the buffer to its file. It
+// returns ErrChangedOnDisk if another program changed the file.
 func (e *Editor) Save() error {
-	err := e.buffer().Save()
-	if err == nil {
-		e.activeTab().MarkSaved()
-	}
-	return err
+	return e.saveTab(e.activeTab(), e.Filepath(), true)
 }
 
-// SaveAs saves the buffer to a new file.
+// SaveAs runs the save pipeline and saves the buffer to a new file.
 func (e *Editor) SaveAs(filepath string) error {
-	err := e.buffer().SaveAs(filepath)
+	if filepath != e.buffer().Filepath() {
+		e.activeTab().loadEditorConfig(filepath)
+	}
+	err := e.saveTab(e.activeTab(), filepath, false)
 	if err == nil {
-		e.activeTab().MarkSaved()
 		e.highlighter().SetLanguageFromPath(filepath)
 		e.highlightDirty = true
 	}
@@ -711,9 +929,10 @@ func (e *Editor) CursorLine() int {
 	return e.cursor().Line
 }
 
-// CursorColumn returns the current cursor column (0-indexed).
+// CursorColumn returns the cell at which the cursor is drawn in its line
+// (0-indexed), counting wide characters twice and tabs up to their tab stop.
 func (e *Editor) CursorColumn() int {
-	return e.cursor().Column
+	return displayColumn(e.buffer().Line(e.cursor().Line), e.cursor().Column, e.TabWidth())
 }
 
 // LineEnding returns the line ending style.
@@ -726,12 +945,29 @@ func (e *Editor) Encoding() string {
 	return e.buffer().Encoding()
 }
 
-// updateHighlighting refreshes syntax highlighting cache.
+// highlightContext is the number of lines above the view highlighted along
+// with it, so that constructs opened there, such as block comments, are
+// usually colored right.
+const highlightContext = 100
+
+// updateHighlighting refreshes the syntax highlighting cache for the lines
+// on screen. Only those and the lines of highlightContext are lexed, so the
+// cost does not grow with the size of the file.
 func (e *Editor) updateHighlighting() {
-	if !e.highlightDirty {
+	buf := e.buffer()
+	top := min(e.scrollY(), buf.LineCount()-1)
+	bottom := min(top+max(e.height, 1), buf.LineCount())
+	if !e.highlightDirty && top >= e.highlightStart && bottom <= e.highlightStart+len(e.highlightedLines) &&
+		(top-e.highlightStart >= highlightContext || e.highlightStart == 0) {
 		return
 	}
-	e.highlightedLines = e.highlighter().Highlight(e.buffer().Content())
+
+	start := max(top-highlightContext, 0)
+	end := min(bottom+e.height, buf.LineCount())
+	text := buf.Substring(buf.PositionToOffset(start, 0), buf.PositionToOffset(end-1, buf.LineLength(end-1)))
+	e.highlighter().SetTheme(e.theme)
+	e.highlightedLines = e.highlighter().Highlight(text)
+	e.highlightStart = start
 	e.highlightDirty = false
 }
 
@@ -742,13 +978,13 @@ func (e *Editor) View() string {
 	}
 
 	e.updateHighlighting()
-
-	var lines []string
-	textWidth := e.width - e.gutterWidth - 1
-	if textWidth < 1 {
-		textWidth = 1
+	diags := e.activeDiagnostics()
+	if e.wordWrap {
+		return e.wrappedView(diags)
 	}
 
+	var lines []string
+	textWidth := e.textWidth()
 	scrollY := e.scrollY()
 
 	for y := 0; y < e.height; y++ {
@@ -756,30 +992,14 @@ func (e *Editor) View() string {
 		var lineContent string
 
 		if lineNum < e.buffer().LineCount() {
-			// Render line number
+			// Render diagnostic marker and line number
 			if e.showLineNum {
-				lineNumStr := lipgloss.NewStyle().
-					Width(e.gutterWidth - 1).
-					Align(lipgloss.Right).
-					Render(formatLineNum(lineNum + 1))
-				if lineNum == e.cursor().Line {
-					lineNumStr = lipgloss.NewStyle().
-						Foreground(lipgloss.Color("252")).
-						Width(e.gutterWidth - 1).
-						Align(lipgloss.Right).
-						Render(formatLineNum(lineNum + 1))
-				} else {
-					lineNumStr = e.lineNumStyle.
-						Width(e.gutterWidth - 1).
-						Align(lipgloss.Right).
-						Render(formatLineNum(lineNum + 1))
-				}
-				lineContent = lineNumStr + " "
+				lineContent = e.gutterMarker(lineNum, diags) + e.lineNumber(lineNum) + " "
 			}
 
 			// Render line content with syntax highlighting and selection
-			lineText := e.buffer().Line(lineNum)
-			lineContent += e.renderLine(lineNum, lineText, textWidth)
+			lineLen := e.buffer().LineLength(lineNum)
+			lineContent += e.renderLine(lineNum, 0, lineLen, e.scrollX(), textWidth, diags)
 		} else {
 			// Empty line
 			if e.showLineNum {
@@ -794,37 +1014,109 @@ func (e *Editor) View() string {
 	return strings.Join(lines, "\n")
 }
 
-// renderLine renders a single line with syntax highlighting and selection.
-func (e *Editor) renderLine(lineNum int, lineText string, maxWidth int) string {
-	scrollX := e.scrollX()
-
-	// Handle horizontal scrolling
-	runes := []rune(lineText)
-	if scrollX > 0 {
-		if scrollX >= len(runes) {
-			runes = nil
-		} else {
-			runes = runes[scrollX:]
+// lineNumber renders the number of a line for the gutter, brighter on
+// the cursor's line.
+func (e *Editor) lineNumber(lineNum int) string {
+	if lineNum == e.cursor().Line {
+		return lipgloss.NewStyle().
+			Foreground(lipgloss.Color("252")).
+			Width(e.gutterWidth - 2).
+			Align(lipgloss.Right).
+			Render(formatLineNum(lineNum + 1))
+	}
+	return e.lineNumStyle.
+		Width(e.gutterWidth - 2).
+		Align(lipgloss.Right).
+		Render(formatLineNum(lineNum + 1))
+}
+
+// gutterMarker renders the marker of the most severe diagnostic starting
+// on a line, or a space.
+func (e *Editor) gutterMarker(lineNum int, diags []Diagnostic) string {
+	var severity lint.Severity
+	for _, d := range diags {
+		if d.Line == lineNum && (severity == 0 || d.Severity < severity) {
+			severity = d.Severity
 		}
 	}
-
-	// Truncate to fit width
-	if len(runes) > maxWidth {
-		runes = runes[:maxWidth]
+	if severity == 0 {
+		return " "
+	}
+	return lipgloss.NewStyle().Foreground(e.severityColors[severity]).Render("●")
+}
+
+// renderLine renders the columns [start, end) of a line, in the maxWidth
+// cells from cell left on, with syntax highlighting, selection and the
+// underlined ranges of diagnostics. A caret at the end of the line is drawn
+// if end reaches it.
+func (e *Editor) renderLine(lineNum, start, end, left, maxWidth int, diags []Diagnostic) string {
+	// Get selection ranges and cursor columns of every caret on this line
+	lineLen := e.buffer().LineLength(lineNum)
+	var selRanges [][2]int
+	var cursorCols, lineCursorCols []int
+	for _, c := range e.activeTab().Carets() {
+		selStart, selEnd := c.Selection.GetLineRange(lineNum, lineLen)
+		if selStart != -1 {
+			selRanges = append(selRanges, [2]int{selStart, selEnd})
+		}
+		if c.Cursor.Line == lineNum {
+			col := c.Cursor.Column
+			// Cursor highlight inside the text only if the caret has no selection
+			if !c.Selection.Active {
+				cursorCols = append(cursorCols, col)
+			}
+			lineCursorCols = append(lineCursorCols, col)
+		}
+	}
+	inRanges := func(col int) bool {
+		for _, r := range selRanges {
+			if col >= r[0] && col < r[1] {
+				return true
+			}
+		}
+		return false
 	}
 
-	// Replace tabs with spaces
-	lineText = e.expandTabs(string(runes))
-	runes = []rune(lineText)
-
-	// Get selection range for this line
-	selStart, selEnd := e.selection().GetLineRange(lineNum, len([]rune(e.buffer().Line(lineNum))))
-	if selStart != -1 {
-		selStart -= scrollX
-		selEnd -= scrollX
-		if selStart < 0 {
-			selStart = 0
+	// Search matches on this line
+	matchRanges, currentMatch := e.lineMatches(lineNum, lineLen)
+	matchAt := func(col int) int {
+		for j, r := range matchRanges {
+			if col >= r[0] && col < r[1] {
+				return j
+			}
+		}
+		return -1
+	}
+	// Diagnostic ranges on this line, most severe last so it wins
+	type diagRange struct {
+		start, end int
+		severity   lint.Severity
+	}
+	var diagRanges []diagRange
+	for _, d := range diags {
+		if start, end, ok := d.covers(lineNum, lineLen); ok {
+			diagRanges = append(diagRanges, diagRange{start, end, d.Severity})
+		}
+	}
+	sort.SliceStable(diagRanges, func(i, j int) bool {
+		return diagRanges[i].severity > diagRanges[j].severity
+	})
+	diagAt := func(col int) lint.Severity {
+		var severity lint.Severity
+		for _, r := range diagRanges {
+			if col >= r.start && col < r.end {
+				severity = r.severity
+			}
+		}
+		return severity
+	}
+	isCursor := func(col int) bool {
+		for _, c := range cursorCols {
+			if col == c {
+				return true
+			}
 		}
+		return false
 	}
 
 	// Build the line with highlighting and selection
@@ -832,81 +1124,111 @@ func (e *Editor) renderLine(lineNum int, lineText string, maxWidth int) string {
 
 	// Get highlighted segments for this line
 	var segments []syntax.StyledSegment
-	if lineNum < len(e.highlightedLines) {
-		segments = e.highlightedLines[lineNum].Segments
+	if i := lineNum - e.highlightStart; i >= 0 && i < len(e.highlightedLines) {
+		segments = e.highlightedLines[i].Segments
 	}
 	if len(segments) == 0 {
-		segments = []syntax.StyledSegment{{Text: lineText, Style: lipgloss.NewStyle()}}
+		segments = []syntax.StyledSegment{{Text: e.buffer().Line(lineNum), Style: lipgloss.NewStyle()}}
 	}
 
-	// Flatten segments accounting for scrollX
-	flatRunes := make([]struct {
-		r     rune
-		style lipgloss.Style
-	}, 0, len(runes))
-
-	pos := 0
+	// Styles of the runes of the line
+	styles := make([]lipgloss.Style, 0, lineLen)
 	for _, seg := range segments {
-		for _, r := range seg.Text {
-			if pos >= scrollX && pos < scrollX+maxWidth {
-				// Expand tab
-				if r == '\t' {
-					for i := 0; i < e.tabWidth; i++ {
-						flatRunes = append(flatRunes, struct {
-							r     rune
-							style lipgloss.Style
-						}{' ', seg.Style})
-					}
-				} else {
-					flatRunes = append(flatRunes, struct {
-						r     rune
-						style lipgloss.Style
-					}{r, seg.Style})
-				}
+		for range seg.Text {
+			styles = append(styles, seg.Style)
+		}
+	}
+
+	// Lay the glyphs of the columns [start, end) out in the cells from
+	// left on. A glyph is drawn in its first cell and covers the others; a
+	// tab, or a glyph cut off at either edge, is drawn as blanks
+	type cell struct {
+		text  string
+		col   int
+		x     int
+		first bool // First cell of its column
+		style lipgloss.Style
+	}
+	glyphs := e.layout(lineNum)
+	cells := make([]cell, 0, maxWidth)
+	for _, g := range glyphs {
+		if g.start < start || g.start >= end {
+			continue
+		}
+		style := lipgloss.NewStyle()
+		if g.start < len(styles) {
+			style = styles[g.start]
+		}
+		whole := g.text != "\t" && g.x >= left && g.x+g.width <= left+maxWidth
+		for x := g.x; x < g.x+g.width; x++ {
+			if x < left || x >= left+maxWidth {
+				continue
 			}
-			pos++
+			text := " "
+			if whole && x == g.x {
+				text = g.text
+			} else if whole {
+				text = ""
+			}
+			cells = append(cells, cell{text, g.start, x, x == g.x, style})
 		}
 	}
 
-	// Render each rune with appropriate style
-	cursorLine := e.cursor().Line
-	cursorColumn := e.cursor().Column
-	selectionActive := e.selection().Active
+	// Cells beyond the .editorconfig max_line_length
+	maxLineLength := e.activeTab().editorConfig.MaxLineLength
+
+	// Render each cell with appropriate style
+	for _, c := range cells {
+		style := c.style
+
+		if maxLineLength > 0 && c.x >= maxLineLength {
+			style = style.Background(e.overflowStyle.GetBackground())
+		}
+
+		// Underline diagnostics in the color of their severity
+		if severity := diagAt(c.col); severity != 0 {
+			style = style.Underline(true).Foreground(e.severityColors[severity])
+		}
 
-	for i, fr := range flatRunes {
-		style := fr.style
+		// Apply match highlighting, keeping the current match visible
+		// on top of the selection
+		match := matchAt(c.col)
+		if match >= 0 {
+			style = style.Background(e.matchStyle.GetBackground())
+		}
 
 		// Apply selection highlighting
-		if selStart != -1 && i >= selStart && i < selEnd {
-			style = style.Background(lipgloss.Color("24"))
+		if inRanges(c.col) {
+			style = style.Background(e.selectionStyle.GetBackground())
+		}
+		if match >= 0 && match == currentMatch {
+			style = style.Background(e.currentMatchStyle.GetBackground())
 		}
 
-		// Apply cursor highlight (only if no selection)
-		if lineNum == cursorLine && i == cursorColumn-scrollX && !selectionActive {
+		// Apply cursor highlight
+		if c.first && isCursor(c.col) {
 			style = style.Reverse(true)
 		}
 
-		result.WriteString(style.Render(string(fr.r)))
+		result.WriteString(style.Render(c.text))
 	}
 
 	// Render cursor at end of line
-	cursorCol := cursorColumn - scrollX
-	if lineNum == cursorLine && cursorCol >= 0 && cursorCol == len(flatRunes) {
-		style := lipgloss.NewStyle().Reverse(true)
-		if selectionActive && selStart != -1 && cursorCol >= selStart && cursorCol < selEnd {
-			style = style.Background(lipgloss.Color("24"))
+	endX := lineWidth(glyphs)
+	for _, col := range lineCursorCols {
+		if col == lineLen && col >= start && col <= end && endX >= left && endX <= left+maxWidth {
+			style := lipgloss.NewStyle().Reverse(true)
+			if inRanges(col) {
+				style = style.Background(e.selectionStyle.GetBackground())
+			}
+			result.WriteString(style.Render(" "))
+			break
 		}
-		result.WriteString(style.Render(" "))
 	}
 
 	return result.String()
 }
 
-// expandTabs replaces tabs with spaces.
-func (e *Editor) expandTabs(s string) string {
-	return strings.ReplaceAll(s, "\t", strings.Repeat(" ", e.tabWidth))
-}
-
 // formatLineNum formats a line number for display.
 func formatLineNum(n int) string {
 	return strings.TrimSpace(lipgloss.NewStyle().Render(intToStr(n)))
@@ -948,81 +1270,120 @@ func (e *Editor) Selection() *Selection {
 	return e.selection()
 }
 
-// Find searches for text and moves cursor to the match.
-func (e *Editor) Find(text string, caseSensitive bool) bool {
+// Find moves to the next match after the cursor and selects it.
+func (e *Editor) Find(search *Search) bool {
+	e.beginJump()
+	defer e.endJump()
+	e.collapseCarets()
 	offset := e.cursor().Offset(e.buffer())
-	found := e.buffer().FindNext(text, offset+1, caseSensitive)
-	if found == -1 {
+	match, found := e.buffer().FindNext(search, offset+1)
+	if !found {
 		// Wrap around
-		found = e.buffer().FindNext(text, 0, caseSensitive)
+		match, found = e.buffer().FindNext(search, 0)
 	}
-	if found == -1 {
+	if !found {
 		return false
 	}
 
-	line, col := e.buffer().OffsetToPosition(found)
-	e.cursor().MoveTo(line, col, e.buffer())
-
-	// Select the found text
-	e.selection().SetRange(
-		Position{Line: line, Column: col},
-		Position{Line: line, Column: col + len([]rune(text))},
-	)
-	// Adjust end position for multi-line matches
-	endLine, endCol := e.buffer().OffsetToPosition(found + len([]rune(text)))
-	e.selection().End = Position{Line: endLine, Column: endCol}
-
-	e.ensureCursorVisible()
+	e.selectMatch(match)
 	return true
 }
 
-// FindPrevious searches backwards for text.
-func (e *Editor) FindPrevious(text string, caseSensitive bool) bool {
+// FindPrevious moves to the previous match before the cursor and selects it.
+func (e *Editor) FindPrevious(search *Search) bool {
+	e.beginJump()
+	defer e.endJump()
+	e.collapseCarets()
 	offset := e.cursor().Offset(e.buffer())
-	found := e.buffer().FindPrevious(text, offset, caseSensitive)
-	if found == -1 {
+	match, found := e.buffer().FindPrevious(search, offset)
+	if !found {
 		// Wrap around
-		found = e.buffer().FindPrevious(text, e.buffer().Length(), caseSensitive)
+		match, found = e.buffer().FindPrevious(search, e.buffer().Length()+1)
 	}
-	if found == -1 {
+	if !found {
 		return false
 	}
 
-	line, col := e.buffer().OffsetToPosition(found)
-	e.cursor().MoveTo(line, col, e.buffer())
+	e.selectMatch(match)
+	return true
+}
 
-	// Select the found text
-	endLine, endCol := e.buffer().OffsetToPosition(found + len([]rune(text)))
+// selectMatch moves the cursor to the start of a match and selects it.
+func (e *Editor) selectMatch(match Match) {
+	line, col := e.buffer().OffsetToPosition(match.Start)
+	endLine, endCol := e.buffer().OffsetToPosition(match.End)
+	e.cursor().MoveTo(line, col, e.buffer())
 	e.selection().SetRange(
 		Position{Line: line, Column: col},
 		Position{Line: endLine, Column: endCol},
 	)
-
 	e.ensureCursorVisible()
-	return true
 }
 
-// Replace replaces the current selection or next occurrence.
-func (e *Editor) Replace(find, replace string, caseSensitive bool) bool {
-	// If we have a selection that matches, replace it
+// Replace replaces the current selection if it is a match, otherwise it
+// selects the next match.
+func (e *Editor) Replace(search *Search, replacement string) bool {
+	e.collapseCarets()
+
+	// If the selection is a match, replace it
 	if e.selection().Active && !e.selection().IsEmpty() {
-		selectedText := e.selection().Text(e.buffer())
-		matches := selectedText == find
-		if !caseSensitive {
-			matches = strings.EqualFold(selectedText, find)
-		}
-		if matches {
-			e.deleteSelection()
-			e.InsertText(replace)
-			return true
+		buf := e.buffer()
+		start, end := e.selection().Normalized()
+		startOffset := buf.PositionToOffset(start.Line, start.Column)
+		endOffset := buf.PositionToOffset(end.Line, end.Column)
+
+		content := buf.Content()
+		for _, m := range search.Matches(content) {
+			if m.Start == startOffset && m.End == endOffset {
+				text := search.Expand(m, content, replacement)
+				e.replaceRange(m.Start, m.End, text)
+
+				line, col := buf.OffsetToPosition(m.Start + len([]rune(text)))
+				e.cursor().MoveTo(line, col, buf)
+				e.selection().Clear()
+				e.highlightDirty = true
+				e.ensureCursorVisible()
+				e.updateGutterWidth()
+				return true
+			}
 		}
 	}
 
 	// Otherwise find next and select it
-	return e.Find(find, caseSensitive)
+	return e.Find(search)
+}
+
+// ReplaceAll replaces all matches as a single undo step and returns the
+// number of replacements.
+func (e *Editor) ReplaceAll(search *Search, replacement string) int {
+	e.collapseCarets()
+	buf := e.buffer()
+	content := buf.Content()
+	matches := search.Matches(content)
+	if len(matches) == 0 {
+		return 0
+	}
+
+	// Replace from the end so earlier offsets stay valid
+	e.history().BeginCompound()
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		e.replaceRange(m.Start, m.End, search.Expand(m, content, replacement))
+	}
+	e.history().EndCompound([]Position{e.cursor().Position()})
+
+	e.selection().Clear()
+	e.cursor().Clamp(buf)
+	e.highlightDirty = true
+	e.ensureCursorVisible()
+	e.updateGutterWidth()
+	return len(matches)
 }
 
-// ReplaceAll replaces all occurrences.
-func (e *Editor) ReplaceAll(find, replace string, caseSensitive bool) int {
-	return e.buffer().ReplaceAll(find, replace, caseSensitive)
+// replaceRange replaces the runes in [start, end) with text and records it.
+func (e *Editor) replaceRange(start, end int, text string) {
+	buf := e.buffer()
+	old := buf.Delete(start, end-start)
+	buf.Insert(start, text)
+	e.history().RecordReplace(start, old, text, e.cursor().Position())
 }
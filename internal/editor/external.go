@@ -0,0 +1,117 @@
+package editor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrChangedOnDisk is returned by Buffer.Save when another program changed
+// the file since the buffer loaded or saved it.
+var ErrChangedOnDisk = errors.New("file changed on disk")
+
+// DiskChange tells how a file differs from the state its buffer last
+// loaded or saved.
+type DiskChange int
+
+const (
+	DiskUnchanged DiskChange = iota
+	DiskChanged
+	DiskDeleted
+)
+
+// CheckDisk compares the file with the state the buffer last loaded or
+// saved. The file is only read if its modification time or size differ;
+// a file touched without changing its content updates the state. For
+// DiskChanged, the current state of the file is returned. Buffers without
+// a file on disk, and files that cannot be read, count as unchanged.
+func (b *Buffer) CheckDisk() (DiskChange, DiskState) {
+	if b.filepath == "" || b.disk.Hash == "" {
+		return DiskUnchanged, DiskState{}
+	}
+	info, err := os.Stat(b.filepath)
+	if os.IsNotExist(err) {
+		return DiskDeleted, DiskState{}
+	}
+	if err != nil || info.ModTime().Equal(b.disk.ModTime) && info.Size() == b.disk.Size {
+		return DiskUnchanged, DiskState{}
+	}
+
+	content, err := os.ReadFile(b.filepath)
+	if err != nil {
+		return DiskUnchanged, DiskState{}
+	}
+	sum := sha256.Sum256(content)
+	state := DiskState{ModTime: info.ModTime(), Size: info.Size(), Hash: hex.EncodeToString(sum[:])}
+	if state.Hash == b.disk.Hash {
+		b.disk = state
+		return DiskUnchanged, DiskState{}
+	}
+	return DiskChanged, state
+}
+
+// Reload replaces the text with the file on disk as a single undo step and
+// marks it saved. The cursor is kept within the new text.
+func (ts *TabState) Reload() error {
+	path := ts.Filepath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	state, err := FileDiskState(path, data)
+	if err != nil {
+		return err
+	}
+
+	content := strings.ReplaceAll(ts.buffer.readText(data), "\r\n", "\n")
+	if content != ts.buffer.Content() {
+		ts.ReplaceContent(content)
+	}
+	ts.applyEditorConfig()
+	ts.buffer.modified = false
+	ts.buffer.disk = state
+	ts.history.MarkSaved()
+	return nil
+}
+
+// DiskText returns the text of the tab's file on disk, decoded in the
+// buffer's encoding as Reload decodes it, with LF line endings. The buffer
+// is not changed.
+func (ts *TabState) DiskText() (string, error) {
+	data, err := os.ReadFile(ts.Filepath())
+	if err != nil {
+		return "", err
+	}
+	text := strings.ReplaceAll(decodeText(data, ts.buffer.encoding), "\r\n", "\n")
+	if !strings.Contains(text, "\n") {
+		// CR line endings, as readText converts them
+		text = strings.ReplaceAll(text, "\r", "\n")
+	}
+	return text, nil
+}
+
+// KeepChanges keeps the text of a tab whose file changed on disk: the file
+// as it is now becomes the one the buffer is based on, so saving overwrites
+// it without ErrChangedOnDisk. Since no state of the history matches the
+// file any more, the tab stays modified until it is saved.
+func (ts *TabState) KeepChanges(state DiskState) {
+	ts.buffer.disk = state
+	ts.history.saved = nil
+}
+
+// ReloadTab reloads a tab from disk; see TabState.Reload.
+func (e *Editor) ReloadTab(ts *TabState) error {
+	if err := ts.Reload(); err != nil {
+		return err
+	}
+	e.highlightDirty = true
+	e.updateGutterWidth()
+	return nil
+}
+
+// Overwrite saves the active buffer even if its file changed on disk.
+func (e *Editor) Overwrite() error {
+	return e.saveTab(e.activeTab(), e.Filepath(), false)
+}
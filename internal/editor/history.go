@@ -2,6 +2,7 @@ package editor
 
 import (
 	"time"
+	"unicode/utf8"
 )
 
 // ActionType represents the type of edit action.
@@ -11,6 +12,7 @@ const (
 	ActionInsert ActionType = iota
 	ActionDelete
 	ActionReplace
+	ActionGroup // Several actions undone and redone as one step
 )
 
 // EditAction represents a single undoable edit operation.
@@ -21,61 +23,86 @@ type EditAction struct {
 	OldText   string // Text that was replaced/deleted (for undo)
 	Timestamp time.Time
 	CursorPos Position // Cursor position before the action
+
+	// Multi-cursor edits (ActionGroup)
+	Children []EditAction // Applied in order; each Position is valid after the previous child
+	Carets   []Position   // Positions of all carets before the action, primary first
 }
 
-// History manages undo/redo stacks for edit operations.
+// History keeps the edits of a buffer as an undo tree. Undoing and then
+// making another edit starts a new branch; the undone states stay in the
+// tree and can be reached again with GoToUndoState.
 type History struct {
-	undoStack       []EditAction
-	redoStack       []EditAction
-	maxSize         int
-	groupTimeout    time.Duration // Time window for grouping actions
-	savedUndoCount  int           // Undo stack size at last save (-1 if never saved or unreachable)
+	root    *undoState // The text the history started with
+	current *undoState
+	saved   *undoState // State at the last save (nil if trimmed away)
+	lastSeq int        // Sequence number of the newest state
+
+	maxSize      int           // Maximum number of edits between the root and the current state
+	groupTimeout time.Duration // Time window for grouping actions
+
+	// Actions collected between BeginCompound and EndCompound
+	compound   []EditAction
+	inCompound bool
+}
+
+// undoState is a state of the text in the undo tree. Every state but the
+// root is reached from its parent by redoing its action.
+type undoState struct {
+	action   EditAction
+	parent   *undoState
+	children []*undoState // Oldest first
+	next     *undoState   // Child Redo moves to: the latest one made or visited
+	seq      int          // Creation order, 0 for the root
+	depth    int          // Number of actions from the root
+	sealed   bool         // Later actions are not merged into this one
 }
 
 // NewHistory creates a new history with the specified max size.
 func NewHistory(maxSize int) *History {
+	root := &undoState{}
 	return &History{
-		undoStack:      make([]EditAction, 0, maxSize),
-		redoStack:      make([]EditAction, 0, maxSize),
-		maxSize:        maxSize,
-		groupTimeout:   500 * time.Millisecond,
-		savedUndoCount: 0, // Empty buffer is considered "saved"
+		root:         root,
+		current:      root,
+		saved:        root, // Empty buffer is considered "saved"
+		maxSize:      maxSize,
+		groupTimeout: 500 * time.Millisecond,
 	}
 }
 
-// Push adds an action to the undo stack.
-// It may merge with the previous action if they are similar and recent.
+// Push adds an action as a new state after the current one.
+// It may merge with the current state's action if they are similar and
+// recent, unless the state is saved or has branches.
 func (h *History) Push(action EditAction) {
 	action.Timestamp = time.Now()
 
-	// Clear redo stack on new action
-	// If save point was in redo stack, it's now unreachable
-	if len(h.redoStack) > 0 && h.savedUndoCount > len(h.undoStack) {
-		h.savedUndoCount = -1 // Save point is now unreachable
+	if h.inCompound {
+		h.compound = append(h.compound, action)
+		return
 	}
-	h.redoStack = h.redoStack[:0]
-
-	// Try to merge with previous action
-	if len(h.undoStack) > 0 {
-		last := &h.undoStack[len(h.undoStack)-1]
-		if h.canMerge(last, &action) {
-			h.merge(last, &action)
-			return
-		}
+
+	// Try to merge with the current action
+	if c := h.current; c != h.root && !c.sealed && c != h.saved && len(c.children) == 0 &&
+		h.canMerge(&c.action, &action) {
+		h.merge(&c.action, &action)
+		return
 	}
 
-	// Add new action
-	h.undoStack = append(h.undoStack, action)
+	// Add new state
+	h.lastSeq++
+	state := &undoState{
+		action: action,
+		parent: h.current,
+		seq:    h.lastSeq,
+		depth:  h.current.depth + 1,
+	}
+	h.current.children = append(h.current.children, state)
+	h.current.next = state
+	h.current = state
 
 	// Trim if over max size
-	if len(h.undoStack) > h.maxSize {
-		h.undoStack = h.undoStack[1:]
-		// Adjust save point (it shifted by 1, or became unreachable)
-		if h.savedUndoCount > 0 {
-			h.savedUndoCount--
-		} else if h.savedUndoCount == 0 {
-			h.savedUndoCount = -1 // Save point trimmed away
-		}
+	if h.current.depth > h.maxSize {
+		h.trim()
 	}
 }
 
@@ -98,13 +125,40 @@ func (h *History) canMerge(prev, next *EditAction) bool {
 		prevEnd := prev.Position + len([]rune(prev.Text))
 		return next.Position == prevEnd && len([]rune(next.Text)) == 1
 	case ActionDelete:
-		// Merge consecutive deletes (backspace)
-		return next.Position == prev.Position-1 || next.Position == prev.Position
+		// Merge consecutive character deletes (backspace or delete). A
+		// deleted selection stays its own step: merged into prev, its text
+		// could be restored out of order.
+		return (next.Position == prev.Position-1 || next.Position == prev.Position) &&
+			utf8.RuneCountInString(next.OldText) == 1
+	case ActionGroup:
+		// Merge multi-cursor typing when every caret continues its own edit
+		if len(prev.Children) != len(next.Children) {
+			return false
+		}
+		for i := range prev.Children {
+			child := groupChildForMerge(prev, next, i)
+			if !h.canMerge(&prev.Children[i], &child) {
+				return false
+			}
+		}
+		return true
 	}
 
 	return false
 }
 
+// groupChildForMerge returns the i-th child of next with its position moved
+// back into the coordinates prev's i-th child was recorded in. Carets are
+// edited from the end of the buffer backwards, so the children after i in
+// prev lie before it and have shifted it by their net length change.
+func groupChildForMerge(prev, next *EditAction, i int) EditAction {
+	child := next.Children[i]
+	for _, later := range prev.Children[i+1:] {
+		child.Position -= len([]rune(later.Text)) - len([]rune(later.OldText))
+	}
+	return child
+}
+
 // merge combines two actions into the first one.
 func (h *History) merge(prev, next *EditAction) {
 	switch prev.Type {
@@ -119,85 +173,112 @@ func (h *History) merge(prev, next *EditAction) {
 			// Delete forward - append text
 			prev.OldText += next.OldText
 		}
+	case ActionGroup:
+		for i := range prev.Children {
+			child := groupChildForMerge(prev, next, i)
+			h.merge(&prev.Children[i], &child)
+		}
 	}
 	prev.Timestamp = next.Timestamp
 }
 
-// Undo returns the action to undo, or nil if stack is empty.
+// Undo returns the action to undo, or nil at the root.
 func (h *History) Undo() *EditAction {
-	if len(h.undoStack) == 0 {
+	if h.current == h.root {
 		return nil
 	}
 
-	// Pop from undo stack
-	action := h.undoStack[len(h.undoStack)-1]
-	h.undoStack = h.undoStack[:len(h.undoStack)-1]
-
-	// Push to redo stack
-	h.redoStack = append(h.redoStack, action)
+	// Move to the parent; Redo comes back here
+	state := h.current
+	state.parent.next = state
+	h.current = state.parent
 
+	action := state.action
 	return &action
 }
 
-// Redo returns the action to redo, or nil if stack is empty.
+// Redo returns the action to redo, or nil if there is none.
 func (h *History) Redo() *EditAction {
-	if len(h.redoStack) == 0 {
+	state := h.current.next
+	if state == nil {
 		return nil
 	}
+	h.current = state
 
-	// Pop from redo stack
-	action := h.redoStack[len(h.redoStack)-1]
-	h.redoStack = h.redoStack[:len(h.redoStack)-1]
-
-	// Push to undo stack
-	h.undoStack = append(h.undoStack, action)
-
+	action := state.action
 	return &action
 }
 
 // CanUndo returns true if there are actions to undo.
 func (h *History) CanUndo() bool {
-	return len(h.undoStack) > 0
+	return h.current != h.root
 }
 
 // CanRedo returns true if there are actions to redo.
 func (h *History) CanRedo() bool {
-	return len(h.redoStack) > 0
+	return h.current.next != nil
 }
 
 // Clear removes all history.
 func (h *History) Clear() {
-	h.undoStack = h.undoStack[:0]
-	h.redoStack = h.redoStack[:0]
-	h.savedUndoCount = 0
+	h.root = &undoState{}
+	h.current = h.root
+	h.saved = h.root
+	h.lastSeq = 0
 }
 
-// MarkSaved marks the current position as the save point.
+// MarkSaved marks the current state as the save point.
 func (h *History) MarkSaved() {
-	h.savedUndoCount = len(h.undoStack)
+	h.saved = h.current
 }
 
-// IsAtSavePoint returns true if the current state matches the last saved state.
+// IsAtSavePoint returns true if the current state matches the last saved
+// state, on whichever branch it is.
 func (h *History) IsAtSavePoint() bool {
-	return h.savedUndoCount >= 0 && len(h.undoStack) == h.savedUndoCount
+	return h.current == h.saved
 }
 
-// UndoCount returns the number of actions in the undo stack.
+// UndoCount returns the number of actions Undo can undo.
 func (h *History) UndoCount() int {
-	return len(h.undoStack)
+	return h.current.depth
 }
 
-// RedoCount returns the number of actions in the redo stack.
+// RedoCount returns the number of actions Redo can redo.
 func (h *History) RedoCount() int {
-	return len(h.redoStack)
+	n := 0
+	for state := h.current.next; state != nil; state = state.next {
+		n++
+	}
+	return n
 }
 
 // BeginGroup starts a new action group (prevents merging with previous).
 func (h *History) BeginGroup() {
-	if len(h.undoStack) > 0 {
-		// Set timestamp to past to prevent merging
-		h.undoStack[len(h.undoStack)-1].Timestamp = time.Time{}
+	h.current.sealed = true
+}
+
+// BeginCompound starts collecting recorded actions into a single undo step.
+func (h *History) BeginCompound() {
+	h.inCompound = true
+	h.compound = nil
+}
+
+// EndCompound records the actions collected since BeginCompound as one group.
+// carets holds the caret positions before the edit, primary first.
+func (h *History) EndCompound(carets []Position) {
+	h.inCompound = false
+	actions := h.compound
+	h.compound = nil
+	if len(actions) == 0 {
+		return
 	}
+
+	h.Push(EditAction{
+		Type:      ActionGroup,
+		Children:  actions,
+		CursorPos: carets[0],
+		Carets:    carets,
+	})
 }
 
 // RecordInsert records an insert action.
@@ -244,6 +325,10 @@ func ApplyUndo(action *EditAction, buf *Buffer) Position {
 		// Undo replace = replace new text with old text
 		buf.Delete(action.Position, len([]rune(action.Text)))
 		buf.Insert(action.Position, action.OldText)
+	case ActionGroup:
+		for i := len(action.Children) - 1; i >= 0; i-- {
+			ApplyUndo(&action.Children[i], buf)
+		}
 	}
 	return action.CursorPos
 }
@@ -267,6 +352,14 @@ func ApplyRedo(action *EditAction, buf *Buffer) Position {
 		buf.Insert(action.Position, action.Text)
 		line, col := buf.OffsetToPosition(action.Position + len([]rune(action.Text)))
 		return Position{Line: line, Column: col}
+	case ActionGroup:
+		// The last child is the first caret in the buffer; its position is
+		// the only one not shifted by a later child
+		pos := action.CursorPos
+		for i := range action.Children {
+			pos = ApplyRedo(&action.Children[i], buf)
+		}
+		return pos
 	}
 	return action.CursorPos
 }
@@ -0,0 +1,72 @@
+package editor
+
+import "testing"
+
+// undoTo undoes steps until the text is want or nothing is left to undo.
+// It returns whether want was reached.
+func undoTo(e *Editor, want string) bool {
+	for e.Content() != want {
+		if !e.history().CanUndo() {
+			return false
+		}
+		e.Undo()
+	}
+	return true
+}
+
+func TestUndoMixedDeletes(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits func(e *Editor)
+	}{
+		{"delete then backspace over a selection", func(e *Editor) {
+			// Delete the "b" of "bar", then select " a" and delete it
+			moveLeft(e, 3)
+			e.Delete()
+			e.MoveCursor("left", false)
+			e.MoveCursor("right", true)
+			e.MoveCursor("right", true)
+			e.Backspace()
+		}},
+		{"typing, backspace and a selected word", func(e *Editor) {
+			e.InsertRune('a')
+			e.Backspace()
+			moveLeft(e, 3)
+			e.AddNextOccurrence()
+			e.Backspace()
+		}},
+		{"backspaces then a selection before them", func(e *Editor) {
+			e.Backspace()
+			e.Backspace()
+			moveLeft(e, 1)
+			e.MoveCursor("left", true)
+			e.MoveCursor("left", true)
+			e.Backspace()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEditor()
+			e.InsertText("foo bar")
+			tt.edits(e)
+			edited := e.Content()
+
+			if !undoTo(e, "foo bar") {
+				t.Fatalf("undoing never restored %q, ended with %q", "foo bar", e.Content())
+			}
+			for e.history().CanRedo() {
+				e.Redo()
+			}
+			if got := e.Content(); got != edited {
+				t.Errorf("after redoing everything: %q, want %q", got, edited)
+			}
+		})
+	}
+}
+
+// moveLeft moves the cursor n runes to the left.
+func moveLeft(e *Editor, n int) {
+	for range n {
+		e.MoveCursor("left", false)
+	}
+}
@@ -0,0 +1,315 @@
+package editor
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Indent describes how lines are indented.
+type Indent struct {
+	Tabs  bool // One tab per level instead of spaces
+	Width int  // Cells per level; the tab width when indenting with tabs
+}
+
+// unit returns the text of one indentation level.
+func (in Indent) unit() string {
+	if in.Tabs {
+		return "\t"
+	}
+	return strings.Repeat(" ", in.Width)
+}
+
+// DetectIndent guesses the indentation of a text from its indented lines:
+// tabs if more lines start with a tab than with spaces, otherwise the most
+// common step between the indentation of neighbouring lines. ok is false
+// if the text has too little indentation to tell. Width is 0 for tabs.
+func DetectIndent(text string) (indent Indent, ok bool) {
+	tabs, spaces := 0, 0
+	steps := make(map[int]int)
+	prev := 0
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if line[0] == '\t' {
+			tabs++
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " "))
+		if n > 0 {
+			spaces++
+		}
+		// Steps of one space are usually alignment, such as in block
+		// comments
+		if step := n - prev; step >= 2 && step <= 8 {
+			steps[step]++
+		} else if step := prev - n; step >= 2 && step <= 8 {
+			steps[step]++
+		}
+		prev = n
+	}
+
+	switch {
+	case tabs > spaces:
+		return Indent{Tabs: true}, true
+	case spaces == 0:
+		return Indent{}, false
+	}
+	best := 0
+	for step, count := range steps {
+		if best == 0 || count > steps[best] || count == steps[best] && step < best {
+			best = step
+		}
+	}
+	if best == 0 {
+		return Indent{}, false
+	}
+	return Indent{Width: best}, true
+}
+
+// Indent returns the indentation of the active tab: the one its
+// .editorconfig sets, the one detected in its file, or the configured one,
+// in that order.
+func (e *Editor) Indent() Indent {
+	tab := e.activeTab()
+	indent := Indent{Tabs: !e.insertSpaces, Width: e.tabWidth}
+	if tab.indent != nil {
+		indent = *tab.indent
+	}
+	switch ec := tab.editorConfig; ec.IndentStyle {
+	case "tab":
+		indent.Tabs = true
+	case "space":
+		indent.Tabs = false
+		if ec.IndentSize > 0 {
+			indent.Width = ec.IndentSize
+		}
+	default:
+		if ec.IndentSize > 0 && !indent.Tabs {
+			indent.Width = ec.IndentSize
+		}
+	}
+	if indent.Tabs || indent.Width == 0 {
+		indent.Width = e.TabWidth()
+	}
+	return indent
+}
+
+// leadingWhitespace returns the spaces and tabs a line starts with.
+func leadingWhitespace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// blockOpeners returns the characters that open an indented block when a
+// line ends with them, in the given language.
+func blockOpeners(language string) string {
+	switch language {
+	case "plain", "plaintext", "Markdown", "reStructuredText", "TeX":
+		return ""
+	case "Python", "Python 2", "YAML", "Nim", "CoffeeScript":
+		return "{[(:"
+	}
+	return "{[("
+}
+
+// closers maps closing brackets to their opening ones.
+var closers = map[rune]rune{'}': '{', ']': '[', ')': '('}
+
+// opensBlock returns true if a line ends with a character that opens an
+// indented block.
+func (e *Editor) opensBlock(text string) bool {
+	text = strings.TrimRight(text, " \t")
+	if text == "" {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(text)
+	return strings.ContainsRune(blockOpeners(e.Language()), last)
+}
+
+// IndentLines adds one level of indentation to the lines of every caret.
+func (e *Editor) IndentLines() {
+	unit := e.Indent().unit()
+	e.editLines(func(line string) (int, string) {
+		if line == "" {
+			return 0, ""
+		}
+		return 0, unit
+	})
+}
+
+// OutdentLines removes one level of indentation from the lines of every
+// caret.
+func (e *Editor) OutdentLines() {
+	indent := e.Indent()
+	e.editLines(func(line string) (int, string) {
+		if strings.HasPrefix(line, "\t") {
+			return 1, ""
+		}
+		n := len(line) - len(strings.TrimLeft(line, " "))
+		return min(n, indent.Width), ""
+	})
+}
+
+// editLines replaces the first n runes of each line touched by a caret with
+// text, as returned by edit, in one undo step. A selection ending at the
+// start of a line does not touch it. Carets and selections stay on the same
+// text, but a position at the start of a line stays there.
+func (e *Editor) editLines(edit func(line string) (n int, text string)) {
+	tab := e.activeTab()
+	buf := tab.Buffer()
+
+	lines := make(map[int]bool)
+	for _, c := range tab.Carets() {
+		start, end := c.span()
+		if end.Line > start.Line && end.Column == 0 {
+			end.Line--
+		}
+		for line := start.Line; line <= end.Line; line++ {
+			lines[line] = true
+		}
+	}
+
+	// Edit from the last line so offsets stay valid
+	deltas := make(map[int]int)
+	before := []Position{tab.Cursor().Position()}
+	for _, c := range tab.Carets() {
+		if c.Cursor != tab.Cursor() {
+			before = append(before, c.Cursor.Position())
+		}
+	}
+	e.history().BeginCompound()
+	for line := buf.LineCount() - 1; line >= 0; line-- {
+		if !lines[line] {
+			continue
+		}
+		n, text := edit(buf.Line(line))
+		if n == 0 && text == "" {
+			continue
+		}
+		start := buf.PositionToOffset(line, 0)
+		old := buf.Delete(start, n)
+		buf.Insert(start, text)
+		e.history().RecordReplace(start, old, text, before[0])
+		deltas[line] = utf8.RuneCountInString(text) - n
+	}
+	e.history().EndCompound(before)
+
+	shift := func(pos Position) Position {
+		if pos.Column > 0 {
+			pos.Column = max(pos.Column+deltas[pos.Line], 0)
+		}
+		return pos
+	}
+	for _, c := range tab.Carets() {
+		pos := shift(c.Cursor.Position())
+		c.Cursor.MoveTo(pos.Line, pos.Column, buf)
+		if c.Selection.Active {
+			c.Selection.Start = shift(c.Selection.Start)
+			c.Selection.End = shift(c.Selection.End)
+		}
+	}
+	tab.MergeCarets()
+
+	e.highlightDirty = true
+	e.ensureCursorVisible()
+}
+
+// spansLines returns true if a caret selects text across lines.
+func (e *Editor) spansLines() bool {
+	for _, c := range e.activeTab().Carets() {
+		start, end := c.span()
+		if end.Line > start.Line {
+			return true
+		}
+	}
+	return false
+}
+
+// newlineIndent returns what to insert for a line break at a caret: a line
+// break with the indentation of its line, one level more after a block
+// opener. If the caret is right between an opener and its closer, after is
+// a second line break that moves the closer below the caret's new line.
+func (e *Editor) newlineIndent(c *Caret) (text, after string) {
+	line := e.buffer().Line(c.Cursor.Line)
+	runes := []rune(line)
+	col := min(c.Cursor.Column, len(runes))
+	indent := leadingWhitespace(line)
+	if len(indent) > col {
+		indent = indent[:col]
+	}
+	beforeCaret := string(runes[:col])
+	if !e.opensBlock(beforeCaret) {
+		return "\n" + indent, ""
+	}
+
+	text = "\n" + indent + e.Indent().unit()
+	rest := strings.TrimLeft(string(runes[col:]), " \t")
+	last, _ := utf8.DecodeLastRuneInString(strings.TrimRight(beforeCaret, " \t"))
+	if next, _ := utf8.DecodeRuneInString(rest); closers[next] == last {
+		after = "\n" + indent
+	}
+	return text, after
+}
+
+// maxDedentScan caps the lines dedentFor looks back for an opener, so a
+// closer typed far from any opener does not scan the whole file.
+const maxDedentScan = 1000
+
+// dedentFor returns the indentation a line should get when closer is typed
+// at the end of its leading whitespace: that of the line with the matching
+// opener. ok is false if closer is no closing bracket or the opener is not
+// found.
+func (e *Editor) dedentFor(lineNum int, closer rune) (indent string, ok bool) {
+	opener, ok := closers[closer]
+	if !ok || !strings.ContainsRune(blockOpeners(e.Language()), opener) {
+		return "", false
+	}
+
+	// Count brackets backwards from the line before, up to maxDedentScan
+	// lines; brackets in strings and comments count too
+	buf := e.buffer()
+	depth := 0
+	for line := lineNum - 1; line >= max(lineNum-maxDedentScan, 0); line-- {
+		text := buf.Line(line)
+		// Brackets are ASCII, so the bytes can be scanned
+		for i := len(text) - 1; i >= 0; i-- {
+			switch rune(text[i]) {
+			case closer:
+				depth++
+			case opener:
+				if depth == 0 {
+					return leadingWhitespace(text), true
+				}
+				depth--
+			}
+		}
+	}
+	return "", false
+}
+
+// dedentAt types a closing bracket at a caret that only has whitespace
+// before it on its line, giving the line the indentation of the matching
+// opener. Returns false if that does not apply.
+func (e *Editor) dedentAt(c *Caret, closer rune) bool {
+	if c.hasSelection() {
+		return false
+	}
+	buf := e.buffer()
+	line := buf.Line(c.Cursor.Line)
+	before := string([]rune(line)[:min(c.Cursor.Column, utf8.RuneCountInString(line))])
+	if strings.TrimLeft(before, " \t") != "" {
+		return false
+	}
+	indent, ok := e.dedentFor(c.Cursor.Line, closer)
+	if !ok || indent == before {
+		return false
+	}
+
+	start := buf.PositionToOffset(c.Cursor.Line, 0)
+	text := indent + string(closer)
+	old := buf.Delete(start, c.Cursor.Column)
+	buf.Insert(start, text)
+	e.history().RecordReplace(start, old, text, c.Cursor.Position())
+	c.Cursor.MoveTo(c.Cursor.Line, utf8.RuneCountInString(text), buf)
+	return true
+}
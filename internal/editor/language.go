@@ -0,0 +1,120 @@
+package editor
+
+import (
+	"path/filepath"
+
+	"github.com/DDZ-DO/vex/internal/lsp"
+)
+
+// languageDoc is an open file as last sent to the language servers.
+type languageDoc struct {
+	path    string
+	version int
+	saves   int
+}
+
+// SetLanguageServers connects the editor to language servers. Open files
+// are sent to them by the next SyncDocuments.
+func (e *Editor) SetLanguageServers(m *lsp.Manager) {
+	e.lsp = m
+	e.documents = make(map[*TabState]*languageDoc)
+}
+
+// LanguageServers returns the connected language servers, or nil.
+func (e *Editor) LanguageServers() *lsp.Manager {
+	return e.lsp
+}
+
+// SyncDocuments tells the language servers which files were opened,
+// changed, saved or closed since the last call. It is cheap when nothing
+// changed, so the app calls it after every update.
+func (e *Editor) SyncDocuments() {
+	if e.lsp == nil {
+		return
+	}
+
+	open := make(map[*TabState]bool)
+	for _, tab := range e.tabManager.Tabs() {
+		if tab.Filepath() == "" {
+			continue
+		}
+		path, err := filepath.Abs(tab.Filepath())
+		if err != nil || !e.lsp.Handles(path) {
+			continue
+		}
+		open[tab] = true
+		buf := tab.Buffer()
+
+		doc := e.documents[tab]
+		if doc != nil && doc.path != path {
+			// Saved under a new name
+			e.lsp.Close(doc.path)
+			doc = nil
+		}
+		if doc == nil {
+			e.lsp.Open(path, buf.Content(), buf.Version())
+			e.documents[tab] = &languageDoc{path: path, version: buf.Version(), saves: buf.SaveCount()}
+			continue
+		}
+		if doc.version == buf.Version() && doc.saves == buf.SaveCount() {
+			continue
+		}
+
+		// The text is only built when it changed or was saved
+		text := buf.Content()
+		if doc.version != buf.Version() {
+			doc.version = buf.Version()
+			e.lsp.Change(path, text, doc.version)
+		}
+		if doc.saves != buf.SaveCount() {
+			doc.saves = buf.SaveCount()
+			e.lsp.Save(path, text)
+		}
+	}
+
+	for tab, doc := range e.documents {
+		if !open[tab] {
+			e.lsp.Close(doc.path)
+			delete(e.documents, tab)
+		}
+	}
+}
+
+// DocumentPosition returns the absolute path of the active file and the
+// cursor position as language servers count it. ok is false for untitled
+// files.
+func (e *Editor) DocumentPosition() (path string, pos lsp.Position, ok bool) {
+	if e.Filepath() == "" {
+		return "", pos, false
+	}
+	path, err := filepath.Abs(e.Filepath())
+	if err != nil {
+		return "", pos, false
+	}
+	cursor := e.cursor()
+	pos.Line = cursor.Line
+	pos.Character = lsp.Character(e.buffer().Line(cursor.Line), cursor.Column)
+	return path, pos, true
+}
+
+// GoToLocation opens the file of a location and puts the cursor at its
+// start.
+func (e *Editor) GoToLocation(loc lsp.Location) error {
+	e.beginJump()
+	defer e.endJump()
+	if err := e.LoadFile(loc.Path); err != nil {
+		return err
+	}
+	buf := e.buffer()
+	line := min(max(loc.Range.Start.Line, 0), buf.LineCount()-1)
+	e.jumpTo(line, lsp.RuneColumn(buf.Line(line), loc.Range.Start.Character))
+	return nil
+}
+
+// jumpTo moves a single cursor to a position and scrolls it into view.
+func (e *Editor) jumpTo(line, col int) {
+	e.collapseCarets()
+	e.cursor().MoveTo(line, col, e.buffer())
+	e.selection().Clear()
+	e.ensureCursorVisible()
+}
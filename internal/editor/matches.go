@@ -0,0 +1,193 @@
+package editor
+
+import "sort"
+
+// matchTracker keeps the matches of the active search in a buffer up to
+// date while the buffer is edited. Plain-text searches are updated
+// incrementally by rescanning only the lines touched by an edit; other
+// searches are recomputed on the next access.
+type matchTracker struct {
+	search  *Search
+	buffer  *Buffer
+	matches []Match
+	valid   bool
+
+	removeListener func()
+}
+
+// newMatchTracker starts tracking the matches of search in buf.
+func newMatchTracker(search *Search, buf *Buffer) *matchTracker {
+	t := &matchTracker{search: search, buffer: buf}
+	t.removeListener = buf.AddEditListener(t.bufferEdited)
+	return t
+}
+
+// detach stops listening to buffer edits.
+func (t *matchTracker) detach() {
+	t.removeListener()
+}
+
+// list returns the current matches, recomputing them if necessary.
+func (t *matchTracker) list() []Match {
+	if !t.valid {
+		t.matches = t.buffer.FindAll(t.search)
+		t.valid = true
+	}
+	return t.matches
+}
+
+// bufferEdited updates the matches after runes were removed and inserted
+// at offset.
+func (t *matchTracker) bufferEdited(offset, removed, inserted int) {
+	if !t.valid {
+		return
+	}
+	if !t.search.lineLocal {
+		t.valid = false
+		return
+	}
+
+	// Rescan the lines touched by the edit (in new coordinates)
+	buf := t.buffer
+	startLine, _ := buf.OffsetToPosition(offset)
+	endLine, _ := buf.OffsetToPosition(offset + inserted)
+	winStart := buf.PositionToOffset(startLine, 0)
+	winEnd := buf.PositionToOffset(endLine, buf.LineLength(endLine))
+
+	// Matches past the edit move by the change in length
+	delta := inserted - removed
+	oldEnd := offset + removed
+
+	updated := make([]Match, 0, len(t.matches))
+	for _, m := range t.matches {
+		if m.End <= winStart {
+			updated = append(updated, m)
+		}
+	}
+	for _, m := range buf.search(t.search, winStart, winEnd) {
+		updated = append(updated, m)
+	}
+	for _, m := range t.matches {
+		if m.Start >= oldEnd && m.Start+delta > winEnd {
+			m.Start += delta
+			m.End += delta
+			updated = append(updated, m)
+		}
+	}
+	t.matches = updated
+}
+
+// index returns the position of the match covering exactly [start, end),
+// or -1.
+func (t *matchTracker) index(start, end int) int {
+	matches := t.list()
+	i := sort.Search(len(matches), func(i int) bool {
+		return matches[i].Start >= start
+	})
+	if i < len(matches) && matches[i].Start == start && matches[i].End == end {
+		return i
+	}
+	return -1
+}
+
+// inRange returns the matches overlapping [start, end).
+func (t *matchTracker) inRange(start, end int) []Match {
+	matches := t.list()
+	i := sort.Search(len(matches), func(i int) bool {
+		return matches[i].End > start
+	})
+	j := i
+	for j < len(matches) && matches[j].Start < end {
+		j++
+	}
+	return matches[i:j]
+}
+
+// SetSearchHighlight highlights every match of search in the active buffer.
+// A nil search turns match highlighting off.
+func (e *Editor) SetSearchHighlight(search *Search) {
+	if e.matches != nil {
+		e.matches.detach()
+		e.matches = nil
+	}
+	e.search = search
+}
+
+// searchMatches returns the match tracker for the active buffer, or nil if
+// no search is highlighted.
+func (e *Editor) searchMatches() *matchTracker {
+	if e.search == nil {
+		return nil
+	}
+	if e.matches == nil || e.matches.buffer != e.buffer() {
+		// The active tab changed
+		if e.matches != nil {
+			e.matches.detach()
+		}
+		e.matches = newMatchTracker(e.search, e.buffer())
+	}
+	return e.matches
+}
+
+// SearchMatchInfo returns the 1-based index of the selected match and the
+// total number of matches. The index is 0 if the selection is not a match.
+func (e *Editor) SearchMatchInfo() (current, total int) {
+	t := e.searchMatches()
+	if t == nil {
+		return 0, 0
+	}
+	total = len(t.list())
+	if i := t.index(e.primarySelectionOffsets()); i >= 0 {
+		current = i + 1
+	}
+	return current, total
+}
+
+// primarySelectionOffsets returns the primary selection as buffer offsets.
+// Without a selection both offsets are -1.
+func (e *Editor) primarySelectionOffsets() (start, end int) {
+	if e.selection().IsEmpty() {
+		return -1, -1
+	}
+	from, to := e.selection().Normalized()
+	buf := e.buffer()
+	return buf.PositionToOffset(from.Line, from.Column), buf.PositionToOffset(to.Line, to.Column)
+}
+
+// lineMatches returns the column ranges of the matches on a line and the
+// index of the selected match among them (-1 if none).
+func (e *Editor) lineMatches(lineNum, lineLen int) (ranges [][2]int, current int) {
+	current = -1
+	t := e.searchMatches()
+	if t == nil {
+		return nil, current
+	}
+
+	lineStart := e.buffer().PositionToOffset(lineNum, 0)
+	selStart, selEnd := e.primarySelectionOffsets()
+	for _, m := range t.inRange(lineStart, lineStart+lineLen) {
+		if m.Start == selStart && m.End == selEnd {
+			current = len(ranges)
+		}
+		ranges = append(ranges, [2]int{m.Start - lineStart, m.End - lineStart})
+	}
+	return ranges, current
+}
+
+// FindIncremental selects the first match at or after the cursor. It is used
+// while the search text is typed, so the current match stays selected as
+// long as it still matches.
+func (e *Editor) FindIncremental(search *Search) bool {
+	e.collapseCarets()
+	offset := e.cursor().Offset(e.buffer())
+	match, found := e.buffer().FindNext(search, offset)
+	if !found {
+		match, found = e.buffer().FindNext(search, 0)
+	}
+	if !found {
+		return false
+	}
+
+	e.selectMatch(match)
+	return true
+}
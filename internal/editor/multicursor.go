@@ -0,0 +1,264 @@
+package editor
+
+import (
+	"sort"
+	"strings"
+)
+
+// editCarets applies edit to every caret. Carets are edited from the last to
+// the first in the buffer, so the offsets of carets not yet edited stay
+// valid. With more than one caret the edits are recorded in History as a
+// single undo step.
+func (e *Editor) editCarets(edit func(i int, c *Caret)) {
+	tab := e.activeTab()
+	buf := tab.Buffer()
+
+	if !tab.HasMultipleCarets() {
+		edit(0, tab.PrimaryCaret())
+	} else {
+		carets := tab.Carets()
+
+		// Positions before the edit (primary first) for undo
+		before := []Position{tab.Cursor().Position()}
+		for _, c := range carets {
+			if c.Cursor != tab.Cursor() {
+				before = append(before, c.Cursor.Position())
+			}
+		}
+
+		// Track the resulting offsets, shifting carets after each edit
+		offsets := make([]int, len(carets))
+		e.history().BeginCompound()
+		for i := len(carets) - 1; i >= 0; i-- {
+			length := buf.Length()
+			edit(i, carets[i])
+			offsets[i] = carets[i].Cursor.Offset(buf)
+
+			delta := buf.Length() - length
+			for j := i + 1; j < len(carets); j++ {
+				offsets[j] += delta
+			}
+		}
+		e.history().EndCompound(before)
+
+		for i, c := range carets {
+			line, col := buf.OffsetToPosition(offsets[i])
+			c.Cursor.MoveTo(line, col, buf)
+		}
+		tab.MergeCarets()
+	}
+
+	e.highlightDirty = true
+	e.ensureCursorVisible()
+	e.updateGutterWidth()
+}
+
+// collapseCarets removes all secondary carets.
+func (e *Editor) collapseCarets() {
+	e.activeTab().ClearExtraCarets()
+}
+
+// CollapseCarets removes all secondary carets, keeping the primary one.
+func (e *Editor) CollapseCarets() {
+	e.collapseCarets()
+}
+
+// HasMultipleCarets returns true if more than one caret is active.
+func (e *Editor) HasMultipleCarets() bool {
+	return e.activeTab().HasMultipleCarets()
+}
+
+// CaretCount returns the number of carets in the active tab.
+func (e *Editor) CaretCount() int {
+	return len(e.activeTab().extraCarets) + 1
+}
+
+// anyCaretSelection returns true if at least one caret selects text.
+func (e *Editor) anyCaretSelection() bool {
+	for _, c := range e.activeTab().Carets() {
+		if c.hasSelection() {
+			return true
+		}
+	}
+	return false
+}
+
+// copyCarets returns the selected text of every caret, one per line. If no
+// caret selects anything, the lines of all carets are returned instead.
+func (e *Editor) copyCarets() string {
+	buf := e.buffer()
+	carets := e.activeTab().Carets()
+
+	if e.anyCaretSelection() {
+		parts := make([]string, 0, len(carets))
+		for _, c := range carets {
+			parts = append(parts, c.Selection.Text(buf))
+		}
+		return strings.Join(parts, "\n")
+	}
+
+	var result strings.Builder
+	lastLine := -1
+	for _, c := range carets {
+		if c.Cursor.Line != lastLine {
+			result.WriteString(buf.Line(c.Cursor.Line) + "\n")
+			lastLine = c.Cursor.Line
+		}
+	}
+	return result.String()
+}
+
+// addPrimaryCaret keeps the current primary caret as a secondary one and
+// moves the primary to a new caret selecting [start, end).
+func (e *Editor) addPrimaryCaret(start, end Position) {
+	tab := e.activeTab()
+	tab.PushPrimaryCaret()
+
+	tab.Cursor().MoveTo(end.Line, end.Column, tab.Buffer())
+	if start == end {
+		tab.Selection().Clear()
+	} else {
+		tab.Selection().SetRange(start, end)
+	}
+
+	tab.MergeCarets()
+	e.ensureCursorVisible()
+}
+
+// AddCursorAbove adds a caret on the line above the topmost caret.
+func (e *Editor) AddCursorAbove() {
+	top := e.activeTab().Carets()[0].Cursor
+	e.addCursorOnLine(top.Line-1, top.PreferredX(e.buffer(), e.TabWidth()))
+}
+
+// AddCursorBelow adds a caret on the line below the bottommost caret.
+func (e *Editor) AddCursorBelow() {
+	carets := e.activeTab().Carets()
+	bottom := carets[len(carets)-1].Cursor
+	e.addCursorOnLine(bottom.Line+1, bottom.PreferredX(e.buffer(), e.TabWidth()))
+}
+
+// addCursorOnLine adds a caret on line, as close to cell x as possible.
+func (e *Editor) addCursorOnLine(line, x int) {
+	if line < 0 || line >= e.buffer().LineCount() {
+		return
+	}
+
+	pos := Position{Line: line, Column: columnAtCell(e.layout(line), x)}
+	e.addPrimaryCaret(pos, pos)
+	e.cursor().MoveToCell(line, x, e.buffer(), e.TabWidth())
+}
+
+// AddCaretAt adds a caret at a screen position (e.g. Alt+Click).
+func (e *Editor) AddCaretAt(x, y int) {
+	pos := e.screenToPosition(x, y)
+	e.addPrimaryCaret(pos, pos)
+}
+
+// occurrenceText returns the text to search for when adding occurrences.
+// Without a selection, the word at the cursor is selected first and false
+// is returned, so the first invocation only selects the word.
+func (e *Editor) occurrenceText() (string, bool) {
+	if !e.selection().IsEmpty() {
+		return e.selection().Text(e.buffer()), true
+	}
+	e.selection().SelectWord(e.buffer(), e.cursor())
+	if !e.selection().IsEmpty() {
+		_, end := e.selection().Normalized()
+		e.cursor().MoveTo(end.Line, end.Column, e.buffer())
+	}
+	return "", false
+}
+
+// occurrences returns the case-sensitive literal matches of text.
+func (e *Editor) occurrences(text string) []Match {
+	search, err := NewSearch(text, SearchOptions{CaseSensitive: true})
+	if err != nil {
+		return nil
+	}
+	return e.buffer().FindAll(search)
+}
+
+// AddNextOccurrence adds a caret selecting the next occurrence of the
+// primary selection. Without a selection, the word at the cursor is
+// selected. Returns false if there is no further occurrence.
+func (e *Editor) AddNextOccurrence() bool {
+	text, ok := e.occurrenceText()
+	if !ok {
+		return !e.selection().IsEmpty()
+	}
+
+	buf := e.buffer()
+	matches := e.occurrences(text)
+	if len(matches) == 0 {
+		return false
+	}
+
+	// Occurrences already selected by a caret
+	taken := make(map[int]bool)
+	for _, c := range e.activeTab().Carets() {
+		if c.hasSelection() {
+			start, _ := c.Selection.Normalized()
+			taken[buf.PositionToOffset(start.Line, start.Column)] = true
+		}
+	}
+
+	// Search after the primary selection, wrapping around
+	_, end := e.selection().Normalized()
+	from := buf.PositionToOffset(end.Line, end.Column)
+	first := sort.Search(len(matches), func(i int) bool {
+		return matches[i].Start >= from
+	})
+	length := len([]rune(text))
+
+	for i := range matches {
+		match := matches[(first+i)%len(matches)].Start
+		if taken[match] {
+			continue
+		}
+		startLine, startCol := buf.OffsetToPosition(match)
+		endLine, endCol := buf.OffsetToPosition(match + length)
+		e.addPrimaryCaret(
+			Position{Line: startLine, Column: startCol},
+			Position{Line: endLine, Column: endCol},
+		)
+		return true
+	}
+	return false
+}
+
+// SelectAllOccurrences puts a caret on every occurrence of the primary
+// selection (or the word at the cursor). Returns the number of carets.
+func (e *Editor) SelectAllOccurrences() int {
+	text, ok := e.occurrenceText()
+	if !ok {
+		if e.selection().IsEmpty() {
+			return 0
+		}
+		text = e.selection().Text(e.buffer())
+	}
+
+	tab := e.activeTab()
+	buf := tab.Buffer()
+	primaryStart, _ := tab.Selection().Normalized()
+	primaryOffset := buf.PositionToOffset(primaryStart.Line, primaryStart.Column)
+
+	tab.ClearExtraCarets()
+	for _, match := range e.occurrences(text) {
+		if match.Start == primaryOffset {
+			continue
+		}
+		startLine, startCol := buf.OffsetToPosition(match.Start)
+		endLine, endCol := buf.OffsetToPosition(match.End)
+
+		c := newCaretAt(Position{Line: endLine, Column: endCol})
+		c.Selection.SetRange(
+			Position{Line: startLine, Column: startCol},
+			Position{Line: endLine, Column: endCol},
+		)
+		tab.AddCaret(c)
+	}
+	tab.MergeCarets()
+
+	return e.CaretCount()
+}
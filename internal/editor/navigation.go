@@ -0,0 +1,154 @@
+package editor
+
+import "path/filepath"
+
+const (
+	// navigationMax is the number of locations kept in each direction.
+	navigationMax = 100
+	// jumpDistance is how many lines a movement within a file must span to
+	// be recorded as a jump.
+	jumpDistance = 10
+)
+
+// Location is a cursor position in a file.
+type Location struct {
+	Path   string // Absolute file path
+	Line   int
+	Column int
+}
+
+// navigationHistory holds the locations jumped away from. Going back moves
+// the current location to forward, going forward moves it back again.
+type navigationHistory struct {
+	back    []Location
+	forward []Location
+
+	// Outer jump in progress and where it started
+	jumpDepth  int
+	jumpFrom   Location
+	jumpFromOK bool
+
+	navigating bool // Going back or forward; tab switches are not recorded
+}
+
+// beginJump starts a movement that may take the cursor far away, such as
+// go to line or opening a file. The matching endJump records where it
+// started if it went to another file or far within the file. Nested jumps
+// are recorded once, and tab switches during a jump are not recorded on
+// their own.
+func (e *Editor) beginJump() {
+	if e.navigation.jumpDepth == 0 {
+		e.navigation.jumpFrom, e.navigation.jumpFromOK = e.location()
+	}
+	e.navigation.jumpDepth++
+}
+
+// endJump ends a movement started with beginJump.
+func (e *Editor) endJump() {
+	e.navigation.jumpDepth--
+	if e.navigation.jumpDepth > 0 || !e.navigation.jumpFromOK {
+		return
+	}
+	from := e.navigation.jumpFrom
+	to, ok := e.location()
+	if !ok || to.Path != from.Path || max(to.Line-from.Line, from.Line-to.Line) >= jumpDistance {
+		e.recordLocation(from)
+	}
+}
+
+// leaveTab records the cursor of a tab that is switched away from or
+// closed.
+func (e *Editor) leaveTab(tab *TabState) {
+	if e.navigation.jumpDepth > 0 || e.navigation.navigating {
+		return
+	}
+	if loc, ok := tabLocation(tab); ok {
+		e.recordLocation(loc)
+	}
+}
+
+// recordLocation adds a location to go back to and drops the locations to
+// go forward to.
+func (e *Editor) recordLocation(loc Location) {
+	pushLocation(&e.navigation.back, loc)
+	e.navigation.forward = nil
+}
+
+// GoBack returns to the location before the last jump, reopening its file
+// if it was closed. Returns false if there is none.
+func (e *Editor) GoBack() bool {
+	return e.navigate(&e.navigation.back, &e.navigation.forward)
+}
+
+// GoForward undoes GoBack. Returns false if there is nothing to go forward
+// to.
+func (e *Editor) GoForward() bool {
+	return e.navigate(&e.navigation.forward, &e.navigation.back)
+}
+
+// navigate goes to the latest location of from and adds the current one to
+// to. Locations whose file can no longer be opened are dropped.
+func (e *Editor) navigate(from, to *[]Location) bool {
+	current, ok := e.location()
+	for len(*from) > 0 {
+		loc := (*from)[len(*from)-1]
+		*from = (*from)[:len(*from)-1]
+		if ok && loc.Path == current.Path && loc.Line == current.Line {
+			continue
+		}
+		if err := e.openLocation(loc); err != nil {
+			continue
+		}
+		if ok {
+			pushLocation(to, current)
+		}
+		return true
+	}
+	return false
+}
+
+// openLocation opens the file of a location and moves the cursor there.
+func (e *Editor) openLocation(loc Location) error {
+	e.navigation.navigating = true
+	defer func() { e.navigation.navigating = false }()
+
+	if _, err := e.tabManager.AddTabFromFile(loc.Path); err != nil {
+		return err
+	}
+	e.highlightDirty = true
+	e.updateGutterWidth()
+	e.jumpTo(loc.Line, loc.Column)
+	return nil
+}
+
+// location returns the cursor position in the active file. ok is false for
+// untitled tabs, which cannot be reopened.
+func (e *Editor) location() (Location, bool) {
+	return tabLocation(e.activeTab())
+}
+
+// tabLocation returns the cursor position of a tab.
+func tabLocation(tab *TabState) (Location, bool) {
+	if tab == nil || tab.Filepath() == "" {
+		return Location{}, false
+	}
+	path, err := filepath.Abs(tab.Filepath())
+	if err != nil {
+		return Location{}, false
+	}
+	cursor := tab.Cursor()
+	return Location{Path: path, Line: cursor.Line, Column: cursor.Column}, true
+}
+
+// pushLocation adds a location to a stack, replacing a top entry on the
+// same line and dropping the oldest entries beyond navigationMax.
+func pushLocation(stack *[]Location, loc Location) {
+	if n := len(*stack); n > 0 && (*stack)[n-1].Path == loc.Path && (*stack)[n-1].Line == loc.Line {
+		(*stack)[n-1] = loc
+		return
+	}
+	*stack = append(*stack, loc)
+	if len(*stack) > navigationMax {
+		*stack = (*stack)[len(*stack)-navigationMax:]
+	}
+}
@@ -0,0 +1,32 @@
+package editor
+
+import "os"
+
+// RecoverFile opens a file and replaces its text with recovered content as
+// a single undo step, so undo shows the version on disk. A file that no
+// longer exists is opened as a new buffer with that path.
+func (e *Editor) RecoverFile(path, content string) error {
+	if err := e.LoadFile(path); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		e.NewFile()
+		e.buffer().SetFilepath(path)
+		e.highlighter().SetLanguageFromPath(path)
+	}
+	e.activeTab().ReplaceContent(content)
+	e.highlightDirty = true
+	e.updateGutterWidth()
+	return nil
+}
+
+// RecoverUntitled opens recovered content in an untitled tab. An untouched
+// untitled tab that is active is reused.
+func (e *Editor) RecoverUntitled(content string) {
+	if !e.activeTab().isPristine() {
+		e.NewFile()
+	}
+	e.activeTab().ReplaceContent(content)
+	e.highlightDirty = true
+	e.updateGutterWidth()
+}
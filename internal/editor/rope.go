@@ -0,0 +1,323 @@
+package editor
+
+// ropeLeafMax is the maximum number of runes stored in a single rope leaf.
+const ropeLeafMax = 1024
+
+// ropeNode is a node of a height-balanced (AVL) rope.
+// Leaves hold the text; internal nodes always have two children and cache the
+// rune count, newline count and height of their subtree. Those cached counts
+// form the buffer's line index: an edit only updates the nodes on one
+// root-to-leaf path, so offset and line lookups stay O(log n) regardless of
+// file size.
+type ropeNode struct {
+	left     *ropeNode
+	right    *ropeNode
+	leaf     []rune
+	length   int
+	newlines int
+	height   int
+}
+
+// isLeaf returns true if the node holds text directly.
+func (n *ropeNode) isLeaf() bool {
+	return n.left == nil && n.right == nil
+}
+
+// update recomputes the cached counts of an internal node from its children.
+func (n *ropeNode) update() {
+	n.length = n.left.length + n.right.length
+	n.newlines = n.left.newlines + n.right.newlines
+	n.height = n.left.height
+	if n.right.height > n.height {
+		n.height = n.right.height
+	}
+	n.height++
+}
+
+// ropeLength returns the rune count of a (possibly nil) rope.
+func ropeLength(n *ropeNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.length
+}
+
+// ropeNewlines returns the newline count of a (possibly nil) rope.
+func ropeNewlines(n *ropeNode) int {
+	if n == nil {
+		return 0
+	}
+	return n.newlines
+}
+
+// countNewlines counts '\n' runes in a slice.
+func countNewlines(runes []rune) int {
+	count := 0
+	for _, r := range runes {
+		if r == '\n' {
+			count++
+		}
+	}
+	return count
+}
+
+// newRopeLeaf creates a leaf owning the given runes.
+func newRopeLeaf(runes []rune) *ropeNode {
+	return &ropeNode{
+		leaf:     runes,
+		length:   len(runes),
+		newlines: countNewlines(runes),
+		height:   1,
+	}
+}
+
+// newRopeNode creates an internal node joining two non-nil subtrees.
+func newRopeNode(left, right *ropeNode) *ropeNode {
+	n := &ropeNode{left: left, right: right}
+	n.update()
+	return n
+}
+
+// buildRope builds a balanced rope from runes. Leaves reference disjoint,
+// capacity-limited windows of the slice, so the caller must not reuse it.
+func buildRope(runes []rune) *ropeNode {
+	if len(runes) == 0 {
+		return nil
+	}
+	if len(runes) <= ropeLeafMax {
+		return newRopeLeaf(runes[:len(runes):len(runes)])
+	}
+
+	// Split on a leaf boundary so every leaf but the last is full
+	leaves := (len(runes) + ropeLeafMax - 1) / ropeLeafMax
+	mid := (leaves / 2) * ropeLeafMax
+	return newRopeNode(buildRope(runes[:mid:mid]), buildRope(runes[mid:]))
+}
+
+// ropeRotateLeft rotates an internal node whose right child is internal.
+func ropeRotateLeft(n *ropeNode) *ropeNode {
+	r := n.right
+	n.right = r.left
+	n.update()
+	r.left = n
+	r.update()
+	return r
+}
+
+// ropeRotateRight rotates an internal node whose left child is internal.
+func ropeRotateRight(n *ropeNode) *ropeNode {
+	l := n.left
+	n.left = l.right
+	n.update()
+	l.right = n
+	l.update()
+	return l
+}
+
+// ropeRebalance restores the AVL invariant at n after one child changed height.
+func ropeRebalance(n *ropeNode) *ropeNode {
+	n.update()
+	balance := n.left.height - n.right.height
+
+	if balance > 1 {
+		if n.left.left.height < n.left.right.height {
+			n.left = ropeRotateLeft(n.left)
+		}
+		return ropeRotateRight(n)
+	}
+	if balance < -1 {
+		if n.right.right.height < n.right.left.height {
+			n.right = ropeRotateRight(n.right)
+		}
+		return ropeRotateLeft(n)
+	}
+	return n
+}
+
+// ropeJoin concatenates two ropes of arbitrary heights.
+// Small adjacent leaves are merged to keep the tree from fragmenting.
+func ropeJoin(l, r *ropeNode) *ropeNode {
+	if l == nil {
+		return r
+	}
+	if r == nil {
+		return l
+	}
+
+	if l.isLeaf() && r.isLeaf() && l.length+r.length <= ropeLeafMax {
+		return newRopeLeaf(append(l.leaf, r.leaf...))
+	}
+
+	if l.height > r.height+1 {
+		l.right = ropeJoin(l.right, r)
+		return ropeRebalance(l)
+	}
+	if r.height > l.height+1 {
+		r.left = ropeJoin(l, r.left)
+		return ropeRebalance(r)
+	}
+	return newRopeNode(l, r)
+}
+
+// ropeRelink reattaches (possibly changed) children to n, falling back to a
+// full join when they are out of balance or can be merged into one leaf.
+func ropeRelink(n, left, right *ropeNode) *ropeNode {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+
+	diff := left.height - right.height
+	if diff > 1 || diff < -1 ||
+		(left.isLeaf() && right.isLeaf() && left.length+right.length <= ropeLeafMax) {
+		return ropeJoin(left, right)
+	}
+
+	n.left = left
+	n.right = right
+	n.update()
+	return n
+}
+
+// ropeInsert inserts runes at pos and returns the new root of the subtree.
+func ropeInsert(n *ropeNode, pos int, runes []rune) *ropeNode {
+	if n == nil {
+		return buildRope(runes)
+	}
+
+	if n.isLeaf() {
+		if n.length+len(runes) <= ropeLeafMax {
+			// Fast path: insert in place within the leaf
+			oldLen := len(n.leaf)
+			n.leaf = append(n.leaf, runes...)
+			copy(n.leaf[pos+len(runes):], n.leaf[pos:oldLen])
+			copy(n.leaf[pos:], runes)
+			n.length = len(n.leaf)
+			n.newlines += countNewlines(runes)
+			return n
+		}
+
+		combined := make([]rune, 0, n.length+len(runes))
+		combined = append(combined, n.leaf[:pos]...)
+		combined = append(combined, runes...)
+		combined = append(combined, n.leaf[pos:]...)
+		return buildRope(combined)
+	}
+
+	left, right := n.left, n.right
+	if pos <= left.length {
+		left = ropeInsert(left, pos, runes)
+	} else {
+		right = ropeInsert(right, pos-left.length, runes)
+	}
+	return ropeRelink(n, left, right)
+}
+
+// ropeDelete removes runes in [start, end) and returns the new subtree root.
+func ropeDelete(n *ropeNode, start, end int) *ropeNode {
+	if n == nil || start >= end {
+		return n
+	}
+	if start <= 0 && end >= n.length {
+		return nil
+	}
+
+	if n.isLeaf() {
+		n.newlines -= countNewlines(n.leaf[start:end])
+		n.leaf = append(n.leaf[:start], n.leaf[end:]...)
+		n.length = len(n.leaf)
+		return n
+	}
+
+	left, right := n.left, n.right
+	leftLen := left.length
+	if start < leftLen {
+		left = ropeDelete(left, start, min(end, leftLen))
+	}
+	if end > leftLen {
+		right = ropeDelete(right, max(start-leftLen, 0), end-leftLen)
+	}
+	return ropeRelink(n, left, right)
+}
+
+// ropeRuneAt returns the rune at pos, which must be within bounds.
+func ropeRuneAt(n *ropeNode, pos int) rune {
+	for !n.isLeaf() {
+		if pos < n.left.length {
+			n = n.left
+		} else {
+			pos -= n.left.length
+			n = n.right
+		}
+	}
+	return n.leaf[pos]
+}
+
+// ropeAppendRange appends the runes in [start, end) to dst.
+func ropeAppendRange(dst []rune, n *ropeNode, start, end int) []rune {
+	if n == nil || start >= end {
+		return dst
+	}
+	if n.isLeaf() {
+		return append(dst, n.leaf[start:end]...)
+	}
+
+	leftLen := n.left.length
+	if start < leftLen {
+		dst = ropeAppendRange(dst, n.left, start, min(end, leftLen))
+	}
+	if end > leftLen {
+		dst = ropeAppendRange(dst, n.right, max(start-leftLen, 0), end-leftLen)
+	}
+	return dst
+}
+
+// ropeNewlineOffset returns the offset of the k-th newline (1-based).
+// The caller must ensure 1 <= k <= ropeNewlines(n).
+func ropeNewlineOffset(n *ropeNode, k int) int {
+	offset := 0
+	for !n.isLeaf() {
+		if k <= n.left.newlines {
+			n = n.left
+		} else {
+			k -= n.left.newlines
+			offset += n.left.length
+			n = n.right
+		}
+	}
+
+	for i, r := range n.leaf {
+		if r == '\n' {
+			k--
+			if k == 0 {
+				return offset + i
+			}
+		}
+	}
+	return offset + n.length
+}
+
+// ropeNewlinesBefore counts the newlines in [0, pos).
+func ropeNewlinesBefore(n *ropeNode, pos int) int {
+	if n == nil {
+		return 0
+	}
+
+	count := 0
+	for !n.isLeaf() {
+		if pos <= n.left.length {
+			n = n.left
+		} else {
+			pos -= n.left.length
+			count += n.left.newlines
+			n = n.right
+		}
+	}
+
+	if pos > n.length {
+		pos = n.length
+	}
+	return count + countNewlines(n.leaf[:pos])
+}
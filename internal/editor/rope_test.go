@@ -0,0 +1,119 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchSizes are the buffer sizes the benchmarks run with. The cost per
+// operation should stay about the same across them.
+var benchSizes = []int{1 << 20, 10 << 20, 50 << 20}
+
+// benchTexts caches the generated texts by size.
+var benchTexts = make(map[int]string)
+
+// benchText returns about size bytes of source-like text.
+func benchText(size int) string {
+	if text, ok := benchTexts[size]; ok {
+		return text
+	}
+	var sb strings.Builder
+	sb.Grow(size + 100)
+	for i := 0; sb.Len() < size; i++ {
+		fmt.Fprintf(&sb, "\tvalue%d := compute(%d, \"some text\") // line %d\n", i, i*7, i)
+	}
+	benchTexts[size] = sb.String()
+	return benchTexts[size]
+}
+
+// benchBuffers runs fn as a sub-benchmark for a buffer of every size.
+func benchBuffers(b *testing.B, fn func(b *testing.B, buf *Buffer)) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("%dMB", size>>20), func(b *testing.B) {
+			buf := NewBuffer()
+			buf.SetContent(benchText(size))
+			b.ResetTimer()
+			fn(b, buf)
+		})
+	}
+}
+
+func BenchmarkBufferInsert(b *testing.B) {
+	benchBuffers(b, func(b *testing.B, buf *Buffer) {
+		n := buf.Length()
+		for i := 0; i < b.N; i++ {
+			buf.Insert((i*7919)%n, "x")
+		}
+	})
+}
+
+func BenchmarkBufferDelete(b *testing.B) {
+	benchBuffers(b, func(b *testing.B, buf *Buffer) {
+		n := buf.Length() / 2
+		for i := 0; i < b.N; i++ {
+			buf.Delete((i*7919)%n, 1)
+		}
+	})
+}
+
+func BenchmarkBufferLineCount(b *testing.B) {
+	benchBuffers(b, func(b *testing.B, buf *Buffer) {
+		for i := 0; i < b.N; i++ {
+			buf.LineCount()
+		}
+	})
+}
+
+func BenchmarkBufferOffsetToPosition(b *testing.B) {
+	benchBuffers(b, func(b *testing.B, buf *Buffer) {
+		n := buf.Length()
+		for i := 0; i < b.N; i++ {
+			buf.OffsetToPosition((i * 7919) % n)
+		}
+	})
+}
+
+func BenchmarkBufferPositionToOffset(b *testing.B) {
+	benchBuffers(b, func(b *testing.B, buf *Buffer) {
+		lines := buf.LineCount()
+		for i := 0; i < b.N; i++ {
+			buf.PositionToOffset((i*7919)%lines, 5)
+		}
+	})
+}
+
+// BenchmarkEditorKeystroke types a character in the middle of the file and
+// renders the view, as a keystroke does.
+func BenchmarkEditorKeystroke(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run(fmt.Sprintf("%dMB", size>>20), func(b *testing.B) {
+			e := NewEditor()
+			e.SetSize(120, 40)
+			e.buffer().SetContent(benchText(size))
+			e.highlighter().SetLanguage("Go")
+			e.GoToLine(e.LineCount() / 2)
+			e.View()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				e.InsertRune('x')
+				e.View()
+			}
+		})
+	}
+}
+
+// BenchmarkWordsKeystroke types a character in the middle of the file and
+// updates its completion words, as a keystroke with the popup open does.
+func BenchmarkWordsKeystroke(b *testing.B) {
+	benchBuffers(b, func(b *testing.B, buf *Buffer) {
+		p := newWordProvider(NewTabManager())
+		p.words(buf)
+		offset := buf.PositionToOffset(buf.LineCount()/2, 5)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			buf.Insert(offset+i, "x")
+			p.words(buf)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package editor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DDZ-DO/vex/internal/format"
+)
+
+// formatTimeout bounds how long a formatter may run after a save.
+const formatTimeout = 5 * time.Second
+
+// TransformError is returned by ApplyFormat when a formatter failed, such
+// as on a syntax error. The file stays saved as it was.
+type TransformError struct {
+	Name string // Name of the formatter
+	Err  error
+}
+
+func (e *TransformError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e *TransformError) Unwrap() error {
+	return e.Err
+}
+
+// saveTransform is a step of the save pipeline: it returns the text a file
+// should be saved with.
+type saveTransform struct {
+	name  string
+	apply func(text string) string
+}
+
+// FormatJob is a formatter run on the text of a saved tab. Saving queues
+// one for every file with a formatter; the app takes them with FormatJobs,
+// runs them off the UI goroutine and passes the results to ApplyFormat.
+type FormatJob struct {
+	tab       *TabState
+	path      string // Absolute path the tab was saved to
+	version   int    // Buffer version of the saved text
+	text      string
+	formatter format.Formatter
+}
+
+// FormatResult is the output of a FormatJob.
+type FormatResult struct {
+	job  *FormatJob
+	text string
+	err  error
+}
+
+// Run runs the formatter on the saved text. It only reads the job, so it
+// may run on any goroutine.
+func (j *FormatJob) Run() FormatResult {
+	ctx, cancel := context.WithTimeout(context.Background(), formatTimeout)
+	defer cancel()
+	text, err := j.formatter.Run(ctx, j.path, j.text)
+	return FormatResult{job: j, text: text, err: err}
+}
+
+// SetSaveOptions sets whether saving removes whitespace at the end of lines
+// and ends files with a newline. A file's .editorconfig overrides both.
+func (e *Editor) SetSaveOptions(trimTrailingWhitespace, insertFinalNewline bool) {
+	e.trimTrailingWhitespace = trimTrailingWhitespace
+	e.insertFinalNewline = insertFinalNewline
+}
+
+// SetFormatters sets the formatters run on save, by language ID. nil turns
+// formatting on save off.
+func (e *Editor) SetFormatters(formatters map[string]format.Formatter) {
+	e.formatters = formatters
+}
+
+// saveTransforms returns the steps of the save pipeline for a tab, in
+// order: trimming whitespace and the final newline. The formatter runs
+// after the file is written, see FormatJob.
+func (e *Editor) saveTransforms(tab *TabState) []saveTransform {
+	ec := tab.editorConfig
+	var transforms []saveTransform
+
+	trim := e.trimTrailingWhitespace
+	if ec.TrimTrailingWhitespace != nil {
+		trim = *ec.TrimTrailingWhitespace
+	}
+	if trim {
+		transforms = append(transforms, saveTransform{"trim_trailing_whitespace", trimTrailingWhitespace})
+	}
+
+	// insert_final_newline = false in an .editorconfig removes the newline;
+	// in the config it only leaves the end of the file alone
+	switch {
+	case ec.InsertFinalNewline != nil && !*ec.InsertFinalNewline:
+		transforms = append(transforms, saveTransform{"insert_final_newline", removeFinalNewline})
+	case ec.InsertFinalNewline != nil || e.insertFinalNewline:
+		transforms = append(transforms, saveTransform{"insert_final_newline", insertFinalNewline})
+	}
+	return transforms
+}
+
+// trimTrailingWhitespace removes spaces and tabs at the end of lines.
+func trimTrailingWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// insertFinalNewline ends text that is not empty with a newline.
+func insertFinalNewline(text string) string {
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text
+}
+
+// removeFinalNewline removes the newlines at the end of text.
+func removeFinalNewline(text string) string {
+	return strings.TrimRight(text, "\n")
+}
+
+// runSavePipeline applies the save pipeline to a tab, each step as one undo
+// step.
+func (e *Editor) runSavePipeline(tab *TabState) {
+	for _, t := range e.saveTransforms(tab) {
+		text := tab.buffer.Content()
+		if result := t.apply(text); result != text {
+			tab.applyText(result)
+			e.highlightDirty = true
+		}
+	}
+	if tab == e.activeTab() {
+		e.updateGutterWidth()
+		e.ensureCursorVisible()
+	}
+}
+
+// saveTab runs the save pipeline on a tab, writes it to path and queues
+// the formatter of the file. With check, a file another program changed is
+// left alone and ErrChangedOnDisk returned.
+func (e *Editor) saveTab(tab *TabState, path string, check bool) error {
+	if path == "" {
+		return os.ErrInvalid
+	}
+	if check {
+		if change, _ := tab.buffer.CheckDisk(); change == DiskChanged {
+			return ErrChangedOnDisk
+		}
+	}
+
+	e.runSavePipeline(tab)
+	if err := tab.buffer.SaveAs(path); err != nil {
+		return err
+	}
+	tab.MarkSaved()
+	e.queueFormat(tab, path)
+	return nil
+}
+
+// queueFormat queues a formatter run on the text a tab was saved with,
+// replacing a run of the tab that was not taken yet.
+func (e *Editor) queueFormat(tab *TabState, path string) {
+	f, ok := format.ForFile(e.formatters, path)
+	if !ok {
+		return
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	job := &FormatJob{
+		tab:       tab,
+		path:      path,
+		version:   tab.buffer.Version(),
+		text:      tab.buffer.Content(),
+		formatter: f,
+	}
+	for i, queued := range e.formatJobs {
+		if queued.tab == tab {
+			e.formatJobs[i] = job
+			return
+		}
+	}
+	e.formatJobs = append(e.formatJobs, job)
+}
+
+// FormatJobs returns the formatter runs queued by saves since the last
+// call.
+func (e *Editor) FormatJobs() []*FormatJob {
+	jobs := e.formatJobs
+	e.formatJobs = nil
+	return jobs
+}
+
+// ApplyFormat applies the output of a formatter to its tab as one undo
+// step and saves the tab again. Output for a tab edited, saved elsewhere or
+// changed on disk since the save is dropped. A formatter that failed is
+// returned as a *TransformError; a missing default formatter is ignored.
+func (e *Editor) ApplyFormat(result FormatResult) error {
+	job := result.job
+	tab := job.tab
+	path, err := filepath.Abs(tab.Filepath())
+	if err != nil || path != job.path || tab.buffer.Version() != job.version {
+		return nil
+	}
+
+	if result.err != nil {
+		if errors.Is(result.err, exec.ErrNotFound) && !job.formatter.Configured {
+			return nil
+		}
+		return &TransformError{Name: job.formatter.Name(), Err: result.err}
+	}
+	if result.text == job.text {
+		return nil
+	}
+	if change, _ := tab.buffer.CheckDisk(); change != DiskUnchanged {
+		return nil
+	}
+
+	tab.applyText(result.text)
+	if tab == e.activeTab() {
+		e.highlightDirty = true
+		e.updateGutterWidth()
+		e.ensureCursorVisible()
+	}
+	if err := tab.buffer.SaveAs(tab.Filepath()); err != nil {
+		return err
+	}
+	tab.MarkSaved()
+	return nil
+}
+
+// FormatNow runs the queued formatter runs and applies their output. It
+// blocks, so it is meant for saving right before quitting.
+func (e *Editor) FormatNow() error {
+	var firstErr error
+	for _, job := range e.FormatJobs() {
+		if err := e.ApplyFormat(job.Run()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SaveAll saves all modified tabs with a file. Tabs whose file changed on
+// disk are skipped; ErrChangedOnDisk is returned for them once the others
+// are saved.
+func (e *Editor) SaveAll() error {
+	var conflict error
+	for _, tab := range e.tabManager.Tabs() {
+		if !tab.Modified() || tab.Filepath() == "" {
+			continue
+		}
+		err := e.saveTab(tab, tab.Filepath(), true)
+		if errors.Is(err, ErrChangedOnDisk) {
+			conflict = fmt.Errorf("%s: %w", tab.Name(), err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return conflict
+}
@@ -0,0 +1,119 @@
+package editor
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SearchOptions controls how search text is matched.
+type SearchOptions struct {
+	CaseSensitive bool
+	WholeWord     bool
+	Regex         bool
+}
+
+// Search is a compiled search query. Plain text and regular expressions are
+// both matched with Go's RE2 engine; plain text is quoted first.
+type Search struct {
+	re        *regexp.Regexp
+	regex     bool
+	wholeWord bool
+
+	// lineLocal is set when every match lies within one line and does not
+	// depend on text outside it, so a single line can be searched on its own
+	lineLocal bool
+}
+
+// Match is a search match in rune offsets.
+type Match struct {
+	Start int
+	End   int
+
+	loc []int // Byte offsets of the match and its groups in the searched text
+}
+
+// NewSearch compiles a search query. An invalid regular expression returns
+// the parse error.
+func NewSearch(text string, opts SearchOptions) (*Search, error) {
+	pattern := text
+	if !opts.Regex {
+		pattern = regexp.QuoteMeta(text)
+	}
+
+	// Validate the pattern as typed so errors don't mention the (?i) flag
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.CaseSensitive {
+		re = regexp.MustCompile("(?i)" + pattern)
+	}
+
+	return &Search{
+		re:        re,
+		regex:     opts.Regex,
+		wholeWord: opts.WholeWord,
+		// Regexes may use anchors or span lines, so only plain text qualifies
+		lineLocal: !opts.Regex && !strings.Contains(text, "\n"),
+	}, nil
+}
+
+// Matches returns all non-empty matches in text, with rune offsets.
+func (s *Search) Matches(text string) []Match {
+	locs := s.re.FindAllStringSubmatchIndex(text, -1)
+
+	var matches []Match
+	bytePos, runePos := 0, 0
+	for _, loc := range locs {
+		if loc[0] == loc[1] {
+			continue
+		}
+		if s.wholeWord && !isWordBoundary(text, loc[0], loc[1]) {
+			continue
+		}
+
+		// Convert byte offsets to rune offsets incrementally
+		runePos += utf8.RuneCountInString(text[bytePos:loc[0]])
+		start := runePos
+		runePos += utf8.RuneCountInString(text[loc[0]:loc[1]])
+		bytePos = loc[1]
+
+		matches = append(matches, Match{Start: start, End: runePos, loc: loc})
+	}
+	return matches
+}
+
+// Expand returns the replacement for a match found in text. In regex mode,
+// $1, ${1} and ${name} in template refer to capture groups.
+func (s *Search) Expand(m Match, text, template string) string {
+	if !s.regex || m.loc == nil {
+		return template
+	}
+	return string(s.re.ExpandString(nil, template, text, m.loc))
+}
+
+// isWordBoundary checks that text[start:end] is not preceded or followed by
+// a word character.
+func isWordBoundary(text string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(text[:start])
+		if isSearchWordChar(r) {
+			return false
+		}
+	}
+	if end < len(text) {
+		r, _ := utf8.DecodeRuneInString(text[end:])
+		if isSearchWordChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSearchWordChar reports whether r is part of a word for whole-word search.
+// Unlike isWordChar it includes non-ASCII letters such as umlauts.
+func isSearchWordChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,97 @@
+package editor
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DDZ-DO/vex/internal/syntax"
+	"github.com/DDZ-DO/vex/internal/tags"
+)
+
+// Symbols returns the symbols defined in the active file, ordered by line.
+// They come from the tags file if it lists the file, or else from the
+// tokens of the highlighter. tagsFile may be nil.
+func (e *Editor) Symbols(tagsFile *tags.File) []syntax.Symbol {
+	buf := e.buffer()
+	if tagsFile != nil && e.Filepath() != "" {
+		if path, err := filepath.Abs(e.Filepath()); err == nil {
+			if fileTags := tagsFile.InFile(path); len(fileTags) > 0 {
+				return tagSymbols(fileTags, buf)
+			}
+		}
+	}
+	return e.highlighter().Symbols(buf.Content())
+}
+
+// tagSymbols locates the tags of a file in its buffer. Tags whose pattern
+// no longer matches are left out.
+func tagSymbols(fileTags []tags.Tag, buf *Buffer) []syntax.Symbol {
+	lines := strings.Split(buf.Content(), "\n")
+	symbols := make([]syntax.Symbol, 0, len(fileTags))
+	for _, t := range fileTags {
+		line := t.FindLine(lines)
+		if line < 0 {
+			continue
+		}
+		symbols = append(symbols, syntax.Symbol{
+			Name:   t.Name,
+			Kind:   t.Kind,
+			Line:   line,
+			Column: nameColumn(lines[line], t.Name),
+		})
+	}
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return symbols[i].Line < symbols[j].Line
+	})
+	return symbols
+}
+
+// GoToSymbol selects the name of a symbol of the active file.
+func (e *Editor) GoToSymbol(s syntax.Symbol) {
+	e.GoToMatch(s.Line, s.Column, s.Column+utf8.RuneCountInString(s.Name))
+}
+
+// GoToTag opens the file of a tag and selects its name.
+func (e *Editor) GoToTag(t tags.Tag) error {
+	e.beginJump()
+	defer e.endJump()
+	if err := e.LoadFile(t.Path); err != nil {
+		return err
+	}
+	buf := e.buffer()
+	line := t.FindLine(strings.Split(buf.Content(), "\n"))
+	if line < 0 {
+		line = 0
+	}
+	col := nameColumn(buf.Line(line), t.Name)
+	e.GoToMatch(line, col, col+utf8.RuneCountInString(t.Name))
+	return nil
+}
+
+// WordAtCursor returns the identifier under the cursor, or the one ending
+// right before it.
+func (e *Editor) WordAtCursor() string {
+	buf := e.buffer()
+	offset := e.cursor().Offset(buf)
+	if offset < buf.Length() && isWordChar(buf.RuneAt(offset)) {
+		word, _, _ := buf.WordAt(offset)
+		return word
+	}
+	if offset > 0 && isWordChar(buf.RuneAt(offset-1)) {
+		word, _, _ := buf.WordAt(offset - 1)
+		return word
+	}
+	return ""
+}
+
+// nameColumn returns the rune column of name in line, or 0 if it is not
+// found.
+func nameColumn(line, name string) int {
+	i := strings.Index(line, name)
+	if i < 0 {
+		return 0
+	}
+	return utf8.RuneCountInString(line[:i])
+}
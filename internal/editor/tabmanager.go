@@ -1,11 +1,20 @@
 package editor
 
-import "path/filepath"
+import (
+	"path/filepath"
+)
 
 // TabManager manages multiple open tabs.
 type TabManager struct {
 	tabs      []*TabState
 	activeIdx int
+	recent    []*TabState // Open tabs, most recently active first
+
+	// Called with the active tab before another tab becomes active
+	leaveListener func(tab *TabState)
+
+	// Directory of the undo files kept across sessions, "" if disabled
+	undoDir string
 }
 
 // NewTabManager creates a new tab manager with one empty tab.
@@ -14,9 +23,44 @@ func NewTabManager() *TabManager {
 		tabs:      []*TabState{NewTabState()},
 		activeIdx: 0,
 	}
+	tm.touch()
 	return tm
 }
 
+// SetLeaveListener registers a function called with the active tab right
+// before another tab becomes active or the active tab is closed.
+func (tm *TabManager) SetLeaveListener(fn func(tab *TabState)) {
+	tm.leaveListener = fn
+}
+
+// SetUndoDir enables undo files in dir: the history of a file is loaded
+// when it is opened and saved when its tab is closed. An empty dir
+// disables them.
+func (tm *TabManager) SetUndoDir(dir string) {
+	tm.undoDir = dir
+}
+
+// SaveUndoFiles writes the undo files of all open tabs, if enabled.
+func (tm *TabManager) SaveUndoFiles() error {
+	if tm.undoDir == "" {
+		return nil
+	}
+	var firstErr error
+	for _, tab := range tm.tabs {
+		if err := tab.SaveUndo(tm.undoDir); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// leave reports that the active tab is about to be left.
+func (tm *TabManager) leave() {
+	if tm.leaveListener != nil && len(tm.tabs) > 0 {
+		tm.leaveListener(tm.tabs[tm.activeIdx])
+	}
+}
+
 // ActiveTab returns the currently active tab.
 func (tm *TabManager) ActiveTab() *TabState {
 	if len(tm.tabs) == 0 {
@@ -42,9 +86,11 @@ func (tm *TabManager) TabCount() int {
 
 // AddTab adds a new empty tab and makes it active.
 func (tm *TabManager) AddTab() *TabState {
+	tm.leave()
 	tab := NewTabState()
 	tm.tabs = append(tm.tabs, tab)
 	tm.activeIdx = len(tm.tabs) - 1
+	tm.touch()
 	return tab
 }
 
@@ -64,7 +110,11 @@ func (tm *TabManager) AddTabFromFile(path string) (*TabState, error) {
 			tabAbsPath = tabPath
 		}
 		if tabAbsPath == absPath {
+			if i != tm.activeIdx {
+				tm.leave()
+			}
 			tm.activeIdx = i
+			tm.touch()
 			return tab, nil
 		}
 	}
@@ -74,9 +124,15 @@ func (tm *TabManager) AddTabFromFile(path string) (*TabState, error) {
 	if err != nil {
 		return nil, err
 	}
+	if tm.undoDir != "" {
+		// The history is optional; the file opens without it
+		tab.LoadUndo(tm.undoDir)
+	}
+	tm.leave()
 
 	tm.tabs = append(tm.tabs, tab)
 	tm.activeIdx = len(tm.tabs) - 1
+	tm.touch()
 	return tab, nil
 }
 
@@ -86,14 +142,23 @@ func (tm *TabManager) CloseTab(idx int) bool {
 	if idx < 0 || idx >= len(tm.tabs) {
 		return false
 	}
+	if idx == tm.activeIdx {
+		tm.leave()
+	}
+	if tm.undoDir != "" {
+		tm.tabs[idx].SaveUndo(tm.undoDir)
+	}
 
 	// If only one tab, replace with empty tab
 	if len(tm.tabs) == 1 {
 		tm.tabs[0] = NewTabState()
+		tm.recent = nil
+		tm.touch()
 		return true
 	}
 
 	// Remove the tab
+	tm.forget(tm.tabs[idx])
 	tm.tabs = append(tm.tabs[:idx], tm.tabs[idx+1:]...)
 
 	// Adjust active index
@@ -102,6 +167,7 @@ func (tm *TabManager) CloseTab(idx int) bool {
 	} else if tm.activeIdx > idx {
 		tm.activeIdx--
 	}
+	tm.touch()
 
 	return true
 }
@@ -114,21 +180,51 @@ func (tm *TabManager) CloseActiveTab() bool {
 // SwitchTab switches to the tab at the given index.
 func (tm *TabManager) SwitchTab(idx int) {
 	if idx >= 0 && idx < len(tm.tabs) {
+		if idx != tm.activeIdx {
+			tm.leave()
+		}
 		tm.activeIdx = idx
+		tm.touch()
 	}
 }
 
 // NextTab switches to the next tab.
 func (tm *TabManager) NextTab() {
 	if len(tm.tabs) > 1 {
+		tm.leave()
 		tm.activeIdx = (tm.activeIdx + 1) % len(tm.tabs)
+		tm.touch()
 	}
 }
 
 // PrevTab switches to the previous tab.
 func (tm *TabManager) PrevTab() {
 	if len(tm.tabs) > 1 {
+		tm.leave()
 		tm.activeIdx = (tm.activeIdx - 1 + len(tm.tabs)) % len(tm.tabs)
+		tm.touch()
+	}
+}
+
+// RecentTabs returns the open tabs, most recently active first.
+func (tm *TabManager) RecentTabs() []*TabState {
+	return append([]*TabState(nil), tm.recent...)
+}
+
+// touch moves the active tab to the front of the recency list.
+func (tm *TabManager) touch() {
+	tab := tm.tabs[tm.activeIdx]
+	tm.forget(tab)
+	tm.recent = append([]*TabState{tab}, tm.recent...)
+}
+
+// forget removes a tab from the recency list.
+func (tm *TabManager) forget(tab *TabState) {
+	for i, t := range tm.recent {
+		if t == tab {
+			tm.recent = append(tm.recent[:i], tm.recent[i+1:]...)
+			return
+		}
 	}
 }
 
@@ -164,18 +260,6 @@ func (tm *TabManager) GetModifiedPaths() []string {
 	return paths
 }
 
-// SaveAll saves all modified tabs.
-func (tm *TabManager) SaveAll() error {
-	for _, tab := range tm.tabs {
-		if tab.Modified() && tab.Filepath() != "" {
-			if err := tab.Buffer().Save(); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
 // FindTabByPath returns the index of the tab with the given path, or -1 if not found.
 func (tm *TabManager) FindTabByPath(path string) int {
 	absPath, err := filepath.Abs(path)
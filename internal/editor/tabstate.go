@@ -2,7 +2,12 @@ package editor
 
 import (
 	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/DDZ-DO/vex/internal/editorconfig"
+	"github.com/DDZ-DO/vex/internal/linediff"
 	"github.com/DDZ-DO/vex/internal/syntax"
 )
 
@@ -14,9 +19,20 @@ type TabState struct {
 	history     *History
 	highlighter *syntax.Highlighter
 
+	// Indentation detected in the file when it was opened, nil if unknown
+	indent *Indent
+
+	// Settings of the .editorconfig files for the file, which override
+	// the configured ones
+	editorConfig editorconfig.Settings
+
+	// Secondary carets for multi-cursor editing
+	extraCarets []*Caret
+
 	// View state per tab
-	scrollX int
-	scrollY int
+	scrollX   int
+	scrollY   int
+	scrollRow int // First visible row of line scrollY when wrapping
 }
 
 // NewTabState creates a new empty tab.
@@ -32,23 +48,63 @@ func NewTabState() *TabState {
 
 // NewTabStateFromFile creates a tab with content loaded from a file.
 func NewTabStateFromFile(path string) (*TabState, error) {
-	buf, err := NewBufferFromFile(path)
+	// The charset is needed to read the file
+	settings, _ := editorconfig.Resolve(path)
+	encoding := encodingFromCharset(settings.Charset)
+	if encoding == "" {
+		encoding = EncodingUTF8
+	}
+	buf, err := newBufferFromFile(path, encoding)
 	if err != nil {
 		return nil, err
 	}
 
 	ts := &TabState{
-		buffer:      buf,
-		cursor:      NewCursor(),
-		selection:   NewSelection(),
-		history:     NewHistory(defaultHistoryMax),
-		highlighter: syntax.NewHighlighter(""),
+		buffer:       buf,
+		cursor:       NewCursor(),
+		selection:    NewSelection(),
+		history:      NewHistory(defaultHistoryMax),
+		highlighter:  syntax.NewHighlighter(""),
+		editorConfig: settings,
 	}
 	ts.highlighter.SetLanguageFromPath(path)
+	ts.applyEditorConfig()
+	if indent, ok := DetectIndent(buf.Content()); ok {
+		ts.indent = &indent
+	}
 
 	return ts, nil
 }
 
+// loadEditorConfig reads the .editorconfig settings for a new path of the
+// tab's file and applies them. Without readable settings, the ones applied
+// before stay in effect.
+func (ts *TabState) loadEditorConfig(path string) {
+	if settings, err := editorconfig.Resolve(path); err == nil {
+		ts.editorConfig = settings
+	}
+	ts.applyEditorConfig()
+}
+
+// applyEditorConfig applies the .editorconfig settings to how the buffer
+// is saved. Indentation, tab width, line length and the whitespace options
+// of the save pipeline are looked up when needed.
+func (ts *TabState) applyEditorConfig() {
+	ec := ts.editorConfig
+	buf := ts.buffer
+	if encoding := encodingFromCharset(ec.Charset); encoding != "" {
+		buf.SetEncoding(encoding)
+	}
+	switch ec.EndOfLine {
+	case "lf":
+		buf.SetLineEnding(LineEndingLF)
+	case "crlf":
+		buf.SetLineEnding(LineEndingCRLF)
+	case "cr":
+		buf.SetLineEnding(LineEndingCR)
+	}
+}
+
 // Buffer returns the buffer.
 func (ts *TabState) Buffer() *Buffer {
 	return ts.buffer
@@ -64,6 +120,201 @@ func (ts *TabState) Selection() *Selection {
 	return ts.selection
 }
 
+// Carets returns the primary and all secondary carets, ordered by position.
+// The primary caret shares the tab's Cursor and Selection.
+func (ts *TabState) Carets() []*Caret {
+	carets := make([]*Caret, 0, len(ts.extraCarets)+1)
+	carets = append(carets, ts.PrimaryCaret())
+	carets = append(carets, ts.extraCarets...)
+	sortCarets(carets)
+	return carets
+}
+
+// PrimaryCaret returns the caret made of the tab's Cursor and Selection.
+func (ts *TabState) PrimaryCaret() *Caret {
+	return &Caret{Cursor: ts.cursor, Selection: ts.selection}
+}
+
+// HasMultipleCarets returns true if the tab has secondary carets.
+func (ts *TabState) HasMultipleCarets() bool {
+	return len(ts.extraCarets) > 0
+}
+
+// AddCaret adds a secondary caret.
+func (ts *TabState) AddCaret(c *Caret) {
+	ts.extraCarets = append(ts.extraCarets, c)
+}
+
+// PushPrimaryCaret keeps a copy of the primary caret as a secondary caret,
+// so the primary can move to a newly added position.
+func (ts *TabState) PushPrimaryCaret() {
+	ts.extraCarets = append(ts.extraCarets, ts.PrimaryCaret().clone())
+}
+
+// ClearExtraCarets removes all secondary carets.
+func (ts *TabState) ClearExtraCarets() {
+	ts.extraCarets = nil
+}
+
+// MergeCarets removes carets that sit on the same position as, or overlap
+// with, another caret. The primary caret is always kept.
+func (ts *TabState) MergeCarets() {
+	if len(ts.extraCarets) == 0 {
+		return
+	}
+
+	var kept []*Caret
+	var prev *Caret
+	for _, c := range ts.Carets() {
+		if prev != nil {
+			_, prevEnd := prev.span()
+			start, _ := c.span()
+			overlaps := positionBefore(start, prevEnd) ||
+				(start == prevEnd && (!prev.hasSelection() || !c.hasSelection()))
+			if overlaps {
+				if c.Cursor == ts.cursor {
+					// Drop the secondary caret in favor of the primary
+					kept = kept[:len(kept)-1]
+				} else {
+					continue
+				}
+			}
+		}
+		kept = append(kept, c)
+		prev = c
+	}
+
+	ts.extraCarets = ts.extraCarets[:0]
+	for _, c := range kept {
+		if c.Cursor != ts.cursor {
+			ts.extraCarets = append(ts.extraCarets, c)
+		}
+	}
+}
+
+// LineEdit replaces the runes [Start, End) of a line (0-based) with Text.
+type LineEdit struct {
+	Line  int
+	Start int
+	End   int
+	Text  string
+}
+
+// ApplyLineEdits applies non-overlapping edits to the buffer as a single
+// undo step. The tab does not need to be active; its cursor keeps its place
+// in the surrounding text.
+func (ts *TabState) ApplyLineEdits(edits []LineEdit) {
+	if len(edits) == 0 {
+		return
+	}
+
+	// Apply from the end so earlier positions stay valid
+	sorted := append([]LineEdit(nil), edits...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Line != sorted[j].Line {
+			return sorted[i].Line > sorted[j].Line
+		}
+		return sorted[i].Start > sorted[j].Start
+	})
+
+	buf := ts.buffer
+	before := ts.cursor.Position()
+	cursorOffset := ts.cursor.Offset(buf)
+
+	ts.history.BeginCompound()
+	for _, edit := range sorted {
+		start := buf.PositionToOffset(edit.Line, edit.Start)
+		end := buf.PositionToOffset(edit.Line, edit.End)
+		old := buf.Delete(start, end-start)
+		buf.Insert(start, edit.Text)
+		ts.history.RecordReplace(start, old, edit.Text, before)
+
+		// Keep the cursor in place, or at the start of an edit it was in
+		delta := utf8.RuneCountInString(edit.Text) - (end - start)
+		if cursorOffset >= end {
+			cursorOffset += delta
+		} else if cursorOffset > start {
+			cursorOffset = start
+		}
+	}
+	ts.history.EndCompound([]Position{before})
+
+	ts.ClearExtraCarets()
+	ts.selection.Clear()
+	line, col := buf.OffsetToPosition(cursorOffset)
+	ts.cursor.MoveTo(line, col, buf)
+}
+
+// ReplaceContent replaces the whole text as a single undo step. The tab
+// does not need to be active; its cursor is kept within the new text.
+func (ts *TabState) ReplaceContent(content string) {
+	buf := ts.buffer
+	before := ts.cursor.Position()
+	old := buf.Delete(0, buf.Length())
+	buf.Insert(0, content)
+	ts.history.RecordReplace(0, old, content, before)
+
+	ts.ClearExtraCarets()
+	ts.selection.Clear()
+	ts.cursor.MoveTo(before.Line, before.Column, buf)
+}
+
+// applyText changes the text to text as a single undo step. Only the lines
+// that differ are replaced, so the cursor stays on the text it was on, and
+// within a changed line on its column. Like ApplyLineEdits, it leaves one
+// caret without a selection.
+func (ts *TabState) applyText(text string) {
+	buf := ts.buffer
+	before := ts.cursor.Position()
+	cursorOffset := ts.cursor.Offset(buf)
+	changes := linediff.Diff(strings.Split(buf.Content(), "\n"), strings.Split(text, "\n"))
+
+	replace := func(start, end int, text string) {
+		old := buf.Delete(start, end-start)
+		buf.Insert(start, text)
+		ts.history.RecordReplace(start, old, text, before)
+
+		n := utf8.RuneCountInString(text)
+		if cursorOffset >= end {
+			cursorOffset += n - (end - start)
+		} else if cursorOffset > start {
+			cursorOffset = min(cursorOffset, start+n)
+		}
+	}
+
+	// Changes are in order, with lines numbered as in the new text, so
+	// everything before a change already matches it
+	ts.history.BeginCompound()
+	for _, c := range changes {
+		lineStart := buf.PositionToOffset(c.Line, 0)
+		switch {
+		case c.Added && c.Line < buf.LineCount():
+			replace(lineStart, lineStart, c.New+"\n")
+		case c.Added:
+			replace(buf.Length(), buf.Length(), "\n"+c.New)
+		case c.Removed && c.Line < buf.LineCount()-1:
+			replace(lineStart, buf.PositionToOffset(c.Line+1, 0), "")
+		case c.Removed:
+			// The last line goes with the line break before it
+			replace(max(lineStart-1, 0), buf.Length(), "")
+		default:
+			replace(lineStart, lineStart+buf.LineLength(c.Line), c.New)
+		}
+	}
+	ts.history.EndCompound([]Position{before})
+
+	ts.ClearExtraCarets()
+	ts.selection.Clear()
+	line, col := buf.OffsetToPosition(cursorOffset)
+	ts.cursor.MoveTo(line, col, buf)
+}
+
+// isPristine returns true for an untitled tab that was never edited, like
+// the one the editor starts with.
+func (ts *TabState) isPristine() bool {
+	return ts.Filepath() == "" && ts.buffer.Length() == 0 && ts.history.UndoCount() == 0
+}
+
 // History returns the history.
 func (ts *TabState) History() *History {
 	return ts.history
@@ -74,7 +325,7 @@ func (ts *TabState) Highlighter() *syntax.Highlighter {
 	return ts.highlighter
 }
 
-// ScrollX returns horizontal scroll position.
+// ScrollX returns horizontal scroll position, in cells.
 func (ts *TabState) ScrollX() int {
 	return ts.scrollX
 }
@@ -84,7 +335,7 @@ func (ts *TabState) ScrollY() int {
 	return ts.scrollY
 }
 
-// SetScrollX sets horizontal scroll position.
+// SetScrollX sets horizontal scroll position, in cells.
 func (ts *TabState) SetScrollX(x int) {
 	ts.scrollX = x
 }
@@ -0,0 +1,112 @@
+package editor
+
+// TabView is the view state of a file tab: where its cursor, selection and
+// scroll position are.
+type TabView struct {
+	Path      string // Absolute file path
+	Cursor    Position
+	Selection *Selection // nil without a selection
+	ScrollX   int
+	ScrollY   int
+}
+
+// View returns the view state of the tab. ok is false for untitled tabs.
+func (ts *TabState) View() (view TabView, ok bool) {
+	path, ok := ts.absPath()
+	if !ok {
+		return TabView{}, false
+	}
+
+	view = TabView{
+		Path:    path,
+		Cursor:  ts.cursor.Position(),
+		ScrollX: ts.scrollX,
+		ScrollY: ts.scrollY,
+	}
+	if ts.selection.Active {
+		sel := *ts.selection
+		view.Selection = &sel
+	}
+	return view, true
+}
+
+// RestoreView applies a view state to the tab. Positions beyond the end of
+// the buffer are clamped, in case the file got shorter.
+func (ts *TabState) RestoreView(view TabView) {
+	buf := ts.buffer
+	ts.ClearExtraCarets()
+	ts.cursor.MoveTo(view.Cursor.Line, view.Cursor.Column, buf)
+	ts.selection.Clear()
+	if view.Selection != nil {
+		ts.selection.SetRange(clampPosition(view.Selection.Start, buf), clampPosition(view.Selection.End, buf))
+	}
+	ts.scrollX = max(view.ScrollX, 0)
+	ts.scrollY = min(max(view.ScrollY, 0), buf.LineCount()-1)
+}
+
+// clampPosition moves a position into the buffer.
+func clampPosition(pos Position, buf *Buffer) Position {
+	line := min(max(pos.Line, 0), buf.LineCount()-1)
+	line, col := buf.OffsetToPosition(buf.PositionToOffset(line, max(pos.Column, 0)))
+	return Position{Line: line, Column: col}
+}
+
+// TabViews returns the view states of the file tabs in tab bar order and
+// the index of the active one among them, or -1 if an untitled tab is
+// active.
+func (e *Editor) TabViews() (views []TabView, active int) {
+	active = -1
+	for i, tab := range e.tabManager.Tabs() {
+		view, ok := tab.View()
+		if !ok {
+			continue
+		}
+		if i == e.tabManager.ActiveIndex() {
+			active = len(views)
+		}
+		views = append(views, view)
+	}
+	return views, active
+}
+
+// RestoreTabs opens the files of saved view states and restores them, then
+// switches to the tab of views[active]. Files that can no longer be opened
+// are skipped. An untouched untitled tab the editor started with is closed.
+// Restoring does not count as navigation. Returns the number of tabs
+// restored.
+func (e *Editor) RestoreTabs(views []TabView, active int) int {
+	var initial *TabState
+	if tabs := e.tabManager.Tabs(); len(tabs) == 1 && tabs[0].isPristine() {
+		initial = tabs[0]
+	}
+
+	e.navigation.navigating = true
+	defer func() { e.navigation.navigating = false }()
+
+	restored := 0
+	activeIdx := -1
+	for i, view := range views {
+		tab, err := e.tabManager.AddTabFromFile(view.Path)
+		if err != nil {
+			continue
+		}
+		tab.RestoreView(view)
+		restored++
+		if i == active || activeIdx < 0 {
+			activeIdx = e.tabManager.ActiveIndex()
+		}
+	}
+	if restored == 0 {
+		return 0
+	}
+
+	if initial != nil && e.tabManager.Tabs()[0] == initial {
+		e.tabManager.CloseTab(0)
+		activeIdx--
+	}
+	e.tabManager.SwitchTab(activeIdx)
+	e.navigation = navigationHistory{}
+	e.highlightDirty = true
+	e.updateGutterWidth()
+	return restored
+}
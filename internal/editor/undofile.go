@@ -0,0 +1,109 @@
+package editor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/DDZ-DO/vex/internal/atomicfile"
+)
+
+// undoFile is the undo tree of a file saved across sessions. It is
+// positioned at the version of the file on disk: undo goes back from it,
+// redo forward to changes that were not saved.
+type undoFile struct {
+	Path   string       `json:"path"`
+	Hash   string       `json:"hash"`  // DiskState.Hash of the file
+	Saved  int          `json:"saved"` // Seq of the state of the file on disk
+	States []savedState `json:"states"`
+}
+
+// undoFilePath returns the undo file of the file at path in dir.
+func undoFilePath(dir, path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// SaveUndo writes the history of the tab to its undo file in dir. Nothing
+// is written for untitled tabs, unchanged histories and histories that can
+// no longer reach the version on disk; an older undo file of the latter is
+// removed.
+func (ts *TabState) SaveUndo(dir string) error {
+	path, ok := ts.absPath()
+	if !ok {
+		return nil
+	}
+	file := undoFilePath(dir, path)
+
+	states, saved, ok := ts.history.export()
+	disk := ts.buffer.Disk()
+	if !ok || disk.Hash == "" {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if len(states) == 1 {
+		return nil
+	}
+
+	data, err := json.Marshal(undoFile{
+		Path:   path,
+		Hash:   disk.Hash,
+		Saved:  saved,
+		States: states,
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	return atomicfile.Write(file, data, 0600)
+}
+
+// LoadUndo restores the history of a freshly opened tab from its undo file
+// in dir. An undo file written for another version of the file is removed,
+// since its positions no longer fit the text.
+func (ts *TabState) LoadUndo(dir string) error {
+	path, ok := ts.absPath()
+	if !ok || ts.history.UndoCount() > 0 || ts.history.RedoCount() > 0 {
+		return nil
+	}
+	file := undoFilePath(dir, path)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var saved undoFile
+	if err := json.Unmarshal(data, &saved); err != nil ||
+		saved.Path != path || saved.Hash == "" || saved.Hash != ts.buffer.Disk().Hash ||
+		!ts.history.restore(saved.States, saved.Saved) {
+		// Stale or damaged
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// absPath returns the absolute path of the tab's file. ok is false for
+// untitled tabs.
+func (ts *TabState) absPath() (string, bool) {
+	if ts.Filepath() == "" {
+		return "", false
+	}
+	path, err := filepath.Abs(ts.Filepath())
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
@@ -0,0 +1,282 @@
+package editor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// UndoState describes a state of the undo tree.
+type UndoState struct {
+	Seq     int       // Creation order; 0 is the text the history started with
+	Parent  int       // Seq of the state it was made from, -1 for the first state
+	Time    time.Time // When its edit was made (zero for the first state)
+	Label   string    // Short description of its edit
+	Current bool
+	Saved   bool
+}
+
+// trim drops the oldest state on the current branch, and the branches
+// made from it, once the current state is more than maxSize edits away
+// from the root.
+func (h *History) trim() {
+	newRoot := h.current
+	for newRoot.depth > 1 {
+		newRoot = newRoot.parent
+	}
+	newRoot.parent = nil
+	newRoot.action = EditAction{}
+	h.root = newRoot
+
+	reachable := false
+	h.walk(func(s *undoState) {
+		s.depth--
+		if s == h.saved {
+			reachable = true
+		}
+	})
+	if !reachable {
+		h.saved = nil
+	}
+}
+
+// walk calls fn for every state, parents before their children.
+func (h *History) walk(fn func(s *undoState)) {
+	stack := []*undoState{h.root}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		fn(s)
+		stack = append(stack, s.children...)
+	}
+}
+
+// find returns the state with the given sequence number, or nil.
+func (h *History) find(seq int) *undoState {
+	var found *undoState
+	h.walk(func(s *undoState) {
+		if s.seq == seq {
+			found = s
+		}
+	})
+	return found
+}
+
+// States returns all states of the tree, oldest first.
+func (h *History) States() []UndoState {
+	var states []UndoState
+	h.walk(func(s *undoState) {
+		state := UndoState{
+			Seq:     s.seq,
+			Parent:  -1,
+			Time:    s.action.Timestamp,
+			Label:   actionLabel(&s.action),
+			Current: s == h.current,
+			Saved:   s == h.saved,
+		}
+		if s.parent != nil {
+			state.Parent = s.parent.seq
+		} else {
+			state.Label = "Original"
+		}
+		states = append(states, state)
+	})
+	sort.Slice(states, func(i, j int) bool { return states[i].Seq < states[j].Seq })
+	return states
+}
+
+// CurrentSeq returns the sequence number of the current state.
+func (h *History) CurrentSeq() int {
+	return h.current.seq
+}
+
+// adjacentSeq returns the sequence number of the state made right before
+// (delta < 0) or after (delta > 0) the current one, on any branch.
+func (h *History) adjacentSeq(delta int) (int, bool) {
+	best, ok := 0, false
+	h.walk(func(s *undoState) {
+		if delta < 0 && s.seq < h.current.seq && (!ok || s.seq > best) ||
+			delta > 0 && s.seq > h.current.seq && (!ok || s.seq < best) {
+			best, ok = s.seq, true
+		}
+	})
+	return best, ok
+}
+
+// pathTo returns how many undos lead from the current state to the state
+// both it and the state with the given sequence number were made from, and
+// how many redos lead from there to the latter. Call followPathTo before
+// the redos. ok is false if there is no such state.
+func (h *History) pathTo(seq int) (undos, redos int, ok bool) {
+	target := h.find(seq)
+	if target == nil {
+		return 0, 0, false
+	}
+
+	onCurrentPath := make(map[*undoState]bool)
+	for s := h.current; s != nil; s = s.parent {
+		onCurrentPath[s] = true
+	}
+	common := target
+	for !onCurrentPath[common] {
+		common = common.parent
+	}
+	return h.current.depth - common.depth, target.depth - common.depth, true
+}
+
+// followPathTo makes Redo move towards the state with the given sequence
+// number.
+func (h *History) followPathTo(seq int) {
+	for s := h.find(seq); s != nil && s.parent != nil; s = s.parent {
+		s.parent.next = s
+	}
+}
+
+// savedState is an undo tree state as stored in an undo file.
+type savedState struct {
+	Seq    int        `json:"seq"`
+	Parent int        `json:"parent"` // -1 for the root
+	Next   int        `json:"next"`   // Seq of the child Redo moves to, -1 for none
+	Action EditAction `json:"action"`
+}
+
+// export returns the states of the tree, parents before their children,
+// and the sequence number of the save point. ok is false if the save point
+// was trimmed away.
+func (h *History) export() (states []savedState, saved int, ok bool) {
+	if h.saved == nil {
+		return nil, 0, false
+	}
+	h.walk(func(s *undoState) {
+		state := savedState{Seq: s.seq, Parent: -1, Next: -1, Action: s.action}
+		if s.parent != nil {
+			state.Parent = s.parent.seq
+		}
+		if s.next != nil {
+			state.Next = s.next.seq
+		}
+		states = append(states, state)
+	})
+	return states, h.saved.seq, true
+}
+
+// restore replaces the history with exported states, positioned at the
+// save point. It returns false, leaving the history unchanged, if the
+// states do not form a tree containing the save point.
+func (h *History) restore(states []savedState, saved int) bool {
+	bySeq := make(map[int]*undoState, len(states))
+	var root *undoState
+	lastSeq := 0
+	for _, s := range states {
+		if bySeq[s.Seq] != nil {
+			return false
+		}
+		state := &undoState{action: s.Action, seq: s.Seq, sealed: true}
+		if s.Parent < 0 {
+			if root != nil {
+				return false
+			}
+			root = state
+		} else {
+			parent := bySeq[s.Parent]
+			if parent == nil {
+				return false
+			}
+			state.parent = parent
+			state.depth = parent.depth + 1
+			parent.children = append(parent.children, state)
+		}
+		bySeq[s.Seq] = state
+		lastSeq = max(lastSeq, s.Seq)
+	}
+	if root == nil || bySeq[saved] == nil {
+		return false
+	}
+	for _, s := range states {
+		if s.Next >= 0 {
+			next := bySeq[s.Next]
+			if next == nil || next.parent != bySeq[s.Seq] {
+				return false
+			}
+			next.parent.next = next
+		}
+	}
+
+	h.root = root
+	h.current = bySeq[saved]
+	h.saved = h.current
+	h.lastSeq = lastSeq
+	for h.current.depth > h.maxSize {
+		h.trim()
+	}
+	return true
+}
+
+// actionLabel describes an action in a few words, such as +"foo".
+func actionLabel(a *EditAction) string {
+	switch a.Type {
+	case ActionInsert:
+		return "+" + quoteSnippet(a.Text)
+	case ActionDelete:
+		return "-" + quoteSnippet(a.OldText)
+	case ActionReplace:
+		return quoteSnippet(a.OldText) + " → " + quoteSnippet(a.Text)
+	case ActionGroup:
+		if len(a.Children) == 1 {
+			return actionLabel(&a.Children[0])
+		}
+		return fmt.Sprintf("%d Änderungen", len(a.Children))
+	}
+	return ""
+}
+
+// quoteSnippet quotes the start of a text on one line.
+func quoteSnippet(text string) string {
+	const maxLen = 16
+	text = strings.ReplaceAll(text, "\n", "⏎")
+	text = strings.ReplaceAll(text, "\t", " ")
+	if utf8.RuneCountInString(text) > maxLen {
+		text = string([]rune(text)[:maxLen-1]) + "…"
+	}
+	return `"` + text + `"`
+}
+
+// UndoStates returns the states of the active tab's undo tree, oldest
+// first.
+func (e *Editor) UndoStates() []UndoState {
+	return e.history().States()
+}
+
+// GoToUndoState undoes and redoes edits until the text is in the state of
+// the undo tree with the given sequence number. Returns false if there is
+// no such state.
+func (e *Editor) GoToUndoState(seq int) bool {
+	undos, redos, ok := e.history().pathTo(seq)
+	if !ok {
+		return false
+	}
+	for i := 0; i < undos; i++ {
+		e.Undo()
+	}
+	e.history().followPathTo(seq)
+	for i := 0; i < redos; i++ {
+		e.Redo()
+	}
+	return true
+}
+
+// UndoOlder moves to the state made right before the current one, on
+// whichever branch it is. Returns false at the oldest state.
+func (e *Editor) UndoOlder() bool {
+	seq, ok := e.history().adjacentSeq(-1)
+	return ok && e.GoToUndoState(seq)
+}
+
+// UndoNewer moves to the state made right after the current one, on
+// whichever branch it is. Returns false at the newest state.
+func (e *Editor) UndoNewer() bool {
+	seq, ok := e.history().adjacentSeq(1)
+	return ok && e.GoToUndoState(seq)
+}
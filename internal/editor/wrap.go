@@ -0,0 +1,293 @@
+package editor
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// wrapRow is a visual row of a line wrapped to the editor width: the
+// columns [start, end) of the line, whose first cell x is drawn after
+// indent cells.
+type wrapRow struct {
+	start, end int
+	x          int
+	indent     int
+}
+
+// textWidth returns the number of cells available for text, keeping one
+// for a caret at the end of a line.
+func (e *Editor) textWidth() int {
+	return max(e.width-e.gutterWidth-1, 1)
+}
+
+// wrapLine splits a line into rows of at most width cells. Rows break
+// after the last space that fits, or inside a word longer than a row.
+// Continuation rows are indented like the line, unless its indentation
+// takes more than half a row.
+func (e *Editor) wrapLine(lineNum, width int) []wrapRow {
+	runes := []rune(e.buffer().Line(lineNum))
+	if !e.wordWrap {
+		return []wrapRow{{start: 0, end: len(runes)}}
+	}
+	glyphs := e.layout(lineNum)
+	blank := func(i int) bool {
+		r := runes[glyphs[i].start]
+		return r == ' ' || r == '\t'
+	}
+
+	// Indentation of the line, in glyphs and cells
+	indentLen := 0
+	for indentLen < len(glyphs) && blank(indentLen) {
+		indentLen++
+	}
+	indent := lineWidth(glyphs[:indentLen])
+	if indent > width/2 {
+		indent = 0
+	}
+
+	var rows []wrapRow
+	row, first := wrapRow{}, 0
+	for {
+		avail := width - row.indent
+		end := first
+		for end < len(glyphs) && glyphs[end].x+glyphs[end].width-row.x <= avail {
+			end++
+		}
+
+		// Break after the last space of the row, but not inside the
+		// indentation of the first row
+		brk := end
+		for brk > max(first, indentLen) && !blank(brk-1) {
+			brk--
+		}
+		if brk <= max(first, indentLen) {
+			brk = max(end, first+1)
+		}
+		if end == len(glyphs) || brk >= len(glyphs) {
+			row.end = len(runes)
+			return append(rows, row)
+		}
+		row.end = glyphs[brk].start
+		rows = append(rows, row)
+		row = wrapRow{start: glyphs[brk].start, x: glyphs[brk].x, indent: indent}
+		first = brk
+	}
+}
+
+// rowOf returns the index of the row of a wrapped line that shows column
+// col. A column at a row boundary belongs to the later row.
+func rowOf(rows []wrapRow, col int) int {
+	for i := len(rows) - 1; i > 0; i-- {
+		if col >= rows[i].start {
+			return i
+		}
+	}
+	return 0
+}
+
+// rowX returns the cell at which column col is drawn within its row.
+func (e *Editor) rowX(lineNum int, row wrapRow, col int) int {
+	return row.indent + cellOf(e.layout(lineNum), col) - row.x
+}
+
+// rowColumn returns the column drawn at cell x of a row. Beyond the text of
+// the row it returns its last column; only the last row of a line reaches
+// the end of the line.
+func (e *Editor) rowColumn(lineNum int, row wrapRow, last bool, x int) int {
+	cell := x - row.indent + row.x
+	lastStart := row.start
+	for _, g := range e.layout(lineNum) {
+		if g.start < row.start || g.start >= row.end {
+			continue
+		}
+		if cell < g.x+g.width {
+			return g.start
+		}
+		lastStart = g.start
+	}
+	if !last && row.end > row.start {
+		return lastStart
+	}
+	return row.end
+}
+
+// visualPos is a visual row on screen: row of line.
+type visualPos struct {
+	line, row int
+}
+
+// stepRows moves a visual position by delta rows, stopping at the first
+// and last row of the buffer. It returns the new position and how many
+// rows it moved.
+func (e *Editor) stepRows(pos visualPos, delta int) (visualPos, int) {
+	width := e.textWidth()
+	buf := e.buffer()
+	moved := 0
+	for ; delta > 0; delta-- {
+		if pos.row+1 < len(e.wrapLine(pos.line, width)) {
+			pos.row++
+		} else if pos.line+1 < buf.LineCount() {
+			pos = visualPos{pos.line + 1, 0}
+		} else {
+			break
+		}
+		moved++
+	}
+	for ; delta < 0; delta++ {
+		if pos.row > 0 {
+			pos.row--
+		} else if pos.line > 0 {
+			pos = visualPos{pos.line - 1, len(e.wrapLine(pos.line-1, width)) - 1}
+		} else {
+			break
+		}
+		moved++
+	}
+	return pos, moved
+}
+
+// scrollTop returns the first visual row on screen.
+func (e *Editor) scrollTop() visualPos {
+	tab := e.activeTab()
+	line := min(tab.scrollY, max(e.buffer().LineCount()-1, 0))
+	if !e.wordWrap {
+		return visualPos{line: line}
+	}
+	rows := e.wrapLine(line, e.textWidth())
+	return visualPos{line, min(tab.scrollRow, len(rows)-1)}
+}
+
+// setScrollTop sets the first visual row on screen.
+func (e *Editor) setScrollTop(pos visualPos) {
+	tab := e.activeTab()
+	tab.scrollY = pos.line
+	tab.scrollRow = pos.row
+}
+
+// ensureCaretRowVisible scrolls a wrapped view so the primary caret's row
+// is on screen.
+func (e *Editor) ensureCaretRowVisible() {
+	cursor := e.cursor()
+	rows := e.wrapLine(cursor.Line, e.textWidth())
+	caret := visualPos{cursor.Line, rowOf(rows, cursor.Column)}
+
+	top := e.scrollTop()
+	if caret.line < top.line || caret.line == top.line && caret.row < top.row {
+		e.setScrollTop(caret)
+		return
+	}
+
+	// Count the rows from the top to the caret, up to a screen
+	pos := top
+	for n := 0; n < e.height; n++ {
+		if pos == caret {
+			return
+		}
+		next, moved := e.stepRows(pos, 1)
+		if moved == 0 {
+			break
+		}
+		pos = next
+	}
+	newTop, _ := e.stepRows(caret, -(e.height - 1))
+	e.setScrollTop(newTop)
+}
+
+// scrollRows scrolls a wrapped view by delta rows, keeping the last row of
+// the buffer at the bottom of the screen at most.
+func (e *Editor) scrollRows(delta int) {
+	top, _ := e.stepRows(e.scrollTop(), delta)
+
+	last := visualPos{line: e.buffer().LineCount() - 1}
+	last.row = len(e.wrapLine(last.line, e.textWidth())) - 1
+	maxTop, _ := e.stepRows(last, -(e.height - 1))
+	if top.line > maxTop.line || top.line == maxTop.line && top.row > maxTop.row {
+		top = maxTop
+	}
+	e.setScrollTop(top)
+}
+
+// moveCaretRow moves a caret up (delta -1) or down (delta 1) by one
+// visual row, keeping the cell it was moved to from the last horizontal
+// move.
+func (e *Editor) moveCaretRow(c *Cursor, delta int) {
+	width := e.textWidth()
+	rows := e.wrapLine(c.Line, width)
+	from := visualPos{c.Line, rowOf(rows, c.Column)}
+	if !c.hasRowX || c.rowXAt != c.Position() {
+		c.rowX = e.rowX(c.Line, rows[from.row], c.Column)
+	}
+
+	to, moved := e.stepRows(from, delta)
+	if moved == 0 {
+		return
+	}
+	toRows := e.wrapLine(to.line, width)
+	c.Line = to.line
+	c.Column = e.rowColumn(to.line, toRows[to.row], to.row == len(toRows)-1, c.rowX)
+	c.hasRowX, c.rowXAt = true, c.Position()
+}
+
+// screenPosition returns where a buffer position is drawn, relative to the
+// editor's top left corner. ok is false if it is scrolled out of view.
+func (e *Editor) screenPosition(pos Position) (x, y int, ok bool) {
+	if !e.wordWrap {
+		x = e.gutterWidth + max(displayColumn(e.buffer().Line(pos.Line), pos.Column, e.TabWidth())-e.scrollX(), 0)
+		y = pos.Line - e.scrollY()
+		return x, y, y >= 0 && y < e.height && x < e.width
+	}
+
+	rows := e.wrapLine(pos.Line, e.textWidth())
+	row := rowOf(rows, pos.Column)
+	top := e.scrollTop()
+	switch {
+	case pos.Line < top.line || pos.Line == top.line && row < top.row:
+		return 0, 0, false
+	case pos.Line == top.line:
+		y = row - top.row
+	default:
+		y = len(e.wrapLine(top.line, e.textWidth())) - top.row
+		for line := top.line + 1; line < pos.Line && y < e.height; line++ {
+			y += len(e.wrapLine(line, e.textWidth()))
+		}
+		y += row
+	}
+	x = e.gutterWidth + e.rowX(pos.Line, rows[row], pos.Column)
+	return x, y, y < e.height
+}
+
+// wrappedView renders the editor with long lines wrapped. The line number
+// is shown on the first row of each line.
+func (e *Editor) wrappedView(diags []Diagnostic) string {
+	width := e.textWidth()
+	pos := e.scrollTop()
+	lines := make([]string, 0, e.height)
+	for pos.line < e.buffer().LineCount() && len(lines) < e.height {
+		rows := e.wrapLine(pos.line, width)
+		for ; pos.row < len(rows) && len(lines) < e.height; pos.row++ {
+			row := rows[pos.row]
+			var lineContent string
+			if e.showLineNum {
+				if pos.row == 0 {
+					lineContent = e.gutterMarker(pos.line, diags) + e.lineNumber(pos.line) + " "
+				} else {
+					lineContent = strings.Repeat(" ", e.gutterWidth)
+				}
+			}
+			lineContent += strings.Repeat(" ", row.indent)
+			lineContent += e.renderLine(pos.line, row.start, row.end, row.x, width-row.indent, diags)
+			lines = append(lines, lineContent)
+		}
+		pos = visualPos{line: pos.line + 1}
+	}
+
+	for len(lines) < e.height {
+		lineContent := ""
+		if e.showLineNum {
+			lineContent = strings.Repeat(" ", e.gutterWidth)
+		}
+		lines = append(lines, lineContent+lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("~"))
+	}
+	return strings.Join(lines, "\n")
+}
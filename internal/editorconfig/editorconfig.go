@@ -0,0 +1,189 @@
+// Package editorconfig finds the settings .editorconfig files give a file
+// (see https://editorconfig.org).
+package editorconfig
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileName is the name of the files the settings are read from.
+const FileName = ".editorconfig"
+
+// Settings are the properties vex supports. Zero values and nil pointers
+// mean the property is not set.
+type Settings struct {
+	IndentStyle            string // "tab" or "space"
+	IndentSize             int
+	TabWidth               int
+	EndOfLine              string // "lf", "crlf" or "cr"
+	Charset                string // "utf-8", "utf-8-bom", "latin1", "utf-16be" or "utf-16le"
+	TrimTrailingWhitespace *bool
+	InsertFinalNewline     *bool
+	MaxLineLength          int
+}
+
+// section is a [glob] section of a file with its properties in order.
+type section struct {
+	glob  string
+	props [][2]string
+}
+
+// file is a parsed .editorconfig file.
+type file struct {
+	dir      string
+	root     bool
+	sections []section
+}
+
+// Resolve returns the settings for the file at path from the .editorconfig
+// files in its directory and the ones above, up to one with root = true.
+// Closer files and later sections win; the value "unset" removes a
+// property. Files that do not exist are skipped.
+func Resolve(path string) (Settings, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	var files []*file
+	for dir := filepath.Dir(abs); ; {
+		f, err := parseFile(filepath.Join(dir, FileName))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return Settings{}, err
+		}
+		if f != nil {
+			files = append(files, f)
+			if f.root {
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	// Apply from the outermost file in
+	props := make(map[string]string)
+	target := filepath.ToSlash(abs)
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		for _, s := range f.sections {
+			if !matchGlob(s.glob, filepath.ToSlash(f.dir), target) {
+				continue
+			}
+			for _, p := range s.props {
+				if p[1] == "unset" {
+					delete(props, p[0])
+				} else {
+					props[p[0]] = p[1]
+				}
+			}
+		}
+	}
+	return settingsFrom(props), nil
+}
+
+// parseFile reads a .editorconfig file. Lines that are neither a section,
+// a property nor a comment are ignored.
+func parseFile(path string) (*file, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	f := &file{dir: filepath.Dir(path)}
+	var current *section
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			if end := strings.LastIndexByte(line, ']'); end > 0 {
+				f.sections = append(f.sections, section{glob: line[1:end]})
+				current = &f.sections[len(f.sections)-1]
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch {
+		case key == "":
+		case current == nil:
+			// Preamble before the first section
+			if key == "root" {
+				f.root = strings.EqualFold(value, "true")
+			}
+		default:
+			current.props = append(current.props, [2]string{key, strings.ToLower(value)})
+		}
+	}
+	return f, scanner.Err()
+}
+
+// settingsFrom converts the properties that apply to a file to Settings.
+// indent_size defaults to tab_width for tabs, and tab_width to indent_size.
+func settingsFrom(props map[string]string) Settings {
+	var s Settings
+	if v := props["indent_style"]; v == "tab" || v == "space" {
+		s.IndentStyle = v
+	}
+	s.TabWidth = positive(props["tab_width"])
+	s.IndentSize = positive(props["indent_size"])
+	if props["indent_size"] == "tab" || s.IndentSize == 0 && s.IndentStyle == "tab" {
+		s.IndentSize = s.TabWidth
+	}
+	if s.TabWidth == 0 {
+		s.TabWidth = s.IndentSize
+	}
+
+	switch v := props["end_of_line"]; v {
+	case "lf", "crlf", "cr":
+		s.EndOfLine = v
+	}
+	switch v := props["charset"]; v {
+	case "utf-8", "utf-8-bom", "latin1", "utf-16be", "utf-16le":
+		s.Charset = v
+	}
+	s.TrimTrailingWhitespace = boolean(props["trim_trailing_whitespace"])
+	s.InsertFinalNewline = boolean(props["insert_final_newline"])
+	s.MaxLineLength = positive(props["max_line_length"])
+	return s
+}
+
+// positive parses a positive number, returning 0 for anything else.
+func positive(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0
+	}
+	return n
+}
+
+// boolean parses true or false, returning nil for anything else.
+func boolean(value string) *bool {
+	switch value {
+	case "true":
+		b := true
+		return &b
+	case "false":
+		b := false
+		return &b
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package editorconfig
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// numRange is a {num1..num2} pattern, matched by a capturing group.
+type numRange struct {
+	lo, hi int
+}
+
+// matchGlob returns true if the section glob of an .editorconfig file in
+// dir matches path. Both use forward slashes. A glob without a slash
+// matches file names in dir and below; one with a slash matches paths
+// relative to dir.
+func matchGlob(glob, dir, path string) bool {
+	var ranges []numRange
+	var pattern string
+	switch {
+	case !strings.Contains(glob, "/"):
+		pattern = "(?:.*/)?" + translate(glob, &ranges)
+	case strings.HasPrefix(glob, "/"):
+		pattern = translate(glob[1:], &ranges)
+	default:
+		pattern = translate(glob, &ranges)
+	}
+	re, err := regexp.Compile("^" + regexp.QuoteMeta(strings.TrimSuffix(dir, "/")+"/") + pattern + "$")
+	if err != nil {
+		return false
+	}
+
+	groups := re.FindStringSubmatch(path)
+	if groups == nil {
+		return false
+	}
+	for i, r := range ranges {
+		if groups[i+1] == "" {
+			// The range is in an alternative that did not match
+			continue
+		}
+		n, err := strconv.Atoi(groups[i+1])
+		if err != nil || n < r.lo || n > r.hi {
+			return false
+		}
+	}
+	return true
+}
+
+// numRangeRe matches the inside of a {num1..num2} pattern.
+var numRangeRe = regexp.MustCompile(`^([+-]?\d+)\.\.([+-]?\d+)$`)
+
+// translate converts a glob to a regular expression: * matches within a
+// path segment, ** across segments, ? one character, [abc] and [!abc]
+// character classes, {a,b} alternatives and {1..9} numbers in a range,
+// appended to ranges in order.
+func translate(glob string, ranges *[]numRange) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 || strings.Contains(glob[i+1:i+1+end], "/") {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			b.WriteByte('[')
+			if strings.HasPrefix(class, "!") {
+				b.WriteByte('^')
+				class = class[1:]
+			}
+			b.WriteString(strings.ReplaceAll(class, `\`, `\\`))
+			b.WriteByte(']')
+			i += end + 1
+		case '{':
+			end := closingBrace(glob, i)
+			if end < 0 {
+				b.WriteString(`\{`)
+				continue
+			}
+			inner := glob[i+1 : end]
+			i = end
+			if m := numRangeRe.FindStringSubmatch(inner); m != nil {
+				lo, _ := strconv.Atoi(m[1])
+				hi, _ := strconv.Atoi(m[2])
+				*ranges = append(*ranges, numRange{min(lo, hi), max(lo, hi)})
+				b.WriteString(`([+-]?\d+)`)
+				continue
+			}
+			parts := splitAlternatives(inner)
+			if len(parts) == 1 {
+				b.WriteString(regexp.QuoteMeta("{" + inner + "}"))
+				continue
+			}
+			b.WriteString("(?:")
+			for j, part := range parts {
+				if j > 0 {
+					b.WriteByte('|')
+				}
+				b.WriteString(translate(part, ranges))
+			}
+			b.WriteByte(')')
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// closingBrace returns the index of the } that closes the { at start, or
+// -1 if there is none.
+func closingBrace(glob string, start int) int {
+	depth := 0
+	for i := start; i < len(glob); i++ {
+		switch glob[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitAlternatives splits the inside of {a,b} at its top-level commas.
+func splitAlternatives(inner string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(inner); i++ {
+		switch inner[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, inner[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, inner[start:])
+}
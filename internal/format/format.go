@@ -0,0 +1,152 @@
+// Package format runs external formatters on the text of a file.
+package format
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/DDZ-DO/vex/internal/config"
+	"github.com/DDZ-DO/vex/internal/language"
+)
+
+// FilePlaceholder in a formatter's arguments is replaced by the file's path.
+// The formatter reads the text from stdin either way.
+const FilePlaceholder = "{file}"
+
+// Formatter is the command line of a formatter for one language. It reads
+// the text on stdin and writes the formatted text to stdout.
+type Formatter struct {
+	Command string
+	Args    []string
+
+	// Configured is true for formatters named in formatters.toml. A
+	// missing default formatter is skipped silently; a configured one is
+	// reported.
+	Configured bool
+}
+
+// DefaultFormatters returns the formatters used unless formatters.toml
+// overrides them.
+func DefaultFormatters() map[string]Formatter {
+	prettier := Formatter{Command: "prettier", Args: []string{"--stdin-filepath", FilePlaceholder}}
+	return map[string]Formatter{
+		"go":              {Command: "gofmt"},
+		"javascript":      prettier,
+		"javascriptreact": prettier,
+		"typescript":      prettier,
+		"typescriptreact": prettier,
+		"json":            prettier,
+		"yaml":            prettier,
+		"html":            prettier,
+		"css":             prettier,
+		"markdown":        prettier,
+	}
+}
+
+// LoadFormatters returns the default formatters merged with
+// formatters.toml from the config directory. A missing file is not an
+// error.
+func LoadFormatters() (map[string]Formatter, []error) {
+	formatters := DefaultFormatters()
+	path, err := config.FormattersPath()
+	if err != nil {
+		return formatters, nil
+	}
+	errs := LoadFormattersFile(path, formatters)
+	return formatters, errs
+}
+
+// LoadFormattersFile merges a formatter file into formatters. Each entry
+// maps a language ID to a command line run in the file's directory, where
+// {file} stands for the file; an empty command removes the formatter:
+//
+//	go       = "goimports"
+//	markdown = ""
+//
+// Invalid entries are skipped and returned as errors.
+func LoadFormattersFile(path string, formatters map[string]Formatter) []error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return []error{err}
+	}
+	defer file.Close()
+
+	name := filepath.Base(path)
+	entries, errs := config.ReadStringTable(file, name)
+	for _, entry := range entries {
+		if !language.Known(entry.Key) {
+			errs = append(errs, &config.ParseError{File: name, Line: entry.Line,
+				Msg: fmt.Sprintf("unknown language %q", entry.Key)})
+			continue
+		}
+		fields := strings.Fields(entry.Value)
+		if len(fields) == 0 {
+			delete(formatters, entry.Key)
+			continue
+		}
+		formatters[entry.Key] = Formatter{Command: fields[0], Args: fields[1:], Configured: true}
+	}
+	return errs
+}
+
+// ForFile returns the formatter configured for a file's language.
+func ForFile(formatters map[string]Formatter, path string) (Formatter, bool) {
+	f, ok := formatters[language.ID(path)]
+	return f, ok
+}
+
+// Name returns the name errors of the formatter are reported under.
+func (f Formatter) Name() string {
+	return filepath.Base(f.Command)
+}
+
+// Run formats the text of the file at path. A formatter exiting with an
+// error, usually for a syntax error, is reported with the first line it
+// wrote to stderr.
+func (f Formatter) Run(ctx context.Context, path, text string) (string, error) {
+	args := make([]string, len(f.Args))
+	for i, arg := range f.Args {
+		args[i] = strings.ReplaceAll(arg, FilePlaceholder, path)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, f.Command, args...)
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if line := firstLine(stderr.Bytes()); line != "" {
+			return "", errors.New(line)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// firstLine returns the first line of output that is not empty.
+func firstLine(output []byte) string {
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return string(line)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package keybindings
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Function keys Bubble Tea does not know. Terminals report Shift+F9..F12 as
+// F21..F24, which Bubble Tea passes on as unknown sequences; KeyFromMsg
+// turns them into key messages of these types.
+const (
+	KeyF21 tea.KeyType = -1000 - iota
+	KeyF22
+	KeyF23
+	KeyF24
+)
+
+// extraKeys maps the CSI sequences of the extra function keys, without the
+// leading ESC [, to their key types.
+var extraKeys = map[string]tea.KeyType{
+	"20;2~": KeyF21,
+	"21;2~": KeyF22,
+	"23;2~": KeyF23,
+	"24;2~": KeyF24,
+}
+
+// extraKeyNames maps the names of the extra function keys to their types.
+var extraKeyNames = map[string]tea.KeyType{
+	"f21": KeyF21,
+	"f22": KeyF22,
+	"f23": KeyF23,
+	"f24": KeyF24,
+}
+
+// unknownSequences maps the string of Bubble Tea's unknown CSI sequence
+// message to the key type of each extra function key.
+var unknownSequences = func() map[string]tea.KeyType {
+	seqs := make(map[string]tea.KeyType, len(extraKeys))
+	for seq, kt := range extraKeys {
+		seqs[fmt.Sprintf("?CSI%+v?", []byte(seq))] = kt
+	}
+	return seqs
+}()
+
+// KeyFromMsg returns the key message of an extra function key Bubble Tea
+// reported as an unknown sequence.
+func KeyFromMsg(msg tea.Msg) (tea.KeyMsg, bool) {
+	if _, ok := msg.(tea.KeyMsg); ok {
+		return tea.KeyMsg{}, false
+	}
+	s, ok := msg.(fmt.Stringer)
+	if !ok {
+		return tea.KeyMsg{}, false
+	}
+	kt, ok := unknownSequences[s.String()]
+	if !ok {
+		return tea.KeyMsg{}, false
+	}
+	return tea.KeyMsg{Type: kt}, true
+}
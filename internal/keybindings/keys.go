@@ -1,6 +1,9 @@
 package keybindings
 
 import (
+	"strings"
+	"time"
+
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -9,24 +12,32 @@ type Action string
 
 const (
 	// File actions
-	ActionSave   Action = "file.save"
-	ActionSaveAs Action = "file.saveAs"
-	ActionNew    Action = "file.new"
-	ActionOpen   Action = "file.open"
-	ActionClose  Action = "file.close"
-	ActionQuit   Action = "app.quit"
+	ActionSave            Action = "file.save"
+	ActionSaveAs          Action = "file.saveAs"
+	ActionNew             Action = "file.new"
+	ActionOpen            Action = "file.open"
+	ActionQuickOpen       Action = "file.quickOpen"
+	ActionClose           Action = "file.close"
+	ActionReloadFromDisk  Action = "file.reloadFromDisk"
+	ActionCompareWithDisk Action = "file.compareWithDisk"
+	ActionQuit            Action = "app.quit"
 
 	// Edit actions
-	ActionUndo          Action = "edit.undo"
-	ActionRedo          Action = "edit.redo"
-	ActionCut           Action = "edit.cut"
-	ActionCopy          Action = "edit.copy"
-	ActionPaste         Action = "edit.paste"
-	ActionSelectAll     Action = "edit.selectAll"
-	ActionDuplicateLine Action = "edit.duplicateLine"
-	ActionDeleteLine    Action = "edit.deleteLine"
-	ActionMoveLineUp    Action = "edit.moveLineUp"
-	ActionMoveLineDown  Action = "edit.moveLineDown"
+	ActionUndo           Action = "edit.undo"
+	ActionRedo           Action = "edit.redo"
+	ActionCut            Action = "edit.cut"
+	ActionCopy           Action = "edit.copy"
+	ActionPaste          Action = "edit.paste"
+	ActionSelectAll      Action = "edit.selectAll"
+	ActionDuplicateLine  Action = "edit.duplicateLine"
+	ActionDeleteLine     Action = "edit.deleteLine"
+	ActionMoveLineUp     Action = "edit.moveLineUp"
+	ActionMoveLineDown   Action = "edit.moveLineDown"
+	ActionTriggerSuggest Action = "edit.triggerSuggest"
+	ActionUndoOlder      Action = "edit.undoOlder"
+	ActionUndoNewer      Action = "edit.undoNewer"
+	ActionIndentLines    Action = "edit.indentLines"
+	ActionOutdentLines   Action = "edit.outdentLines"
 
 	// Navigation actions
 	ActionMoveLeft        Action = "nav.moveLeft"
@@ -42,6 +53,11 @@ const (
 	ActionPageUp          Action = "nav.pageUp"
 	ActionPageDown        Action = "nav.pageDown"
 	ActionGoToLine        Action = "nav.goToLine"
+	ActionGoToDefinition  Action = "nav.goToDefinition"
+	ActionGoToSymbol      Action = "nav.goToSymbol"
+	ActionFindReferences  Action = "nav.findReferences"
+	ActionGoBack          Action = "nav.goBack"
+	ActionGoForward       Action = "nav.goForward"
 
 	// Selection actions
 	ActionSelectLeft      Action = "select.left"
@@ -54,16 +70,27 @@ const (
 	ActionSelectLineEnd   Action = "select.lineEnd"
 	ActionSelectLine      Action = "select.line"
 
+	// Multi-cursor actions
+	ActionAddCursorAbove       Action = "select.addCursorAbove"
+	ActionAddCursorBelow       Action = "select.addCursorBelow"
+	ActionAddNextOccurrence    Action = "select.addNextOccurrence"
+	ActionSelectAllOccurrences Action = "select.allOccurrences"
+
 	// Search actions
-	ActionFind         Action = "search.find"
-	ActionFindNext     Action = "search.findNext"
-	ActionFindPrevious Action = "search.findPrevious"
-	ActionReplace      Action = "search.replace"
+	ActionFind           Action = "search.find"
+	ActionFindNext       Action = "search.findNext"
+	ActionFindPrevious   Action = "search.findPrevious"
+	ActionReplace        Action = "search.replace"
+	ActionFindInFiles    Action = "search.findInFiles"
+	ActionReplaceInFiles Action = "search.replaceInFiles"
 
 	// View actions
 	ActionToggleSidebar  Action = "view.toggleSidebar"
 	ActionCommandPalette Action = "view.commandPalette"
 	ActionFocusExplorer  Action = "view.focusExplorer"
+	ActionShowHover      Action = "view.showHover"
+	ActionShowProblems   Action = "view.problems"
+	ActionShowUndoTree   Action = "view.undoTree"
 
 	// Tab actions
 	ActionNextTab  Action = "tab.next"
@@ -79,8 +106,58 @@ const (
 
 	// No action
 	ActionNone Action = ""
+
+	// Chord state, returned by Lookup while a multi-key sequence is typed
+	ActionChordPending Action = "chord.pending"
+	ActionChordAbort   Action = "chord.abort"
 )
 
+// ChordTimeout is how long a chord prefix waits for the next key.
+const ChordTimeout = 2 * time.Second
+
+// knownActions lists every action that can be bound to a key.
+var knownActions = map[Action]bool{
+	ActionSave: true, ActionSaveAs: true, ActionNew: true, ActionOpen: true,
+	ActionQuickOpen: true, ActionClose: true, ActionQuit: true,
+	ActionReloadFromDisk: true, ActionCompareWithDisk: true,
+
+	ActionUndo: true, ActionRedo: true, ActionCut: true, ActionCopy: true,
+	ActionPaste: true, ActionSelectAll: true, ActionDuplicateLine: true,
+	ActionDeleteLine: true, ActionMoveLineUp: true, ActionMoveLineDown: true,
+	ActionTriggerSuggest: true, ActionUndoOlder: true, ActionUndoNewer: true,
+	ActionIndentLines: true, ActionOutdentLines: true,
+
+	ActionMoveLeft: true, ActionMoveRight: true, ActionMoveUp: true,
+	ActionMoveDown: true, ActionMoveWordLeft: true, ActionMoveWordRight: true,
+	ActionMoveLineStart: true, ActionMoveLineEnd: true,
+	ActionMoveBufferStart: true, ActionMoveBufferEnd: true,
+	ActionPageUp: true, ActionPageDown: true, ActionGoToLine: true,
+	ActionGoToDefinition: true, ActionGoToSymbol: true, ActionFindReferences: true,
+	ActionGoBack: true, ActionGoForward: true,
+
+	ActionSelectLeft: true, ActionSelectRight: true, ActionSelectUp: true,
+	ActionSelectDown: true, ActionSelectWordLeft: true, ActionSelectWordRight: true,
+	ActionSelectLineStart: true, ActionSelectLineEnd: true, ActionSelectLine: true,
+
+	ActionAddCursorAbove: true, ActionAddCursorBelow: true,
+	ActionAddNextOccurrence: true, ActionSelectAllOccurrences: true,
+
+	ActionFind: true, ActionFindNext: true, ActionFindPrevious: true, ActionReplace: true,
+	ActionFindInFiles: true, ActionReplaceInFiles: true,
+
+	ActionToggleSidebar: true, ActionCommandPalette: true, ActionFocusExplorer: true,
+	ActionShowHover: true, ActionShowProblems: true, ActionShowUndoTree: true,
+
+	ActionNextTab: true, ActionPrevTab: true, ActionCloseTab: true, ActionSaveAll: true,
+
+	ActionInsertNewline: true, ActionInsertTab: true, ActionBackspace: true, ActionDelete: true,
+}
+
+// IsKnownAction returns true if the action ID can be bound to a key.
+func IsKnownAction(action Action) bool {
+	return knownActions[action]
+}
+
 // Binding represents a key binding.
 type Binding struct {
 	Key    tea.KeyType
@@ -88,12 +165,26 @@ type Binding struct {
 	Alt    bool
 	Ctrl   bool
 	Shift  bool
+	Chord  []Binding // Further keys of a multi-key sequence (e.g. Ctrl+K Ctrl+L)
 	Action Action
 }
 
+// step returns the key expected at position i of the binding's sequence.
+func (b Binding) step(i int) Binding {
+	if i == 0 {
+		return b
+	}
+	return b.Chord[i-1]
+}
+
 // KeyBindings manages keyboard shortcuts.
 type KeyBindings struct {
 	bindings []Binding
+
+	// Pending chord state
+	pending      []Binding // Candidates whose first keys have been typed
+	pendingKeys  []string  // Display names of the typed keys
+	pendingSince time.Time
 }
 
 // NewKeyBindings creates a new keybindings manager with default VSCode-style bindings.
@@ -111,8 +202,11 @@ func (kb *KeyBindings) loadDefaults() {
 		{Key: tea.KeyF6, Action: ActionSaveAll},
 		{Key: tea.KeyCtrlN, Action: ActionNew},
 		{Key: tea.KeyCtrlO, Action: ActionOpen},
+		{Key: tea.KeyCtrlP, Action: ActionQuickOpen},
 		{Key: tea.KeyCtrlW, Action: ActionCloseTab},
 		{Key: tea.KeyCtrlQ, Action: ActionQuit},
+		{Runes: "r", Alt: true, Action: ActionReloadFromDisk},
+		{Runes: "c", Alt: true, Action: ActionCompareWithDisk},
 
 		// Tab navigation (F7/F8 for tab switching)
 		{Key: tea.KeyF7, Action: ActionPrevTab},
@@ -121,12 +215,20 @@ func (kb *KeyBindings) loadDefaults() {
 		// Edit operations
 		{Key: tea.KeyCtrlZ, Action: ActionUndo},
 		{Key: tea.KeyCtrlY, Action: ActionRedo},
+		{Runes: "z", Alt: true, Action: ActionUndoOlder},
+		{Runes: "y", Alt: true, Action: ActionUndoNewer},
 		{Key: tea.KeyCtrlX, Action: ActionCut},
 		{Key: tea.KeyCtrlC, Action: ActionCopy},
 		{Key: tea.KeyCtrlV, Action: ActionPaste},
 		{Key: tea.KeyCtrlA, Action: ActionSelectAll},
 		{Key: tea.KeyCtrlD, Action: ActionDuplicateLine},
 		{Key: tea.KeyCtrlL, Action: ActionDeleteLine},
+		{Key: tea.KeyCtrlK, Chord: []Binding{{Key: tea.KeyCtrlL}}, Action: ActionSelectLine},
+		{Key: tea.KeyCtrlK, Chord: []Binding{{Runes: "s"}}, Action: ActionSaveAll},
+		{Key: tea.KeyUp, Alt: true, Action: ActionMoveLineUp},
+		{Key: tea.KeyDown, Alt: true, Action: ActionMoveLineDown},
+		{Key: tea.KeyShiftTab, Action: ActionOutdentLines},
+		{Key: tea.KeySpace, Ctrl: true, Action: ActionTriggerSuggest},
 
 		// Navigation
 		{Key: tea.KeyLeft, Action: ActionMoveLeft},
@@ -138,6 +240,12 @@ func (kb *KeyBindings) loadDefaults() {
 		{Key: tea.KeyPgUp, Action: ActionPageUp},
 		{Key: tea.KeyPgDown, Action: ActionPageDown},
 		{Key: tea.KeyCtrlG, Action: ActionGoToLine},
+		{Key: tea.KeyF12, Action: ActionGoToDefinition},
+		{Key: KeyF24, Action: ActionFindReferences},                // Shift+F12
+		{Key: tea.KeyF12, Alt: true, Action: ActionFindReferences}, // For terminals without Shift+F12
+		{Key: tea.KeyCtrlR, Action: ActionGoToSymbol},
+		{Key: tea.KeyLeft, Alt: true, Action: ActionGoBack},
+		{Key: tea.KeyRight, Alt: true, Action: ActionGoForward},
 
 		// Word navigation (Ctrl+Arrow)
 		{Key: tea.KeyCtrlLeft, Action: ActionMoveWordLeft},
@@ -153,15 +261,27 @@ func (kb *KeyBindings) loadDefaults() {
 		{Key: tea.KeyShiftUp, Action: ActionSelectUp},
 		{Key: tea.KeyShiftDown, Action: ActionSelectDown},
 
+		// Multiple cursors
+		{Key: tea.KeyCtrlUp, Alt: true, Action: ActionAddCursorAbove},
+		{Key: tea.KeyCtrlDown, Alt: true, Action: ActionAddCursorBelow},
+		{Runes: "d", Alt: true, Action: ActionAddNextOccurrence},
+		{Runes: "D", Alt: true, Action: ActionSelectAllOccurrences},
+
 		// Search
 		{Key: tea.KeyCtrlF, Action: ActionFind},
 		{Key: tea.KeyCtrlH, Action: ActionReplace},
 		{Key: tea.KeyF3, Action: ActionFindNext},
+		{Key: tea.KeyF15, Action: ActionFindPrevious}, // Shift+F3
+		{Runes: "f", Alt: true, Action: ActionFindInFiles},
+		{Runes: "h", Alt: true, Action: ActionReplaceInFiles},
 
 		// View
 		{Key: tea.KeyCtrlB, Action: ActionToggleSidebar},
-		{Key: tea.KeyCtrlP, Action: ActionCommandPalette},
+		{Key: tea.KeyF1, Action: ActionCommandPalette},
 		{Key: tea.KeyCtrlE, Action: ActionFocusExplorer},
+		{Key: tea.KeyCtrlK, Chord: []Binding{{Runes: "i"}}, Action: ActionShowHover},
+		{Key: tea.KeyCtrlK, Chord: []Binding{{Runes: "m"}}, Action: ActionShowProblems},
+		{Key: tea.KeyCtrlK, Chord: []Binding{{Runes: "u"}}, Action: ActionShowUndoTree},
 
 		// Text input
 		{Key: tea.KeyEnter, Action: ActionInsertNewline},
@@ -172,21 +292,96 @@ func (kb *KeyBindings) loadDefaults() {
 }
 
 // Lookup looks up the action for a key message.
+// Keys that start or continue a multi-key chord return ActionChordPending;
+// a key that does not continue the pending chord returns ActionChordAbort.
 func (kb *KeyBindings) Lookup(msg tea.KeyMsg) Action {
-	// Check for exact matches first
-	for _, binding := range kb.bindings {
-		if kb.matches(binding, msg) {
+	kb.ExpirePending()
+
+	depth := len(kb.pendingKeys)
+	candidates := kb.bindings
+	if depth > 0 {
+		candidates = kb.pending
+	}
+
+	// The first matching binding decides: a complete binding runs at once,
+	// otherwise every chord continuing with this key stays pending.
+	var partial []Binding
+	var stepName string
+	for _, binding := range candidates {
+		step := binding.step(depth)
+		if !kb.matches(step, msg) {
+			continue
+		}
+		if partial == nil && depth == len(binding.Chord) {
+			kb.CancelPending()
 			return binding.Action
 		}
+		if depth < len(binding.Chord) {
+			partial = append(partial, binding)
+			stepName = kb.formatKey(step)
+		}
+	}
+
+	if len(partial) > 0 {
+		kb.pending = partial
+		kb.pendingKeys = append(kb.pendingKeys, stepName)
+		kb.pendingSince = time.Now()
+		return ActionChordPending
 	}
 
+	if depth > 0 {
+		kb.CancelPending()
+		return ActionChordAbort
+	}
 	return ActionNone
 }
 
+// LookupKey looks up the action bound to a single key, ignoring chords and
+// leaving any pending chord untouched.
+func (kb *KeyBindings) LookupKey(msg tea.KeyMsg) Action {
+	for _, binding := range kb.bindings {
+		if len(binding.Chord) == 0 && kb.matches(binding, msg) {
+			return binding.Action
+		}
+	}
+	return ActionNone
+}
+
+// IsPending returns true while a chord prefix waits for its next key.
+func (kb *KeyBindings) IsPending() bool {
+	return len(kb.pendingKeys) > 0
+}
+
+// Pending returns the keys typed so far of a pending chord (e.g. "Ctrl+K").
+func (kb *KeyBindings) Pending() string {
+	return strings.Join(kb.pendingKeys, " ")
+}
+
+// CancelPending discards a pending chord.
+func (kb *KeyBindings) CancelPending() {
+	kb.pending = nil
+	kb.pendingKeys = nil
+}
+
+// ExpirePending cancels a pending chord older than ChordTimeout.
+// Returns true if a chord was cancelled.
+func (kb *KeyBindings) ExpirePending() bool {
+	if kb.IsPending() && time.Since(kb.pendingSince) >= ChordTimeout {
+		kb.CancelPending()
+		return true
+	}
+	return false
+}
+
 // matches checks if a binding matches a key message.
 func (kb *KeyBindings) matches(binding Binding, msg tea.KeyMsg) bool {
-	// Check key type
-	if binding.Key != 0 && msg.Type == binding.Key {
+	// Terminals send NUL for Ctrl+Space, whose key type is the zero value
+	if binding.Ctrl && binding.Key == tea.KeySpace {
+		return msg.Type == tea.KeyCtrlAt && binding.Alt == msg.Alt
+	}
+
+	// Check key type (Alt must match so Alt+Up is distinct from Up)
+	if binding.Key != 0 && msg.Type == binding.Key && binding.Alt == msg.Alt {
 		return true
 	}
 
@@ -218,6 +413,15 @@ func (kb *KeyBindings) GetBindingForAction(action Action) string {
 
 // formatBinding formats a binding as a human-readable string.
 func (kb *KeyBindings) formatBinding(binding Binding) string {
+	result := kb.formatKey(binding)
+	for _, next := range binding.Chord {
+		result += " " + kb.formatKey(next)
+	}
+	return result
+}
+
+// formatKey formats a single key of a binding.
+func (kb *KeyBindings) formatKey(binding Binding) string {
 	var parts []string
 
 	if binding.Ctrl {
@@ -231,10 +435,23 @@ func (kb *KeyBindings) formatBinding(binding Binding) string {
 	}
 
 	keyName := getKeyName(binding.Key)
+	if keyName == "" && binding.Key != 0 {
+		keyName = titleKeyName(binding.Key.String())
+	}
 	if keyName != "" {
+		if binding.Alt && strings.HasPrefix(keyName, "Ctrl+") {
+			// Ctrl+Up with Alt reads as Ctrl+Alt+Up
+			parts = append([]string{"Ctrl"}, parts...)
+			keyName = strings.TrimPrefix(keyName, "Ctrl+")
+		}
 		parts = append(parts, keyName)
 	} else if binding.Runes != "" {
-		parts = append(parts, binding.Runes)
+		// Letters are shown in upper case, with Shift for upper-case runes
+		keyName = strings.ToUpper(binding.Runes)
+		if keyName == binding.Runes && strings.ToLower(keyName) != keyName {
+			parts = append(parts, "Shift")
+		}
+		parts = append(parts, keyName)
 	}
 
 	result := ""
@@ -247,6 +464,18 @@ func (kb *KeyBindings) formatBinding(binding Binding) string {
 	return result
 }
 
+// titleKeyName converts a Bubble Tea key name such as "ctrl+shift+up"
+// into display form ("Ctrl+Shift+Up").
+func titleKeyName(name string) string {
+	parts := strings.Split(name, "+")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "+")
+}
+
 // getKeyName returns the name of a key type.
 func getKeyName(key tea.KeyType) string {
 	switch key {
@@ -278,6 +507,8 @@ func getKeyName(key tea.KeyType) string {
 		return "Ctrl+P"
 	case tea.KeyCtrlQ:
 		return "Ctrl+Q"
+	case tea.KeyCtrlR:
+		return "Ctrl+R"
 	case tea.KeyCtrlS:
 		return "Ctrl+S"
 	case tea.KeyCtrlV:
@@ -294,6 +525,10 @@ func getKeyName(key tea.KeyType) string {
 		return "Enter"
 	case tea.KeyTab:
 		return "Tab"
+	case tea.KeyShiftTab:
+		return "Shift+Tab"
+	case tea.KeySpace:
+		return "Space"
 	case tea.KeyBackspace:
 		return "Backspace"
 	case tea.KeyDelete:
@@ -314,8 +549,21 @@ func getKeyName(key tea.KeyType) string {
 		return "PageUp"
 	case tea.KeyPgDown:
 		return "PageDown"
+	case tea.KeyF1:
+		return "F1"
 	case tea.KeyF3:
 		return "F3"
+	case tea.KeyF15:
+		// Terminals report Shift+F1..F12 as F13..F24
+		return "Shift+F3"
+	case KeyF21:
+		return "Shift+F9"
+	case KeyF22:
+		return "Shift+F10"
+	case KeyF23:
+		return "Shift+F11"
+	case KeyF24:
+		return "Shift+F12"
 	case tea.KeyEsc:
 		return "Esc"
 	case tea.KeyShiftLeft:
@@ -0,0 +1,291 @@
+package keybindings
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DDZ-DO/vex/internal/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// keyTypesByName maps Bubble Tea key names ("ctrl+k", "shift+up", "f5")
+// to their key types.
+var keyTypesByName = func() map[string]tea.KeyType {
+	names := make(map[string]tea.KeyType)
+	// Special keys use small negative values, control keys 0-127
+	for k := tea.KeyType(-128); k <= 127; k++ {
+		if name := k.String(); name != "" && k != tea.KeyRunes {
+			names[name] = k
+		}
+	}
+	names["space"] = tea.KeySpace
+	for name, kt := range extraKeyNames {
+		names[name] = kt
+	}
+	return names
+}()
+
+// keyAliases maps alternative key names to Bubble Tea names.
+var keyAliases = map[string]string{
+	"escape":   "esc",
+	"return":   "enter",
+	"del":      "delete",
+	"ins":      "insert",
+	"pageup":   "pgup",
+	"pagedown": "pgdown",
+}
+
+// Conflict describes a user binding that left another action without a key,
+// or two user entries that resolve to the same key.
+type Conflict struct {
+	Line     int
+	Key      string
+	Action   Action
+	Replaced Action
+	Same     bool // Replaced came from an earlier entry in the same file
+}
+
+// String returns a human-readable description of the conflict.
+func (c Conflict) String() string {
+	if c.Same {
+		return fmt.Sprintf("line %d: %s is the same key as an earlier entry (%s replaced by %s)",
+			c.Line, c.Key, c.Replaced, c.Action)
+	}
+	return fmt.Sprintf("line %d: %s now runs %s, %s has no key binding", c.Line, c.Key, c.Action, c.Replaced)
+}
+
+// ParseKey parses a key description such as "ctrl+s", "alt+up", "shift+tab"
+// or "f5" into a Binding without an action.
+func ParseKey(desc string) (Binding, error) {
+	var b Binding
+
+	desc = strings.ToLower(strings.TrimSpace(desc))
+	parts := strings.Split(desc, "+")
+	key := parts[len(parts)-1]
+	if key == "" && strings.HasSuffix(desc, "++") {
+		// "ctrl++" binds the plus key itself
+		key = "+"
+		parts = parts[:len(parts)-1]
+	}
+	if key == "" {
+		return b, fmt.Errorf("missing key in %q", desc)
+	}
+
+	for _, mod := range parts[:len(parts)-1] {
+		switch mod {
+		case "ctrl", "control":
+			b.Ctrl = true
+		case "alt", "meta", "option":
+			b.Alt = true
+		case "shift":
+			b.Shift = true
+		default:
+			return b, fmt.Errorf("unknown modifier %q in %q", mod, desc)
+		}
+	}
+
+	if alias, ok := keyAliases[key]; ok {
+		key = alias
+	}
+
+	// Plain characters are matched as runes
+	if !b.Ctrl && utf8.RuneCountInString(key) == 1 && key != " " {
+		if b.Shift {
+			key = strings.ToUpper(key)
+		}
+		b.Runes = key
+		return b, nil
+	}
+
+	if b.Ctrl && !b.Shift && (key == "space" || key == "@") {
+		// Ctrl+Space, see KeyBindings.matches
+		b.Key = tea.KeySpace
+		return b, nil
+	}
+
+	prefix := ""
+	if b.Ctrl {
+		prefix += "ctrl+"
+	}
+	if b.Shift {
+		prefix += "shift+"
+	}
+
+	kt, ok := keyTypesByName[prefix+key]
+	if !ok && b.Ctrl && b.Shift {
+		// Terminals report Ctrl+Shift+<letter> as Ctrl+<letter>
+		kt, ok = keyTypesByName["ctrl+"+key]
+	}
+	if !ok && !b.Ctrl && b.Shift {
+		// Terminals report Shift+F1..F12 as F13..F24
+		var n int
+		if _, err := fmt.Sscanf(key, "f%d", &n); err == nil && n >= 1 && n <= 12 {
+			kt, ok = keyTypesByName[fmt.Sprintf("f%d", n+12)]
+		}
+	}
+	if !ok {
+		return b, fmt.Errorf("unknown key %q", desc)
+	}
+
+	// The key type already encodes Ctrl/Shift
+	b.Key = kt
+	b.Ctrl = false
+	b.Shift = false
+	return b, nil
+}
+
+// ParseKeySequence parses a space-separated key sequence such as
+// "ctrl+k ctrl+l" into a Binding. A single key yields a plain binding.
+func ParseKeySequence(desc string) (Binding, error) {
+	fields := strings.Fields(desc)
+	if len(fields) == 0 {
+		return Binding{}, fmt.Errorf("missing key in %q", desc)
+	}
+
+	first, err := ParseKey(fields[0])
+	if err != nil {
+		return first, err
+	}
+	for _, field := range fields[1:] {
+		next, err := ParseKey(field)
+		if err != nil {
+			return first, err
+		}
+		first.Chord = append(first.Chord, next)
+	}
+	return first, nil
+}
+
+// sameKey returns true if two bindings are triggered by the same key
+// sequence.
+func sameKey(a, b Binding) bool {
+	if len(a.Chord) != len(b.Chord) {
+		return false
+	}
+	for i := 0; i <= len(a.Chord); i++ {
+		x, y := a.step(i), b.step(i)
+		// Ctrl and Shift are only kept where the key type does not encode
+		// them, as for Ctrl+Space and Space
+		if x.Key != y.Key || x.Runes != y.Runes || x.Ctrl != y.Ctrl || x.Shift != y.Shift || x.Alt != y.Alt {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadUserBindings loads keybindings.toml from the config directory and
+// merges it over the current bindings. A missing file is not an error.
+func (kb *KeyBindings) LoadUserBindings() ([]Conflict, []error) {
+	path, err := config.KeybindingsPath()
+	if err != nil {
+		return nil, nil
+	}
+	return kb.LoadFile(path)
+}
+
+// LoadFile merges the bindings from a keybindings file over the current ones.
+// Each entry maps a key to an action ID:
+//
+//	"ctrl+k" = "edit.deleteLine"
+//	"ctrl+l" = ""                 # unbind
+//	"ctrl+k ctrl+d" = "edit.duplicateLine"  # chord
+//
+// User bindings take precedence over (and replace) existing bindings for the
+// same key. Invalid entries are skipped and returned as errors.
+func (kb *KeyBindings) LoadFile(path string) ([]Conflict, []error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, []error{err}
+	}
+	defer file.Close()
+
+	name := filepath.Base(path)
+	entries, errs := config.ReadStringTable(file, name)
+
+	var user []Binding
+	var conflicts []Conflict
+	var displaced []Conflict
+
+	for _, entry := range entries {
+		fail := func(format string, args ...any) {
+			errs = append(errs, &config.ParseError{File: name, Line: entry.Line, Msg: fmt.Sprintf(format, args...)})
+		}
+
+		binding, err := ParseKeySequence(entry.Key)
+		if err != nil {
+			fail("%v", err)
+			continue
+		}
+
+		action := Action(entry.Value)
+		if action != ActionNone && !IsKnownAction(action) {
+			fail("unknown action %q", entry.Value)
+			continue
+		}
+		binding.Action = action
+
+		// Two entries that resolve to the same key press
+		duplicate := false
+		for i, prev := range user {
+			if sameKey(prev, binding) {
+				conflicts = append(conflicts, Conflict{
+					Line:     entry.Line,
+					Key:      entry.Key,
+					Action:   action,
+					Replaced: prev.Action,
+					Same:     true,
+				})
+				user[i].Action = action
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		// Remove existing bindings for this key
+		kept := kb.bindings[:0]
+		for _, existing := range kb.bindings {
+			if sameKey(existing, binding) {
+				if action != ActionNone && existing.Action != action {
+					displaced = append(displaced, Conflict{
+						Line:     entry.Line,
+						Key:      entry.Key,
+						Action:   action,
+						Replaced: existing.Action,
+					})
+				}
+				continue
+			}
+			kept = append(kept, existing)
+		}
+		kb.bindings = kept
+
+		user = append(user, binding)
+	}
+
+	// Drop unbind markers and give user bindings precedence
+	var merged []Binding
+	for _, binding := range user {
+		if binding.Action != ActionNone {
+			merged = append(merged, binding)
+		}
+	}
+	kb.bindings = append(merged, kb.bindings...)
+
+	// A displaced action is only a conflict if it lost its last key
+	for _, c := range displaced {
+		if kb.GetBindingForAction(c.Replaced) == "" {
+			conflicts = append(conflicts, c)
+		}
+	}
+
+	return conflicts, errs
+}
@@ -0,0 +1,62 @@
+// Package language detects the language of a file by its extension. Its
+// IDs are the language identifiers of the Language Server Protocol, which
+// also name the languages in languageservers.toml, linters.toml and
+// formatters.toml.
+package language
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// ids maps file extensions to language IDs.
+var ids = map[string]string{
+	".go":   "go",
+	".py":   "python",
+	".pyi":  "python",
+	".js":   "javascript",
+	".mjs":  "javascript",
+	".cjs":  "javascript",
+	".jsx":  "javascriptreact",
+	".ts":   "typescript",
+	".mts":  "typescript",
+	".cts":  "typescript",
+	".tsx":  "typescriptreact",
+	".rs":   "rust",
+	".c":    "c",
+	".h":    "c",
+	".cc":   "cpp",
+	".cpp":  "cpp",
+	".cxx":  "cpp",
+	".hh":   "cpp",
+	".hpp":  "cpp",
+	".hxx":  "cpp",
+	".java": "java",
+	".rb":   "ruby",
+	".lua":  "lua",
+	".zig":  "zig",
+	".sh":   "shellscript",
+	".bash": "shellscript",
+	".json": "json",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".toml": "toml",
+	".html": "html",
+	".css":  "css",
+	".md":   "markdown",
+}
+
+// ID returns the language ID of a file, or "" if unknown.
+func ID(path string) string {
+	return ids[strings.ToLower(filepath.Ext(path))]
+}
+
+// Known returns true for language IDs vex can detect.
+func Known(id string) bool {
+	for _, known := range ids {
+		if known == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+// Package linediff finds the lines that differ between two texts.
+package linediff
+
+// maxDiffCells caps the size of the table used to match changed lines.
+// Larger changes are shown as all old lines replaced by all new lines.
+const maxDiffCells = 4 << 20
+
+// LineChange is a line that differs between an old and a new text.
+type LineChange struct {
+	Line    int    // 0-based line in the new text; for removed lines, the line after them
+	Old     string // Line of the old text, "" if Added
+	New     string // Line of the new text, "" if Removed
+	Removed bool   // Old was removed without a replacement
+	Added   bool   // New was added without replacing a line
+}
+
+// Diff returns the lines that differ between old and new. A changed line
+// pairs the removed line with the one that replaced it.
+func Diff(old, new []string) []LineChange {
+	// Skip the common start and end
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix &&
+		old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	a := old[prefix : len(old)-suffix]
+	b := new[prefix : len(new)-suffix]
+
+	var changes []LineChange
+	addHunk := func(j int, removed, added []string) {
+		for k := 0; k < max(len(removed), len(added)); k++ {
+			c := LineChange{Line: prefix + j + min(k, len(added))}
+			switch {
+			case k < len(removed) && k < len(added):
+				c.Old, c.New = removed[k], added[k]
+			case k < len(removed):
+				c.Old, c.Removed = removed[k], true
+			default:
+				c.New, c.Added = added[k], true
+			}
+			changes = append(changes, c)
+		}
+	}
+
+	if len(a)*len(b) > maxDiffCells {
+		addHunk(0, a, b)
+		return changes
+	}
+
+	// Longest common subsequence, filled from the end
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table, collecting the lines between matches as hunks
+	i, j := 0, 0
+	hunkI, hunkJ := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			addHunk(hunkJ, a[hunkI:i], b[hunkJ:j])
+			i++
+			j++
+			hunkI, hunkJ = i, j
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			j++
+		default:
+			i++
+		}
+	}
+	addHunk(hunkJ, a[hunkI:], b[hunkJ:])
+	return changes
+}
@@ -0,0 +1,103 @@
+// Package lint runs external linters and parses their output.
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity is the severity of a problem. The values match the diagnostic
+// severities of the language server protocol.
+type Severity int
+
+// Problem severities.
+const (
+	SeverityError   Severity = 1
+	SeverityWarning Severity = 2
+	SeverityInfo    Severity = 3
+)
+
+// Problem is one finding of a linter.
+type Problem struct {
+	Path     string // Absolute file path
+	Line     int    // 1-based
+	Column   int    // 1-based byte column, 0 if the linter gave none
+	Severity Severity
+	Message  string
+	Source   string // Name of the linter
+}
+
+// problemLine matches "file:line:col: message" and "file:line: message",
+// optionally after a tool prefix such as "vet: ".
+var problemLine = regexp.MustCompile(`^(?:[\w-]+: )?(.+?):(\d+):(?:(\d+):)?\s*(.*)$`)
+
+// Parse extracts the problems from linter output. Relative paths are
+// resolved against dir, the directory the linter ran in. Other lines, such
+// as "# package" headers or indented continuation lines, are skipped.
+func Parse(output []byte, dir, source string) []Problem {
+	var problems []Problem
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if text == "" || text[0] == ' ' || text[0] == '\t' {
+			continue
+		}
+		m := problemLine.FindStringSubmatch(text)
+		if m == nil || m[4] == "" {
+			continue
+		}
+
+		line, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3]) // 0 when missing
+		path := m[1]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		severity, message := parseSeverity(m[4])
+		problems = append(problems, Problem{
+			Path:     path,
+			Line:     max(line, 1),
+			Column:   column,
+			Severity: severity,
+			Message:  message,
+			Source:   source,
+		})
+	}
+	return problems
+}
+
+// parseSeverity reads the severity from a message such as "error: ..." or
+// "... [Warning/no-unused-vars]" (eslint's unix format). Messages without
+// one are warnings.
+func parseSeverity(message string) (Severity, string) {
+	lower := strings.ToLower(message)
+	for _, prefix := range []struct {
+		text     string
+		severity Severity
+	}{
+		{"error:", SeverityError},
+		{"fatal:", SeverityError},
+		{"warning:", SeverityWarning},
+		{"note:", SeverityInfo},
+		{"info:", SeverityInfo},
+	} {
+		if strings.HasPrefix(lower, prefix.text) {
+			return prefix.severity, strings.TrimSpace(message[len(prefix.text):])
+		}
+	}
+
+	if i := strings.LastIndex(message, " ["); i >= 0 && strings.HasSuffix(message, "]") {
+		tag := strings.ToLower(message[i+2:])
+		switch {
+		case strings.HasPrefix(tag, "error"):
+			return SeverityError, message
+		case strings.HasPrefix(tag, "warning"):
+			return SeverityWarning, message
+		}
+	}
+	return SeverityWarning, message
+}
@@ -0,0 +1,137 @@
+package lint
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/DDZ-DO/vex/internal/config"
+	"github.com/DDZ-DO/vex/internal/language"
+)
+
+// FilePlaceholder in a linter's arguments is replaced by the saved file.
+const FilePlaceholder = "{file}"
+
+// Linter is the command line of a linter for one language.
+type Linter struct {
+	Command string
+	Args    []string
+}
+
+// LoadLinters reads linters.toml from the config directory. There are no
+// default linters; a missing file is not an error.
+func LoadLinters() (map[string]Linter, []error) {
+	linters := make(map[string]Linter)
+	path, err := config.LintersPath()
+	if err != nil {
+		return linters, nil
+	}
+	errs := LoadLintersFile(path, linters)
+	return linters, errs
+}
+
+// LoadLintersFile merges a linter file into linters. Each entry maps a
+// language ID to a command line run in the saved file's directory, where
+// {file} stands for the file; an empty command removes the linter:
+//
+//	go         = "go vet"
+//	javascript = "eslint --format unix {file}"
+//
+// Invalid entries are skipped and returned as errors.
+func LoadLintersFile(path string, linters map[string]Linter) []error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return []error{err}
+	}
+	defer file.Close()
+
+	name := filepath.Base(path)
+	entries, errs := config.ReadStringTable(file, name)
+	for _, entry := range entries {
+		if !language.Known(entry.Key) {
+			errs = append(errs, &config.ParseError{File: name, Line: entry.Line,
+				Msg: fmt.Sprintf("unknown language %q", entry.Key)})
+			continue
+		}
+		fields := strings.Fields(entry.Value)
+		if len(fields) == 0 {
+			delete(linters, entry.Key)
+			continue
+		}
+		linters[entry.Key] = Linter{Command: fields[0], Args: fields[1:]}
+	}
+	return errs
+}
+
+// ForFile returns the linter configured for a file's language.
+func ForFile(linters map[string]Linter, path string) (Linter, bool) {
+	l, ok := linters[language.ID(path)]
+	return l, ok
+}
+
+// Name returns the name the linter's problems are reported under.
+func (l Linter) Name() string {
+	return filepath.Base(l.Command)
+}
+
+// Scope returns what a run for path covers: the file itself if the
+// command names it with {file}, otherwise the file's whole directory.
+// A new run replaces the problems of an earlier run with the same scope.
+func (l Linter) Scope(path string) string {
+	for _, arg := range l.Args {
+		if strings.Contains(arg, FilePlaceholder) {
+			return path
+		}
+	}
+	return filepath.Dir(path)
+}
+
+// Run lints a saved file and returns the problems found in any file. A
+// linter exiting with an error is normal when it finds problems; it is
+// only reported if its output contains none.
+func (l Linter) Run(ctx context.Context, path string) ([]Problem, error) {
+	dir := filepath.Dir(path)
+	args := make([]string, len(l.Args))
+	for i, arg := range l.Args {
+		args[i] = strings.ReplaceAll(arg, FilePlaceholder, path)
+	}
+
+	cmd := exec.CommandContext(ctx, l.Command, args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	problems := Parse(output, dir, l.Name())
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil, len(problems) > 0:
+		return problems, nil
+	case errors.As(err, &exitErr):
+		if line := firstLine(output); line != "" {
+			return nil, errors.New(line)
+		}
+	}
+	return nil, err
+}
+
+// firstLine returns the first line of output that is not empty or a
+// "# package" header.
+func firstLine(output []byte) string {
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return string(line)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,321 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// shutdownTimeout bounds how long a server gets to exit cleanly.
+const shutdownTimeout = 2 * time.Second
+
+// Client is a connection to one language server for one workspace root.
+// Document changes are always sent as the full text, which servers accept
+// for every sync kind.
+type Client struct {
+	conn  *Conn
+	root  string
+	proc  *exec.Cmd // nil for in-process servers
+	caps  serverCapabilities
+	sync  textDocumentSync
+	hooks clientHooks
+}
+
+// clientHooks receive what the server pushes.
+type clientHooks struct {
+	diagnostics func(path string, diags []Diagnostic)
+	message     func(text string, severity Severity)
+}
+
+// startServer launches a language server process in root and initializes
+// it. The server's stderr is discarded.
+func startServer(ctx context.Context, server ServerConfig, root string, hooks clientHooks) (*Client, error) {
+	cmd := exec.Command(server.Command, server.Args...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := newClient(&pipe{ReadCloser: stdout, WriteCloser: stdin}, root, hooks)
+	c.proc = cmd
+	go cmd.Wait() // Reap the process; its exit shows up as EOF on stdout
+
+	if err := c.initialize(ctx); err != nil {
+		c.kill()
+		return nil, err
+	}
+	return c, nil
+}
+
+// newClient creates a client over rwc, such as a process's pipes or an
+// in-process server, without initializing it.
+func newClient(rwc io.ReadWriteCloser, root string, hooks clientHooks) *Client {
+	c := &Client{root: root, hooks: hooks}
+	c.conn = NewConn(rwc, c.handle)
+	return c
+}
+
+// pipe joins a process's stdout and stdin into one stream.
+type pipe struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+// Close closes both directions.
+func (p *pipe) Close() error {
+	err := p.WriteCloser.Close()
+	if rerr := p.ReadCloser.Close(); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// initialize performs the initialize handshake.
+func (c *Client) initialize(ctx context.Context) error {
+	rootURI := PathToURI(c.root)
+	params := map[string]any{
+		"processId": os.Getpid(),
+		"clientInfo": map[string]string{
+			"name": "vex",
+		},
+		"rootUri":  rootURI,
+		"rootPath": c.root,
+		"workspaceFolders": []map[string]string{
+			{"uri": rootURI, "name": filepath.Base(c.root)},
+		},
+		"capabilities": map[string]any{
+			"general": map[string]any{
+				"positionEncodings": []string{"utf-16"},
+			},
+			"textDocument": map[string]any{
+				"synchronization": map[string]any{"didSave": true},
+				"hover": map[string]any{
+					"contentFormat": []string{"plaintext", "markdown"},
+				},
+				"completion": map[string]any{
+					"completionItem": map[string]any{"snippetSupport": false},
+				},
+				"definition":         map[string]any{"linkSupport": true},
+				"publishDiagnostics": map[string]any{},
+			},
+			"workspace": map[string]any{
+				"workspaceFolders": true,
+				"configuration":    true,
+			},
+		},
+	}
+
+	var result struct {
+		Capabilities serverCapabilities `json:"capabilities"`
+	}
+	if err := c.conn.Call(ctx, "initialize", params, &result); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	c.caps = result.Capabilities
+	c.sync = textDocumentSync{Change: syncFull, Save: true}
+	if c.caps.TextDocumentSync != nil {
+		c.sync = *c.caps.TextDocumentSync
+	}
+	return c.conn.Notify("initialized", struct{}{})
+}
+
+// handle answers the requests and notifications the server sends.
+func (c *Client) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p struct {
+			URI         string       `json:"uri"`
+			Diagnostics []Diagnostic `json:"diagnostics"`
+		}
+		if err := json.Unmarshal(params, &p); err == nil && c.hooks.diagnostics != nil {
+			c.hooks.diagnostics(URIToPath(p.URI), p.Diagnostics)
+		}
+		return nil, nil
+	case "window/showMessage":
+		var p struct {
+			Type    Severity `json:"type"`
+			Message string   `json:"message"`
+		}
+		// Message types share the values of error and warning severities
+		if err := json.Unmarshal(params, &p); err == nil && c.hooks.message != nil && p.Type <= SeverityWarning {
+			c.hooks.message(p.Message, p.Type)
+		}
+		return nil, nil
+	case "workspace/configuration":
+		// No settings: one null per requested item
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(params, &p)
+		return make([]any, len(p.Items)), nil
+	case "workspace/workspaceFolders":
+		return []map[string]string{{"uri": PathToURI(c.root), "name": filepath.Base(c.root)}}, nil
+	case "client/registerCapability", "client/unregisterCapability",
+		"window/workDoneProgress/create", "window/showMessageRequest",
+		"window/logMessage", "$/progress", "telemetry/event":
+		return nil, nil
+	}
+	return nil, &ResponseError{Code: codeMethodNotFound, Message: "method not found: " + method}
+}
+
+// Root returns the workspace root the server was started for.
+func (c *Client) Root() string {
+	return c.root
+}
+
+// Done is closed when the connection to the server ends.
+func (c *Client) Done() <-chan struct{} {
+	return c.conn.Done()
+}
+
+// DidOpen tells the server that a file was opened with the given text.
+func (c *Client) DidOpen(path, languageID string, version int, text string) error {
+	return c.conn.Notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{
+			"uri":        PathToURI(path),
+			"languageId": languageID,
+			"version":    version,
+			"text":       text,
+		},
+	})
+}
+
+// DidChange sends the new text of a file.
+func (c *Client) DidChange(path string, version int, text string) error {
+	if c.sync.Change == syncNone {
+		return nil
+	}
+	return c.conn.Notify("textDocument/didChange", map[string]any{
+		"textDocument": map[string]any{
+			"uri":     PathToURI(path),
+			"version": version,
+		},
+		"contentChanges": []map[string]string{{"text": text}},
+	})
+}
+
+// DidSave tells the server that a file was saved.
+func (c *Client) DidSave(path, text string) error {
+	if !c.sync.Save {
+		return nil
+	}
+	params := map[string]any{
+		"textDocument": map[string]string{"uri": PathToURI(path)},
+	}
+	if c.sync.Text {
+		params["text"] = text
+	}
+	return c.conn.Notify("textDocument/didSave", params)
+}
+
+// DidClose tells the server that a file was closed.
+func (c *Client) DidClose(path string) error {
+	return c.conn.Notify("textDocument/didClose", map[string]any{
+		"textDocument": map[string]string{"uri": PathToURI(path)},
+	})
+}
+
+// positionParams builds the parameters of a request at a position.
+func positionParams(path string, pos Position) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]string{"uri": PathToURI(path)},
+		"position":     pos,
+	}
+}
+
+// Hover returns the hover text at a position, or "" if there is none.
+func (c *Client) Hover(ctx context.Context, path string, pos Position) (string, error) {
+	if !supported(c.caps.HoverProvider) {
+		return "", nil
+	}
+	var result *struct {
+		Contents json.RawMessage `json:"contents"`
+	}
+	if err := c.conn.Call(ctx, "textDocument/hover", positionParams(path, pos), &result); err != nil {
+		return "", err
+	}
+	if result == nil {
+		return "", nil
+	}
+	return markupText(result.Contents), nil
+}
+
+// Completion returns the completions at a position.
+func (c *Client) Completion(ctx context.Context, path string, pos Position) ([]CompletionItem, error) {
+	if !supported(c.caps.CompletionProvider) {
+		return nil, nil
+	}
+	var raw json.RawMessage
+	if err := c.conn.Call(ctx, "textDocument/completion", positionParams(path, pos), &raw); err != nil {
+		return nil, err
+	}
+
+	// Either an array of items or a CompletionList
+	var items []wireCompletionItem
+	if json.Unmarshal(raw, &items) != nil {
+		var list struct {
+			Items []wireCompletionItem `json:"items"`
+		}
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, nil
+		}
+		items = list.Items
+	}
+
+	completions := make([]CompletionItem, 0, len(items))
+	for _, item := range items {
+		completions = append(completions, item.completionItem())
+	}
+	return completions, nil
+}
+
+// Definition returns the locations defining the symbol at a position.
+func (c *Client) Definition(ctx context.Context, path string, pos Position) ([]Location, error) {
+	if !supported(c.caps.DefinitionProvider) {
+		return nil, nil
+	}
+	var raw json.RawMessage
+	if err := c.conn.Call(ctx, "textDocument/definition", positionParams(path, pos), &raw); err != nil {
+		return nil, err
+	}
+	return decodeLocations(raw), nil
+}
+
+// Shutdown asks the server to exit and closes the connection. A server
+// that does not answer in time is killed.
+func (c *Client) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := c.conn.Call(ctx, "shutdown", nil, nil); err == nil {
+		_ = c.conn.Notify("exit", nil)
+		if c.proc != nil {
+			select {
+			case <-c.conn.Done():
+			case <-ctx.Done():
+			}
+		}
+	}
+	c.kill()
+}
+
+// kill closes the connection and stops the server process.
+func (c *Client) kill() {
+	c.conn.Close()
+	if c.proc != nil && c.proc.Process != nil {
+		_ = c.proc.Process.Kill()
+	}
+}
@@ -0,0 +1,294 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// ErrClosed is returned for calls on a closed connection.
+var ErrClosed = errors.New("connection closed")
+
+// JSON-RPC error codes used by vex.
+const (
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+)
+
+// ResponseError is an error returned by the other side of a call.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Handler handles the requests and notifications the server sends. For a
+// request the result (or error) is sent back as the response; for a
+// notification it is ignored. Handlers run on the connection's read loop,
+// in message order, and must not block.
+type Handler func(method string, params json.RawMessage) (any, error)
+
+// Conn is a JSON-RPC 2.0 connection speaking the LSP base protocol, where
+// every message is preceded by a Content-Length header.
+type Conn struct {
+	rwc     io.ReadWriteCloser
+	handler Handler
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *wireMessage
+	err     error // Why the connection closed
+	done    chan struct{}
+}
+
+// wireMessage is any message as read from the stream.
+type wireMessage struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+	Result json.RawMessage  `json:"result"`
+	Error  *ResponseError   `json:"error"`
+}
+
+type outgoingRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type outgoingNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type outgoingResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ResponseError  `json:"error,omitempty"`
+}
+
+// NewConn starts a connection over rwc. The handler may be nil, in which
+// case server requests are answered with "method not found".
+func NewConn(rwc io.ReadWriteCloser, handler Handler) *Conn {
+	c := &Conn{
+		rwc:     rwc,
+		handler: handler,
+		pending: make(map[int64]chan *wireMessage),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Call sends a request and waits for its response, which is decoded into
+// result unless result is nil. If ctx ends first the request is cancelled.
+func (c *Conn) Call(ctx context.Context, method string, params, result any) error {
+	id, ch, err := c.request(method, params)
+	if err != nil {
+		return err
+	}
+	return c.wait(ctx, id, ch, result)
+}
+
+// Notify sends a notification.
+func (c *Conn) Notify(method string, params any) error {
+	return c.write(outgoingNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// Close closes the connection. Pending calls fail with ErrClosed.
+func (c *Conn) Close() error {
+	err := c.rwc.Close()
+	c.shutdown(ErrClosed)
+	return err
+}
+
+// Done is closed when the connection is closed, by either side.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection closed, or nil while it is open.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// request sends a request and returns the channel its response arrives on.
+func (c *Conn) request(method string, params any) (int64, chan *wireMessage, error) {
+	ch := make(chan *wireMessage, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return 0, nil, c.err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.write(outgoingRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params}); err != nil {
+		c.forget(id)
+		return 0, nil, err
+	}
+	return id, ch, nil
+}
+
+// wait waits for the response to a request sent with request.
+func (c *Conn) wait(ctx context.Context, id int64, ch chan *wireMessage, result any) error {
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-ctx.Done():
+		c.forget(id)
+		_ = c.Notify("$/cancelRequest", map[string]int64{"id": id})
+		return ctx.Err()
+	case <-c.done:
+		return c.Err()
+	}
+}
+
+// forget drops a pending request; a late response is ignored.
+func (c *Conn) forget(id int64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// write sends one framed message.
+func (c *Conn) write(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.Err(); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.rwc, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = c.rwc.Write(data)
+	return err
+}
+
+// readLoop reads messages until the stream ends, dispatching responses to
+// their callers and everything else to the handler.
+func (c *Conn) readLoop() {
+	r := bufio.NewReader(c.rwc)
+	for {
+		data, err := readMessage(r)
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		var msg wireMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue // Skip malformed messages
+		}
+
+		switch {
+		case msg.Method != "":
+			c.handle(&msg)
+		case msg.ID != nil:
+			id, err := strconv.ParseInt(string(*msg.ID), 10, 64)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- &msg
+			}
+		}
+	}
+}
+
+// handle passes a server request or notification to the handler and
+// answers requests.
+func (c *Conn) handle(msg *wireMessage) {
+	var result any
+	err := error(&ResponseError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method})
+	if c.handler != nil {
+		result, err = c.handler(msg.Method, msg.Params)
+	}
+	if msg.ID == nil {
+		return
+	}
+
+	resp := outgoingResponse{JSONRPC: "2.0", ID: *msg.ID}
+	if err != nil {
+		var respErr *ResponseError
+		if !errors.As(err, &respErr) {
+			respErr = &ResponseError{Code: codeInternalError, Message: err.Error()}
+		}
+		resp.Error = respErr
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			data = []byte("null")
+		}
+		resp.Result = data
+	}
+	_ = c.write(resp)
+}
+
+// shutdown marks the connection closed and fails pending calls.
+func (c *Conn) shutdown(err error) {
+	if errors.Is(err, io.EOF) {
+		err = ErrClosed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.pending = make(map[int64]chan *wireMessage)
+	close(c.done)
+}
+
+// readMessage reads one message body from the base protocol stream.
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
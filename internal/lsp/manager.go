@@ -0,0 +1,366 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/DDZ-DO/vex/internal/language"
+)
+
+// initTimeout bounds how long a server may take to start and initialize.
+const initTimeout = 30 * time.Second
+
+// ErrNoServer is returned for requests on files no language server handles.
+var ErrNoServer = errors.New("no language server for this file")
+
+// Event reports something a language server did that the UI should show.
+type Event struct {
+	Path    string // File whose diagnostics changed, if any
+	Message string // Message for the status bar, if any
+	Error   bool
+}
+
+// clientKey identifies the server of a workspace root and language.
+type clientKey struct {
+	root     string
+	language string
+}
+
+// Manager runs one language server per workspace root and language and
+// keeps it in sync with the open files. All methods may be called from any
+// goroutine. Document updates return at once; a background worker sends
+// them in order and starts servers on first use. Requests wait for earlier
+// updates to be sent, so they see the current text.
+type Manager struct {
+	servers map[string]ServerConfig
+	start   func(ctx context.Context, server ServerConfig, root string, hooks clientHooks) (*Client, error)
+	events  chan Event
+
+	ctx    context.Context // Cancelled by Shutdown to abort starting servers
+	cancel context.CancelFunc
+
+	queueMu sync.Mutex
+	queue   []func()
+	closed  bool
+	wake    chan struct{}
+	changes map[string]pendingChange // Latest unsent text by path
+
+	// Owned by the worker
+	clients map[clientKey]*Client
+	failed  map[clientKey]bool
+	docs    map[string]*Client // Open files by path
+
+	mu          sync.Mutex
+	diagnostics map[string][]Diagnostic
+}
+
+// pendingChange is the latest text of a file waiting to be sent.
+type pendingChange struct {
+	text    string
+	version int
+}
+
+// NewManager creates a manager for the given servers by language ID.
+func NewManager(servers map[string]ServerConfig) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		servers:     servers,
+		start:       startServer,
+		events:      make(chan Event, 64),
+		ctx:         ctx,
+		cancel:      cancel,
+		wake:        make(chan struct{}, 1),
+		changes:     make(map[string]pendingChange),
+		clients:     make(map[clientKey]*Client),
+		failed:      make(map[clientKey]bool),
+		docs:        make(map[string]*Client),
+		diagnostics: make(map[string][]Diagnostic),
+	}
+	go m.run()
+	return m
+}
+
+// Events returns the channel events are delivered on. Events are dropped
+// while the channel is full; diagnostics can always be read again with
+// Diagnostics.
+func (m *Manager) Events() <-chan Event {
+	return m.events
+}
+
+// Handles returns true if a language server is configured for the file.
+func (m *Manager) Handles(path string) bool {
+	_, ok := m.servers[language.ID(path)]
+	return ok
+}
+
+// Open tells the file's server that it was opened. Files of languages
+// without a server are ignored.
+func (m *Manager) Open(path, text string, version int) {
+	lang := language.ID(path)
+	if _, ok := m.servers[lang]; !ok {
+		return
+	}
+	m.dropChange(path)
+	m.enqueue(func() {
+		c := m.client(path, lang)
+		if c == nil {
+			return
+		}
+		if err := c.DidOpen(path, lang, version, text); err == nil {
+			m.docs[path] = c
+		}
+	})
+}
+
+// Change sends the new text of an open file. While an earlier change of
+// the file waits to be sent, as when its server is still starting, the new
+// text replaces it, so only the latest text is kept and sent.
+func (m *Manager) Change(path, text string, version int) {
+	m.queueMu.Lock()
+	_, waiting := m.changes[path]
+	m.changes[path] = pendingChange{text: text, version: version}
+	m.queueMu.Unlock()
+	if waiting {
+		return
+	}
+
+	m.enqueue(func() {
+		m.queueMu.Lock()
+		change, ok := m.changes[path]
+		delete(m.changes, path)
+		m.queueMu.Unlock()
+		if c := m.docs[path]; ok && c != nil {
+			_ = c.DidChange(path, change.version, change.text)
+		}
+	})
+}
+
+// dropChange forgets the unsent change of a file that is opened or closed
+// again; changes made afterwards are queued anew.
+func (m *Manager) dropChange(path string) {
+	m.queueMu.Lock()
+	delete(m.changes, path)
+	m.queueMu.Unlock()
+}
+
+// Save tells the server that an open file was saved.
+func (m *Manager) Save(path, text string) {
+	m.enqueue(func() {
+		if c := m.docs[path]; c != nil {
+			_ = c.DidSave(path, text)
+		}
+	})
+}
+
+// Close tells the server that a file was closed and forgets its
+// diagnostics.
+func (m *Manager) Close(path string) {
+	m.dropChange(path)
+	m.enqueue(func() {
+		if c := m.docs[path]; c != nil {
+			_ = c.DidClose(path)
+			delete(m.docs, path)
+		}
+	})
+	m.setDiagnostics(path, nil)
+}
+
+// Hover returns the hover text at a position in an open file.
+func (m *Manager) Hover(ctx context.Context, path string, pos Position) (string, error) {
+	c, err := m.documentClient(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return c.Hover(ctx, path, pos)
+}
+
+// Completion returns the completions at a position in an open file.
+func (m *Manager) Completion(ctx context.Context, path string, pos Position) ([]CompletionItem, error) {
+	c, err := m.documentClient(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Completion(ctx, path, pos)
+}
+
+// Definition returns where the symbol at a position in an open file is
+// defined.
+func (m *Manager) Definition(ctx context.Context, path string, pos Position) ([]Location, error) {
+	c, err := m.documentClient(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Definition(ctx, path, pos)
+}
+
+// Diagnostics returns the latest diagnostics of a file.
+func (m *Manager) Diagnostics(path string) []Diagnostic {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Diagnostic(nil), m.diagnostics[path]...)
+}
+
+// AllDiagnostics returns the latest diagnostics of every file, by path.
+func (m *Manager) AllDiagnostics() map[string][]Diagnostic {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	all := make(map[string][]Diagnostic, len(m.diagnostics))
+	for path, diags := range m.diagnostics {
+		all[path] = append([]Diagnostic(nil), diags...)
+	}
+	return all
+}
+
+// Shutdown stops every server. The manager cannot be used afterwards.
+func (m *Manager) Shutdown() {
+	m.cancel()
+
+	done := make(chan struct{})
+	queued := m.enqueue(func() {
+		var wg sync.WaitGroup
+		for _, c := range m.clients {
+			wg.Add(1)
+			go func(c *Client) {
+				defer wg.Done()
+				c.Shutdown()
+			}(c)
+		}
+		wg.Wait()
+		close(done)
+	})
+	if !queued {
+		return
+	}
+
+	m.queueMu.Lock()
+	m.closed = true
+	m.queueMu.Unlock()
+	<-done
+}
+
+// documentClient waits until earlier updates are sent and returns the
+// client the file is open in.
+func (m *Manager) documentClient(ctx context.Context, path string) (*Client, error) {
+	result := make(chan *Client, 1)
+	if !m.enqueue(func() { result <- m.docs[path] }) {
+		return nil, ErrClosed
+	}
+
+	select {
+	case c := <-result:
+		if c == nil {
+			return nil, ErrNoServer
+		}
+		return c, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// enqueue adds an operation for the worker. Returns false after Shutdown.
+func (m *Manager) enqueue(op func()) bool {
+	m.queueMu.Lock()
+	if m.closed {
+		m.queueMu.Unlock()
+		return false
+	}
+	m.queue = append(m.queue, op)
+	m.queueMu.Unlock()
+
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// run is the worker: it runs queued operations in order.
+func (m *Manager) run() {
+	for range m.wake {
+		for {
+			m.queueMu.Lock()
+			if len(m.queue) == 0 {
+				m.queueMu.Unlock()
+				break
+			}
+			op := m.queue[0]
+			m.queue = m.queue[1:]
+			m.queueMu.Unlock()
+
+			op()
+		}
+	}
+}
+
+// client returns the running server for a file, starting it if needed.
+// Returns nil if the server cannot be started or has exited; either is
+// reported once.
+func (m *Manager) client(path, language string) *Client {
+	server := m.servers[language]
+	key := clientKey{root: FindRoot(path), language: language}
+
+	if c := m.clients[key]; c != nil {
+		select {
+		case <-c.Done():
+			// The server exited; its files stay unsynced
+			delete(m.clients, key)
+			m.failed[key] = true
+			for p, dc := range m.docs {
+				if dc == c {
+					delete(m.docs, p)
+				}
+			}
+			m.notify(Event{Message: fmt.Sprintf("Language Server %s wurde beendet", server.Command), Error: true})
+			return nil
+		default:
+			return c
+		}
+	}
+	if m.failed[key] {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, initTimeout)
+	defer cancel()
+	c, err := m.start(ctx, server, key.root, clientHooks{
+		diagnostics: func(path string, diags []Diagnostic) {
+			m.setDiagnostics(path, diags)
+			m.notify(Event{Path: path})
+		},
+		message: func(text string, severity Severity) {
+			m.notify(Event{Message: server.Command + ": " + text, Error: severity == SeverityError})
+		},
+	})
+	if err != nil {
+		m.failed[key] = true
+		if !errors.Is(err, exec.ErrNotFound) || server.Configured {
+			m.notify(Event{Message: fmt.Sprintf("Language Server %s: %v", server.Command, err), Error: true})
+		}
+		return nil
+	}
+	m.clients[key] = c
+	return c
+}
+
+// setDiagnostics stores the diagnostics of a file.
+func (m *Manager) setDiagnostics(path string, diags []Diagnostic) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(diags) == 0 {
+		delete(m.diagnostics, path)
+		return
+	}
+	m.diagnostics[path] = diags
+}
+
+// notify delivers an event without blocking.
+func (m *Manager) notify(ev Event) {
+	select {
+	case m.events <- ev:
+	default:
+	}
+}
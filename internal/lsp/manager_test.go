@@ -0,0 +1,248 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is an in-process language server on one end of a pipe. It
+// records the notifications it gets, answers hover and definition, and
+// publishes one diagnostic per changed text.
+type fakeServer struct {
+	conn *Conn
+
+	mu      sync.Mutex
+	methods []string
+	texts   []string // Text of every didOpen and didChange
+}
+
+// newFakeServer starts a fake server and returns the client end of the
+// pipe.
+func newFakeServer() (*fakeServer, net.Conn) {
+	clientEnd, serverEnd := net.Pipe()
+	s := &fakeServer{}
+	s.conn = NewConn(serverEnd, s.handle)
+	return s, clientEnd
+}
+
+func (s *fakeServer) handle(method string, params json.RawMessage) (any, error) {
+	s.mu.Lock()
+	s.methods = append(s.methods, method)
+	s.mu.Unlock()
+
+	var p struct {
+		TextDocument struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+	_ = json.Unmarshal(params, &p)
+
+	switch method {
+	case "initialize":
+		return map[string]any{"capabilities": map[string]any{
+			"textDocumentSync":   1,
+			"hoverProvider":      true,
+			"definitionProvider": true,
+		}}, nil
+	case "textDocument/didOpen", "textDocument/didChange":
+		text := p.TextDocument.Text
+		if len(p.ContentChanges) > 0 {
+			text = p.ContentChanges[0].Text
+		}
+		s.mu.Lock()
+		s.texts = append(s.texts, text)
+		s.mu.Unlock()
+		uri := p.TextDocument.URI
+		go s.conn.Notify("textDocument/publishDiagnostics", map[string]any{
+			"uri": uri,
+			"diagnostics": []Diagnostic{{
+				Range:    Range{Start: Position{Line: 0}, End: Position{Line: 0, Character: 1}},
+				Severity: SeverityError,
+				Message:  "text: " + text,
+			}},
+		})
+	case "textDocument/hover":
+		return map[string]any{"contents": map[string]string{"kind": "plaintext", "value": "func main()"}}, nil
+	case "textDocument/definition":
+		return []map[string]any{{
+			"uri":   p.TextDocument.URI,
+			"range": Range{Start: Position{Line: 3, Character: 5}, End: Position{Line: 3, Character: 9}},
+		}}, nil
+	case "shutdown":
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// received returns the methods and texts the server got so far.
+func (s *fakeServer) received() (methods, texts []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.methods...), append([]string(nil), s.texts...)
+}
+
+// newTestManager returns a manager for Go whose servers are fake ones. The
+// start hook waits for release to be closed, if it is not nil.
+func newTestManager(t *testing.T, release chan struct{}) (*Manager, chan *fakeServer) {
+	t.Helper()
+	servers := make(chan *fakeServer, 4)
+	m := NewManager(map[string]ServerConfig{"go": {Command: "fake"}})
+	m.start = func(ctx context.Context, server ServerConfig, root string, hooks clientHooks) (*Client, error) {
+		if release != nil {
+			<-release
+		}
+		s, rwc := newFakeServer()
+		servers <- s
+		c := newClient(rwc, root, hooks)
+		if err := c.initialize(ctx); err != nil {
+			c.kill()
+			return nil, err
+		}
+		return c, nil
+	}
+	t.Cleanup(m.Shutdown)
+	return m, servers
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestManagerSyncAndRequests(t *testing.T) {
+	m, servers := newTestManager(t, nil)
+	path := filepath.Join(t.TempDir(), "main.go")
+
+	m.Open(path, "package main", 1)
+	m.Change(path, "package main\n\nfunc main() {}", 2)
+
+	ctx := context.Background()
+	hover, err := m.Hover(ctx, path, Position{Line: 2, Character: 6})
+	if err != nil || hover != "func main()" {
+		t.Fatalf("Hover = %q, %v", hover, err)
+	}
+	locs, err := m.Definition(ctx, path, Position{Line: 2, Character: 6})
+	if err != nil || len(locs) != 1 || locs[0].Path != path || locs[0].Range.Start.Line != 3 {
+		t.Fatalf("Definition = %+v, %v", locs, err)
+	}
+
+	s := <-servers
+	methods, texts := s.received()
+	want := []string{"initialize", "initialized", "textDocument/didOpen", "textDocument/didChange"}
+	if strings.Join(methods[:len(want)], " ") != strings.Join(want, " ") {
+		t.Errorf("methods = %v, want prefix %v", methods, want)
+	}
+	if len(texts) != 2 || texts[1] != "package main\n\nfunc main() {}" {
+		t.Errorf("texts = %q", texts)
+	}
+
+	waitFor(t, "diagnostics of the change", func() bool {
+		diags := m.Diagnostics(path)
+		return len(diags) == 1 && diags[0].Message == "text: package main\n\nfunc main() {}"
+	})
+	select {
+	case ev := <-m.Events():
+		if ev.Path != path {
+			t.Errorf("event = %+v, want diagnostics of %s", ev, path)
+		}
+	case <-time.After(time.Second):
+		t.Error("no diagnostics event")
+	}
+}
+
+func TestManagerSendsOnlyLatestPendingChange(t *testing.T) {
+	release := make(chan struct{})
+	m, servers := newTestManager(t, release)
+	path := filepath.Join(t.TempDir(), "main.go")
+
+	// Keystrokes while the server starts
+	m.Open(path, "", 1)
+	for i := 2; i <= 100; i++ {
+		m.Change(path, strings.Repeat("x", i), i)
+	}
+	close(release)
+
+	if _, err := m.Hover(context.Background(), path, Position{}); err != nil {
+		t.Fatal(err)
+	}
+	_, texts := (<-servers).received()
+	if len(texts) != 2 || texts[1] != strings.Repeat("x", 100) {
+		t.Errorf("server got %d texts, want the opened and the latest one", len(texts))
+	}
+}
+
+func TestManagerServerCrash(t *testing.T) {
+	m, servers := newTestManager(t, nil)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+
+	m.Open(path, "package a", 1)
+	if _, err := m.Hover(context.Background(), path, Position{}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := m.documentClient(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	(<-servers).conn.Close()
+
+	// The next file of the same root finds the server gone
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("connection still open after the server closed it")
+	}
+	other := filepath.Join(dir, "b.go")
+	m.Open(other, "package a", 1)
+	if _, err := m.Hover(context.Background(), other, Position{}); !errors.Is(err, ErrNoServer) {
+		t.Errorf("Hover after crash = %v, want ErrNoServer", err)
+	}
+	waitFor(t, "the crash to be reported", func() bool {
+		select {
+		case ev := <-m.Events():
+			return ev.Error && strings.Contains(ev.Message, "beendet")
+		default:
+			return false
+		}
+	})
+}
+
+func TestManagerMissingServer(t *testing.T) {
+	for _, configured := range []bool{false, true} {
+		m := NewManager(map[string]ServerConfig{"go": {Command: "vex-no-such-language-server", Configured: configured}})
+		path := filepath.Join(t.TempDir(), "main.go")
+		m.Open(path, "package main", 1)
+		if _, err := m.Hover(context.Background(), path, Position{}); !errors.Is(err, ErrNoServer) {
+			t.Errorf("configured=%v: Hover = %v, want ErrNoServer", configured, err)
+		}
+
+		var reported bool
+		select {
+		case ev := <-m.Events():
+			reported = ev.Error
+		default:
+		}
+		if reported != configured {
+			t.Errorf("configured=%v: reported = %v", configured, reported)
+		}
+		m.Shutdown()
+	}
+}
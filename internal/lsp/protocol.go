@@ -0,0 +1,339 @@
+package lsp
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position is a zero-based line and UTF-16 code unit offset, as used on the
+// wire. Use Character and RuneColumn to convert from and to rune columns.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a half-open range between two positions.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a file.
+type Location struct {
+	Path  string `json:"-"` // Absolute file path, decoded from URI
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// Severity is the severity of a diagnostic.
+type Severity int
+
+// Diagnostic severities.
+const (
+	SeverityError       Severity = 1
+	SeverityWarning     Severity = 2
+	SeverityInformation Severity = 3
+	SeverityHint        Severity = 4
+)
+
+// Diagnostic is a problem the server reported for a file.
+type Diagnostic struct {
+	Range    Range    `json:"range"`
+	Severity Severity `json:"severity,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// CompletionItem is a completion proposal.
+type CompletionItem struct {
+	Label      string
+	Detail     string
+	Kind       int
+	InsertText string // Plain text; snippet placeholders are removed
+	SortText   string
+	FilterText string
+	Range      *Range // Text the completion replaces; nil means the word at the cursor
+}
+
+// Character returns the UTF-16 offset of rune column col in line.
+func Character(line string, col int) int {
+	character := 0
+	for i, r := range []rune(line) {
+		if i >= col {
+			break
+		}
+		character += utf16Len(r)
+	}
+	return character
+}
+
+// RuneColumn returns the rune column of the UTF-16 offset character in
+// line. Offsets past the end of the line return its length.
+func RuneColumn(line string, character int) int {
+	col := 0
+	for _, r := range line {
+		if character <= 0 {
+			break
+		}
+		character -= utf16Len(r)
+		col++
+	}
+	return col
+}
+
+// utf16Len returns the number of UTF-16 code units encoding r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
+
+// PathToURI converts an absolute file path to a file:// URI.
+func PathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letters
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// URIToPath converts a file:// URI to a file path. Other URIs are returned
+// unchanged.
+func URIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:] // "/C:/dir" on Windows
+	}
+	return filepath.FromSlash(path)
+}
+
+// Wire formats of server responses that come in several shapes.
+
+// textDocumentSync is the server's document sync capability: either a kind
+// or an options object.
+type textDocumentSync struct {
+	Change int
+	Save   bool
+	Text   bool // didSave includes the text
+}
+
+// UnmarshalJSON decodes both shapes of the capability.
+func (s *textDocumentSync) UnmarshalJSON(data []byte) error {
+	var kind int
+	if err := json.Unmarshal(data, &kind); err == nil {
+		s.Change = kind
+		s.Save = true
+		return nil
+	}
+
+	var opts struct {
+		Change int             `json:"change"`
+		Save   json.RawMessage `json:"save"`
+	}
+	if err := json.Unmarshal(data, &opts); err != nil {
+		return err
+	}
+	s.Change = opts.Change
+
+	var save bool
+	var saveOpts struct {
+		IncludeText bool `json:"includeText"`
+	}
+	switch {
+	case json.Unmarshal(opts.Save, &save) == nil:
+		s.Save = save
+	case json.Unmarshal(opts.Save, &saveOpts) == nil:
+		s.Save = true
+		s.Text = saveOpts.IncludeText
+	}
+	return nil
+}
+
+// Text document sync kinds. vex always sends the full text, which is also
+// valid for servers asking for incremental changes.
+const (
+	syncNone = 0
+	syncFull = 1
+)
+
+// serverCapabilities holds the capabilities vex uses.
+type serverCapabilities struct {
+	TextDocumentSync   *textDocumentSync `json:"textDocumentSync"`
+	HoverProvider      json.RawMessage   `json:"hoverProvider"`
+	CompletionProvider json.RawMessage   `json:"completionProvider"`
+	DefinitionProvider json.RawMessage   `json:"definitionProvider"`
+}
+
+// supported reports whether a capability that is a boolean or an options
+// object is present and not false.
+func supported(raw json.RawMessage) bool {
+	s := strings.TrimSpace(string(raw))
+	return s != "" && s != "false" && s != "null"
+}
+
+// markupText extracts plain text from hover contents, which may be a
+// string, a MarkedString, a MarkupContent or an array of those.
+func markupText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	var content struct {
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(raw, &content) == nil && content.Value != "" {
+		return content.Value
+	}
+
+	var parts []json.RawMessage
+	if json.Unmarshal(raw, &parts) == nil {
+		var texts []string
+		for _, part := range parts {
+			if text := markupText(part); text != "" {
+				texts = append(texts, text)
+			}
+		}
+		return strings.Join(texts, "\n\n")
+	}
+	return ""
+}
+
+// wireCompletionItem is a completion item as sent by the server.
+type wireCompletionItem struct {
+	Label            string `json:"label"`
+	Kind             int    `json:"kind"`
+	Detail           string `json:"detail"`
+	SortText         string `json:"sortText"`
+	FilterText       string `json:"filterText"`
+	InsertText       string `json:"insertText"`
+	InsertTextFormat int    `json:"insertTextFormat"`
+	TextEdit         *struct {
+		NewText string `json:"newText"`
+		Range   *Range `json:"range"`
+		Insert  *Range `json:"insert"` // InsertReplaceEdit
+	} `json:"textEdit"`
+}
+
+// insertTextFormatSnippet marks insert text with snippet placeholders.
+const insertTextFormatSnippet = 2
+
+// completionItem converts a wire item for the editor.
+func (w wireCompletionItem) completionItem() CompletionItem {
+	item := CompletionItem{
+		Label:      w.Label,
+		Detail:     w.Detail,
+		Kind:       w.Kind,
+		InsertText: w.Label,
+		SortText:   w.SortText,
+		FilterText: w.FilterText,
+	}
+	if w.InsertText != "" {
+		item.InsertText = w.InsertText
+	}
+	if w.TextEdit != nil {
+		item.InsertText = w.TextEdit.NewText
+		item.Range = w.TextEdit.Range
+		if item.Range == nil {
+			item.Range = w.TextEdit.Insert
+		}
+	}
+	if w.InsertTextFormat == insertTextFormatSnippet {
+		item.InsertText = stripSnippet(item.InsertText)
+	}
+	return item
+}
+
+// stripSnippet removes snippet syntax, keeping placeholder defaults:
+// "Println(${1:a})$0" becomes "Println(a)".
+func stripSnippet(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			sb.WriteByte(s[i])
+		case s[i] == '$' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			for i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9' {
+				i++
+			}
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			// ${1} or ${1:default}, possibly nested
+			j := i + 2
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			if j < len(s) && s[j] == ':' {
+				j++
+			}
+			depth := 1
+			start := j
+			for ; j < len(s); j++ {
+				if s[j] == '{' {
+					depth++
+				} else if s[j] == '}' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			if start < j {
+				sb.WriteString(stripSnippet(s[start:j]))
+			}
+			i = j
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// wireLocation covers both Location and LocationLink.
+type wireLocation struct {
+	URI                  string `json:"uri"`
+	Range                *Range `json:"range"`
+	TargetURI            string `json:"targetUri"`
+	TargetSelectionRange *Range `json:"targetSelectionRange"`
+}
+
+// decodeLocations decodes a definition result: null, a Location, or an
+// array of Locations or LocationLinks.
+func decodeLocations(raw json.RawMessage) []Location {
+	var list []wireLocation
+	if json.Unmarshal(raw, &list) != nil {
+		var single wireLocation
+		if json.Unmarshal(raw, &single) != nil {
+			return nil
+		}
+		list = []wireLocation{single}
+	}
+
+	var locations []Location
+	for _, w := range list {
+		loc := Location{URI: w.URI}
+		if w.Range != nil {
+			loc.Range = *w.Range
+		}
+		if w.TargetURI != "" {
+			loc.URI = w.TargetURI
+			if w.TargetSelectionRange != nil {
+				loc.Range = *w.TargetSelectionRange
+			}
+		}
+		if loc.URI == "" {
+			continue
+		}
+		loc.Path = URIToPath(loc.URI)
+		locations = append(locations, loc)
+	}
+	return locations
+}
@@ -0,0 +1,114 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DDZ-DO/vex/internal/config"
+	"github.com/DDZ-DO/vex/internal/language"
+)
+
+// ServerConfig is the command line that starts a language server speaking
+// LSP over stdio.
+type ServerConfig struct {
+	Command string
+	Args    []string
+
+	// Configured is true for servers named in languageservers.toml. A
+	// missing default server is skipped silently; a configured one is
+	// reported.
+	Configured bool
+}
+
+// DefaultServers returns the servers used when languageservers.toml does
+// not override them, by language ID.
+func DefaultServers() map[string]ServerConfig {
+	typescript := ServerConfig{Command: "typescript-language-server", Args: []string{"--stdio"}}
+	clangd := ServerConfig{Command: "clangd"}
+	return map[string]ServerConfig{
+		"go":              {Command: "gopls"},
+		"python":          {Command: "pyright-langserver", Args: []string{"--stdio"}},
+		"javascript":      typescript,
+		"javascriptreact": typescript,
+		"typescript":      typescript,
+		"typescriptreact": typescript,
+		"rust":            {Command: "rust-analyzer"},
+		"c":               clangd,
+		"cpp":             clangd,
+	}
+}
+
+// LoadServers returns the default servers merged with languageservers.toml
+// from the config directory. A missing file is not an error.
+func LoadServers() (map[string]ServerConfig, []error) {
+	servers := DefaultServers()
+	path, err := config.LanguageServersPath()
+	if err != nil {
+		return servers, nil
+	}
+	errs := LoadServersFile(path, servers)
+	return servers, errs
+}
+
+// LoadServersFile merges a language server file into servers. Each entry
+// maps a language ID to a command line; an empty command disables the
+// language:
+//
+//	go     = "gopls -remote=auto"
+//	python = ""
+//
+// Invalid entries are skipped and returned as errors.
+func LoadServersFile(path string, servers map[string]ServerConfig) []error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return []error{err}
+	}
+	defer file.Close()
+
+	name := filepath.Base(path)
+	entries, errs := config.ReadStringTable(file, name)
+	for _, entry := range entries {
+		if !language.Known(entry.Key) {
+			errs = append(errs, &config.ParseError{File: name, Line: entry.Line,
+				Msg: fmt.Sprintf("unknown language %q", entry.Key)})
+			continue
+		}
+		fields := strings.Fields(entry.Value)
+		if len(fields) == 0 {
+			delete(servers, entry.Key)
+			continue
+		}
+		servers[entry.Key] = ServerConfig{Command: fields[0], Args: fields[1:], Configured: true}
+	}
+	return errs
+}
+
+// rootMarkers are files and directories that mark a workspace root.
+var rootMarkers = []string{
+	"go.work", "go.mod", "package.json", "tsconfig.json", "pyproject.toml",
+	"setup.py", "Cargo.toml", "compile_commands.json", ".git",
+}
+
+// FindRoot returns the workspace root of a file: the nearest directory
+// containing a root marker such as go.mod or .git, or the file's own
+// directory if there is none.
+func FindRoot(path string) string {
+	start := filepath.Dir(path)
+	for dir := start; ; {
+		for _, marker := range rootMarkers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return start
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,102 @@
+// Package session saves the open tabs and the expanded sidebar folders of a
+// working directory, so the next launch in that directory can restore them.
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/DDZ-DO/vex/internal/atomicfile"
+	"github.com/DDZ-DO/vex/internal/config"
+)
+
+// Position is a 0-based line and rune column.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Selection is a selected range, from the anchor to the cursor end.
+type Selection struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Tab is the view state of an open file.
+type Tab struct {
+	Path      string     `json:"path"` // Absolute file path
+	Cursor    Position   `json:"cursor"`
+	Selection *Selection `json:"selection,omitempty"`
+	ScrollX   int        `json:"scroll_x"`
+	ScrollY   int        `json:"scroll_y"`
+}
+
+// Session is the workspace state of one working directory.
+type Session struct {
+	Dir      string   `json:"dir"`    // Absolute working directory
+	Tabs     []Tab    `json:"tabs"`   // In tab bar order
+	Active   int      `json:"active"` // Index into Tabs
+	Expanded []string `json:"expanded,omitempty"`
+}
+
+// Path returns the session file of a working directory. The file is named
+// after a hash of the absolute directory path.
+func Path(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	sessionDir, err := config.SessionDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(sessionDir, hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// Load reads the session of a working directory. Returns nil without an
+// error if none was saved.
+func Load(dir string) (*Session, error) {
+	path, err := Path(dir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(dir); err == nil && s.Dir != abs {
+		// Hash collision; the file belongs to another directory
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// Save writes the session to the session file of s.Dir.
+func (s *Session) Save() error {
+	path, err := Path(s.Dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(path, append(data, '\n'), 0644)
+}
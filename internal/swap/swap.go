@@ -0,0 +1,157 @@
+// Package swap keeps copies of unsaved buffers on disk, so their changes
+// survive a crash and untitled buffers survive a quit.
+package swap
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/DDZ-DO/vex/internal/atomicfile"
+	"github.com/DDZ-DO/vex/internal/config"
+)
+
+// Interval is how often the swap files of modified buffers are updated.
+const Interval = 2 * time.Second
+
+// ext is the extension of swap files.
+const ext = ".swp"
+
+// File is the saved content of an unsaved buffer.
+type File struct {
+	ID string `json:"-"` // Name of the swap file without extension
+
+	Path    string    `json:"path,omitempty"` // Absolute file path, "" for untitled buffers
+	Dir     string    `json:"dir"`            // Working directory of the writing process
+	PID     int       `json:"pid"`            // Process that wrote the file
+	Process string    `json:"process"`        // Random ID of that process, as PIDs are reused
+	HotExit bool      `json:"hot_exit"`       // Kept on a normal quit to be reopened
+	Written time.Time `json:"written"`
+
+	// The file on disk the content is based on
+	BaseModTime time.Time `json:"base_mod_time,omitempty"`
+	BaseSize    int64     `json:"base_size,omitempty"`
+	BaseHash    string    `json:"base_hash,omitempty"`
+
+	Content string `json:"content"`
+}
+
+// process identifies this process in the swap files it writes. Unlike its
+// PID, it is not shared with a crashed process whose PID was reused.
+var process = NewID()
+
+// NewID returns a random swap file ID.
+func NewID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// path returns the path of the swap file with the given ID.
+func path(id string) (string, error) {
+	dir, err := config.SwapDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+ext), nil
+}
+
+// Write saves f, replacing its previous version. PID, Process and Written
+// are set to the current process and time.
+func Write(f *File) error {
+	p, err := path(f.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+
+	f.PID = os.Getpid()
+	f.Process = process
+	f.Written = time.Now()
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	return atomicfile.Write(p, data, 0600)
+}
+
+// Remove deletes the swap file with the given ID. A missing file is not an
+// error.
+func Remove(id string) error {
+	p, err := path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns all swap files. Unreadable files are skipped.
+func List() ([]*File, error) {
+	dir, err := config.SwapDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []*File
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		var f File
+		if err := json.Unmarshal(data, &f); err != nil {
+			continue
+		}
+		f.ID = strings.TrimSuffix(name, ext)
+		files = append(files, &f)
+	}
+	return files, nil
+}
+
+// Orphaned returns true if the process that wrote f is gone, so nobody
+// else is editing its buffer.
+func (f *File) Orphaned() bool {
+	if f.Process == process {
+		return false
+	}
+	if f.PID == os.Getpid() {
+		// Written by an earlier process with this PID
+		return true
+	}
+	process, err := os.FindProcess(f.PID)
+	if err != nil {
+		return true
+	}
+	// Signal 0 only checks that the process exists. Where signals are not
+	// supported it fails, and the file counts as orphaned.
+	return process.Signal(syscall.Signal(0)) != nil
+}
+
+// BaseChanged returns true if the file on disk is no longer the one the
+// content is based on. hash is the hex SHA-256 of the file on disk, or ""
+// if it does not exist.
+func (f *File) BaseChanged(hash string) bool {
+	return hash != f.BaseHash
+}
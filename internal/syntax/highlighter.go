@@ -79,6 +79,16 @@ func MonokaiTheme() *Theme {
 	}
 }
 
+// ThemeByName returns the theme with the given name, or the default theme.
+func ThemeByName(name string) *Theme {
+	switch strings.ToLower(name) {
+	case "monokai":
+		return MonokaiTheme()
+	default:
+		return DefaultTheme()
+	}
+}
+
 // NewHighlighter creates a new syntax highlighter for the given file path.
 func NewHighlighter(filepath string) *Highlighter {
 	h := &Highlighter{
@@ -0,0 +1,162 @@
+package syntax
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+// Symbol is a definition found in a file, such as a function or a heading.
+type Symbol struct {
+	Name   string
+	Kind   string // "function", "type" or "heading"
+	Line   int    // 0-based
+	Column int    // Rune column of the name
+}
+
+// controlWords start lines that call or use names instead of defining them.
+var controlWords = map[string]bool{
+	"return": true, "if": true, "else": true, "elif": true, "for": true,
+	"while": true, "do": true, "switch": true, "case": true, "go": true,
+	"defer": true, "await": true, "yield": true, "throw": true, "raise": true,
+	"new": true, "delete": true, "assert": true, "with": true, "not": true,
+	"typeof": true, "echo": true, "print": true, "lambda": true,
+}
+
+// functionWords and typeWords are keywords whose next name is defined.
+var (
+	functionWords = map[string]bool{
+		"func": true, "function": true, "def": true, "fn": true, "fun": true,
+		"sub": true, "proc": true,
+	}
+	typeWords = map[string]bool{
+		"type": true, "class": true, "struct": true, "enum": true, "trait": true,
+		"interface": true, "record": true, "module": true, "union": true,
+	}
+)
+
+// lineToken is a token of a line, without whitespace.
+type lineToken struct {
+	chroma.Token
+	column int
+}
+
+// Symbols returns the functions, types and headings defined in content,
+// as far as the tokens of the lexer tell. A line defines a symbol if it
+// starts with a keyword that is not a control word, such as "func" or
+// "public static", followed by a function or class name. Headings of
+// markup languages are symbols as well.
+func (h *Highlighter) Symbols(content string) []Symbol {
+	if h.lexer == nil || h.language == "plain" {
+		return nil
+	}
+	iterator, err := h.lexer.Tokenise(nil, content)
+	if err != nil {
+		return nil
+	}
+
+	var symbols []Symbol
+	var tokens []lineToken
+	line, column := 0, 0
+	endLine := func() {
+		if s, ok := lineSymbol(tokens); ok {
+			s.Line = line
+			symbols = append(symbols, s)
+		}
+		tokens = tokens[:0]
+		line++
+		column = 0
+	}
+
+	for token := iterator(); token != chroma.EOF; token = iterator() {
+		parts := strings.Split(token.Value, "\n")
+		for i, part := range parts {
+			if i > 0 {
+				endLine()
+			}
+			if token.Type == chroma.GenericHeading || token.Type == chroma.GenericSubheading {
+				if s, ok := headingSymbol(part); ok {
+					s.Line = line
+					symbols = append(symbols, s)
+				}
+			} else if strings.TrimSpace(part) != "" {
+				tokens = append(tokens, lineToken{
+					Token:  chroma.Token{Type: token.Type, Value: strings.TrimSpace(part)},
+					column: column + utf8.RuneCountInString(part) - utf8.RuneCountInString(strings.TrimLeft(part, " \t")),
+				})
+			}
+			column += utf8.RuneCountInString(part)
+		}
+	}
+	endLine()
+	return symbols
+}
+
+// lineSymbol returns the symbol defined by the tokens of a line.
+func lineSymbol(tokens []lineToken) (Symbol, bool) {
+	if len(tokens) == 0 || tokens[0].Type.Category() != chroma.Keyword || controlWords[tokens[0].Value] {
+		return Symbol{}, false
+	}
+
+	for i, t := range tokens {
+		if t.Type.Category() == chroma.Keyword {
+			switch {
+			case functionWords[t.Value]:
+				return nameAfter(tokens[i+1:], "function")
+			case typeWords[t.Value]:
+				return nameAfter(tokens[i+1:], "type")
+			}
+			continue
+		}
+
+		switch {
+		case t.Type == chroma.NameFunction:
+			return Symbol{Name: t.Value, Kind: "function", Column: t.column}, true
+		case t.Type == chroma.NameClass:
+			return Symbol{Name: t.Value, Kind: "type", Column: t.column}, true
+		case t.Type.Category() == chroma.Name:
+			// Return types, as in "public String name("
+		case t.Value == "*" || t.Value == "&" || t.Value == "<" || t.Value == ">" ||
+			t.Value == "," || t.Value == "[" || t.Value == "]" || t.Value == "::":
+		default:
+			return Symbol{}, false
+		}
+	}
+	return Symbol{}, false
+}
+
+// nameAfter returns the name following a "func" or "class" keyword. A Go
+// receiver or a generator "*" may come first.
+func nameAfter(tokens []lineToken, kind string) (Symbol, bool) {
+	if len(tokens) > 0 && tokens[0].Value == "(" {
+		// Skip the receiver
+		depth := 0
+		for i, t := range tokens {
+			depth += strings.Count(t.Value, "(") - strings.Count(t.Value, ")")
+			if depth <= 0 {
+				tokens = tokens[i+1:]
+				break
+			}
+		}
+	}
+	if len(tokens) > 0 && tokens[0].Value == "*" {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 || tokens[0].Type.Category() != chroma.Name {
+		return Symbol{}, false
+	}
+	return Symbol{Name: tokens[0].Value, Kind: kind, Column: tokens[0].column}, true
+}
+
+// headingSymbol returns the heading in a line of a heading token, such as
+// "## Usage". Setext underlines are not headings of their own.
+func headingSymbol(text string) (Symbol, bool) {
+	trimmed := strings.TrimSpace(text)
+	name := strings.TrimSpace(strings.Trim(trimmed, "#"))
+	if name == "" || strings.Trim(name, "=-") == "" {
+		return Symbol{}, false
+	}
+	column := utf8.RuneCountInString(text) - utf8.RuneCountInString(strings.TrimLeft(text, " \t#"))
+	return Symbol{Name: name, Kind: "heading", Column: column}, true
+}
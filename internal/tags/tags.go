@@ -0,0 +1,271 @@
+// Package tags reads tags files written by universal-ctags.
+package tags
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileNames are the names a tags file is looked for under in the workspace
+// root, in order.
+var FileNames = []string{"tags", ".tags"}
+
+// Tag is a definition listed in a tags file.
+type Tag struct {
+	Name    string
+	Path    string // Absolute path of the file defining the tag
+	Line    int    // 1-based, 0 if the tag is addressed by a pattern only
+	Pattern string // Start of the defining line, "" for line addresses
+	Kind    string // Such as "function" or "type"; may be empty
+	exact   bool   // Pattern is the whole line
+}
+
+// kindNames spells out the one-letter kinds ctags writes by default, as
+// they are named for C-like languages. "--fields=+K" writes long names.
+var kindNames = map[string]string{
+	"c": "class",
+	"d": "macro",
+	"e": "enumerator",
+	"f": "function",
+	"g": "enum",
+	"i": "interface",
+	"m": "member",
+	"n": "namespace",
+	"s": "struct",
+	"t": "type",
+	"u": "union",
+	"v": "variable",
+}
+
+// FindLine returns the 0-based line of the tag in the given lines of its
+// file, or -1 if its pattern no longer matches.
+func (t Tag) FindLine(lines []string) int {
+	if t.Line > 0 {
+		return min(t.Line-1, max(len(lines)-1, 0))
+	}
+	for i, line := range lines {
+		if line == t.Pattern || !t.exact && strings.HasPrefix(line, t.Pattern) {
+			return i
+		}
+	}
+	return -1
+}
+
+// File is a loaded tags file.
+type File struct {
+	path    string
+	modTime time.Time
+	tags    []Tag
+	byName  map[string][]int
+	byPath  map[string][]int
+}
+
+// Load reads a tags file. Lines that are not tags, such as the "!_TAG_"
+// header, are skipped.
+func Load(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{
+		path:    path,
+		modTime: info.ModTime(),
+		byName:  make(map[string][]int),
+		byPath:  make(map[string][]int),
+	}
+	dir := filepath.Dir(path)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		tag, ok := parseLine(strings.TrimRight(scanner.Text(), "\r"), dir)
+		if !ok {
+			continue
+		}
+		f.byName[tag.Name] = append(f.byName[tag.Name], len(f.tags))
+		f.byPath[tag.Path] = append(f.byPath[tag.Path], len(f.tags))
+		f.tags = append(f.tags, tag)
+	}
+	return f, scanner.Err()
+}
+
+// Lookup returns the tags with the given name.
+func (f *File) Lookup(name string) []Tag {
+	return f.collect(f.byName[name])
+}
+
+// InFile returns the tags defined in a file, given by absolute path, in
+// the order of the tags file.
+func (f *File) InFile(path string) []Tag {
+	return f.collect(f.byPath[path])
+}
+
+// collect returns the tags at the given indexes.
+func (f *File) collect(indexes []int) []Tag {
+	tags := make([]Tag, len(indexes))
+	for i, idx := range indexes {
+		tags[i] = f.tags[idx]
+	}
+	return tags
+}
+
+// parseLine parses one line of the extended tags format:
+//
+//	name<TAB>file<TAB>address;"<TAB>kind<TAB>line:12
+//
+// The address is a line number or a /pattern/ or ?pattern?. Relative
+// file names are resolved against dir, the tags file's directory.
+func parseLine(line, dir string) (Tag, bool) {
+	if line == "" || strings.HasPrefix(line, "!_TAG_") {
+		return Tag{}, false
+	}
+	name, rest, ok := strings.Cut(line, "\t")
+	if !ok {
+		return Tag{}, false
+	}
+	path, rest, ok := strings.Cut(rest, "\t")
+	if !ok || name == "" || path == "" {
+		return Tag{}, false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	tag := Tag{Name: name, Path: filepath.Clean(path)}
+
+	address, fields := splitAddress(rest)
+	if n, err := strconv.Atoi(address); err == nil {
+		tag.Line = n
+	} else if len(address) >= 2 && (address[0] == '/' || address[0] == '?') {
+		tag.Pattern, tag.exact = parsePattern(address[1 : len(address)-1])
+	} else {
+		return Tag{}, false
+	}
+
+	for _, field := range fields {
+		key, value, hasKey := strings.Cut(field, ":")
+		switch {
+		case !hasKey:
+			// A bare field is the kind
+			tag.Kind = kindName(field)
+		case key == "kind":
+			tag.Kind = kindName(value)
+		case key == "line":
+			if n, err := strconv.Atoi(value); err == nil {
+				tag.Line = n
+			}
+		}
+	}
+	return tag, true
+}
+
+// splitAddress splits the address of a tag line from its extension
+// fields. Patterns may contain tabs and ";", so their closing delimiter is
+// searched for first.
+func splitAddress(rest string) (string, []string) {
+	end := len(rest)
+	if rest != "" && (rest[0] == '/' || rest[0] == '?') {
+		delim := rest[0]
+		for i := 1; i < len(rest); i++ {
+			if rest[i] == '\\' {
+				i++
+				continue
+			}
+			if rest[i] == delim {
+				end = i + 1
+				break
+			}
+		}
+	} else if i := strings.IndexAny(rest, ";\t"); i >= 0 {
+		end = i
+	}
+
+	address := rest[:end]
+	rest = strings.TrimPrefix(rest[end:], `;"`)
+	var fields []string
+	for _, field := range strings.Split(rest, "\t") {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return address, fields
+}
+
+// parsePattern unescapes a search pattern such as "^func main() {$". exact
+// reports whether it is anchored at both ends; ctags drops the "$" of
+// patterns it truncated.
+func parsePattern(pattern string) (text string, exact bool) {
+	pattern = strings.TrimPrefix(pattern, "^")
+	if strings.HasSuffix(pattern, "$") && !strings.HasSuffix(pattern, `\$`) {
+		pattern = pattern[:len(pattern)-1]
+		exact = true
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		sb.WriteByte(pattern[i])
+	}
+	return sb.String(), exact
+}
+
+// kindName returns the long name of a kind.
+func kindName(kind string) string {
+	if name, ok := kindNames[kind]; ok {
+		return name
+	}
+	return kind
+}
+
+// Cache keeps the tags file of a workspace loaded and reloads it when it
+// changes on disk.
+type Cache struct {
+	file *File
+}
+
+// Get returns the tags file of a workspace root, or nil if it has none.
+func (c *Cache) Get(root string) (*File, error) {
+	for _, name := range FileNames {
+		path := filepath.Join(root, name)
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if c.file != nil && c.file.path == path && c.file.modTime.Equal(info.ModTime()) {
+			return c.file, nil
+		}
+		file, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		c.file = file
+		return file, nil
+	}
+	c.file = nil
+	return nil, nil
+}
+
+// Sorted returns tags ordered by file and line, with the tags addressed by
+// pattern last.
+func Sorted(tags []Tag) []Tag {
+	sorted := append([]Tag(nil), tags...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Line < sorted[j].Line
+	})
+	return sorted
+}
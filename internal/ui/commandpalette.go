@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -16,7 +18,40 @@ type Command struct {
 	Description string
 }
 
-// CommandPalette provides a fuzzy-searchable command palette.
+// FileMatch is a file offered by quick open.
+type FileMatch struct {
+	Path    string // Slash-separated, relative to the workspace root
+	Line    int    // Line given as "path:line" in the query, 0 if none
+	Indexes []int  // Byte indexes of the characters matching the query
+}
+
+// SymbolItem is a symbol of the active file offered by the "@" mode.
+type SymbolItem struct {
+	Name   string
+	Kind   string
+	Line   int // 0-based
+	Column int
+}
+
+// symbolMatch is a symbol matching the query.
+type symbolMatch struct {
+	symbol  SymbolItem
+	indexes []int // Byte indexes of the name matching the query
+}
+
+// CommandPrefix switches quick open to the command palette.
+const CommandPrefix = ">"
+
+// SymbolPrefix switches quick open to the symbols of the active file.
+const SymbolPrefix = "@"
+
+// recentFileBonus is added to the fuzzy score of the most recently active
+// tab; older tabs get less.
+const recentFileBonus = 25
+
+// CommandPalette provides fuzzy-searchable commands and, without the ">"
+// prefix, quick open for the files of the workspace. The "@" prefix lists
+// the symbols of the active file.
 type CommandPalette struct {
 	visible      bool
 	input        string
@@ -26,6 +61,17 @@ type CommandPalette struct {
 	selected     int
 	scrollOffset int
 
+	// Quick open
+	files         []string       // Indexed files, relative to fileRoot
+	fileRoot      string         // Workspace root the files belong to
+	indexing      bool           // The file index is being rebuilt
+	recent        map[string]int // Recency rank of open files (0 = active)
+	filteredFiles []FileMatch
+
+	// Symbols of the active file
+	symbols         []SymbolItem
+	filteredSymbols []symbolMatch
+
 	width  int
 	height int
 
@@ -36,6 +82,7 @@ type CommandPalette struct {
 	selectedStyle lipgloss.Style
 	keybindStyle  lipgloss.Style
 	categoryStyle lipgloss.Style
+	matchStyle    lipgloss.Style
 }
 
 // NewCommandPalette creates a new command palette.
@@ -62,6 +109,9 @@ func NewCommandPalette() *CommandPalette {
 			Foreground(lipgloss.Color("241")),
 		categoryStyle: lipgloss.NewStyle().
 			Foreground(lipgloss.Color("109")),
+		matchStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Bold(true),
 	}
 }
 
@@ -73,34 +123,58 @@ func defaultCommands() []Command {
 		{ID: "file.saveAs", Label: "Save As...", Category: "File", Keybinding: "Ctrl+Shift+S"},
 		{ID: "file.new", Label: "New File", Category: "File", Keybinding: "Ctrl+N"},
 		{ID: "file.open", Label: "Open File", Category: "File", Keybinding: "Ctrl+O"},
+		{ID: "file.quickOpen", Label: "Go to File", Category: "File", Keybinding: "Ctrl+P"},
 		{ID: "file.close", Label: "Close File", Category: "File", Keybinding: "Ctrl+W"},
+		{ID: "file.saveAll", Label: "Save All", Category: "File", Keybinding: "F6"},
+		{ID: "file.reloadFromDisk", Label: "Reload File from Disk", Category: "File", Keybinding: "Alt+R"},
+		{ID: "file.compareWithDisk", Label: "Compare File with Disk", Category: "File", Keybinding: "Alt+C"},
 
 		// Edit operations
 		{ID: "edit.undo", Label: "Undo", Category: "Edit", Keybinding: "Ctrl+Z"},
 		{ID: "edit.redo", Label: "Redo", Category: "Edit", Keybinding: "Ctrl+Y"},
+		{ID: "edit.undoOlder", Label: "Go to Older Undo State", Category: "Edit", Keybinding: "Alt+Z"},
+		{ID: "edit.undoNewer", Label: "Go to Newer Undo State", Category: "Edit", Keybinding: "Alt+Y"},
 		{ID: "edit.cut", Label: "Cut", Category: "Edit", Keybinding: "Ctrl+X"},
 		{ID: "edit.copy", Label: "Copy", Category: "Edit", Keybinding: "Ctrl+C"},
 		{ID: "edit.paste", Label: "Paste", Category: "Edit", Keybinding: "Ctrl+V"},
 		{ID: "edit.selectAll", Label: "Select All", Category: "Edit", Keybinding: "Ctrl+A"},
+		{ID: "select.line", Label: "Select Line", Category: "Edit", Keybinding: "Ctrl+K Ctrl+L"},
+		{ID: "select.addCursorAbove", Label: "Add Cursor Above", Category: "Selection", Keybinding: "Ctrl+Alt+Up"},
+		{ID: "select.addCursorBelow", Label: "Add Cursor Below", Category: "Selection", Keybinding: "Ctrl+Alt+Down"},
+		{ID: "select.addNextOccurrence", Label: "Add Selection To Next Find Match", Category: "Selection", Keybinding: "Alt+D"},
+		{ID: "select.allOccurrences", Label: "Select All Occurrences", Category: "Selection", Keybinding: "Alt+Shift+D"},
 		{ID: "edit.duplicateLine", Label: "Duplicate Line", Category: "Edit", Keybinding: "Ctrl+D"},
 		{ID: "edit.deleteLine", Label: "Delete Line", Category: "Edit", Keybinding: "Ctrl+L"},
 		{ID: "edit.moveLineUp", Label: "Move Line Up", Category: "Edit", Keybinding: "Alt+Up"},
 		{ID: "edit.moveLineDown", Label: "Move Line Down", Category: "Edit", Keybinding: "Alt+Down"},
+		{ID: "edit.indentLines", Label: "Indent Lines", Category: "Edit"},
+		{ID: "edit.outdentLines", Label: "Outdent Lines", Category: "Edit", Keybinding: "Shift+Tab"},
+		{ID: "edit.triggerSuggest", Label: "Trigger Suggest", Category: "Edit", Keybinding: "Ctrl+Space"},
 
 		// Search operations
 		{ID: "search.find", Label: "Find", Category: "Search", Keybinding: "Ctrl+F"},
 		{ID: "search.replace", Label: "Find and Replace", Category: "Search", Keybinding: "Ctrl+H"},
 		{ID: "search.findNext", Label: "Find Next", Category: "Search", Keybinding: "F3"},
 		{ID: "search.findPrevious", Label: "Find Previous", Category: "Search", Keybinding: "Shift+F3"},
+		{ID: "search.findInFiles", Label: "Find in Files", Category: "Search", Keybinding: "Alt+F"},
+		{ID: "search.replaceInFiles", Label: "Replace in Files", Category: "Search", Keybinding: "Alt+H"},
 
 		// Navigation
 		{ID: "nav.goToLine", Label: "Go to Line", Category: "Go", Keybinding: "Ctrl+G"},
-		{ID: "nav.goToStart", Label: "Go to Start", Category: "Go", Keybinding: "Ctrl+Home"},
-		{ID: "nav.goToEnd", Label: "Go to End", Category: "Go", Keybinding: "Ctrl+End"},
+		{ID: "nav.goToDefinition", Label: "Go to Definition", Category: "Go", Keybinding: "F12"},
+		{ID: "nav.findReferences", Label: "Find References", Category: "Go", Keybinding: "Shift+F12"},
+		{ID: "nav.goToSymbol", Label: "Go to Symbol in File", Category: "Go", Keybinding: "Ctrl+R"},
+		{ID: "nav.goBack", Label: "Go Back", Category: "Go", Keybinding: "Alt+Left"},
+		{ID: "nav.goForward", Label: "Go Forward", Category: "Go", Keybinding: "Alt+Right"},
+		{ID: "nav.moveBufferStart", Label: "Go to Start", Category: "Go", Keybinding: "Ctrl+Home"},
+		{ID: "nav.moveBufferEnd", Label: "Go to End", Category: "Go", Keybinding: "Ctrl+End"},
 
 		// View
 		{ID: "view.toggleSidebar", Label: "Toggle Sidebar", Category: "View", Keybinding: "Ctrl+B"},
-		{ID: "view.commandPalette", Label: "Command Palette", Category: "View", Keybinding: "Ctrl+P"},
+		{ID: "view.commandPalette", Label: "Command Palette", Category: "View", Keybinding: "F1"},
+		{ID: "view.showHover", Label: "Show Hover", Category: "View", Keybinding: "Ctrl+K I"},
+		{ID: "view.problems", Label: "Show Problems", Category: "View", Keybinding: "Ctrl+K M"},
+		{ID: "view.undoTree", Label: "Show Undo Tree", Category: "View", Keybinding: "Ctrl+K U"},
 
 		// Application
 		{ID: "app.quit", Label: "Quit", Category: "Application", Keybinding: "Ctrl+Q"},
@@ -119,6 +193,15 @@ func (cp *CommandPalette) AddCommand(cmd Command) {
 	cp.updateFilter()
 }
 
+// UpdateKeybindings refreshes the displayed keybinding of every command.
+// lookup returns the binding for a command ID, or "" if it has none.
+func (cp *CommandPalette) UpdateKeybindings(lookup func(id string) string) {
+	for i := range cp.commands {
+		cp.commands[i].Keybinding = lookup(cp.commands[i].ID)
+	}
+	cp.updateFilter()
+}
+
 // SetSize sets the palette dimensions.
 func (cp *CommandPalette) SetSize(width, height int) {
 	cp.width = width
@@ -127,14 +210,97 @@ func (cp *CommandPalette) SetSize(width, height int) {
 
 // Show shows the command palette.
 func (cp *CommandPalette) Show() {
+	cp.show(CommandPrefix)
+}
+
+// ShowQuickOpen shows quick open for the files below root. The previous
+// index is kept for the same root until SetFiles delivers a new one.
+func (cp *CommandPalette) ShowQuickOpen(root string) {
+	if root != cp.fileRoot {
+		cp.fileRoot = root
+		cp.files = nil
+	}
+	cp.indexing = true
+	cp.show("")
+}
+
+// ShowSymbols shows the symbols of the active file.
+func (cp *CommandPalette) ShowSymbols() {
+	cp.show(SymbolPrefix)
+}
+
+// SetSymbols sets the symbols of the active file, ordered by line.
+func (cp *CommandPalette) SetSymbols(symbols []SymbolItem) {
+	cp.symbols = symbols
+	cp.updateFilter()
+}
+
+// show opens the palette with the given input.
+func (cp *CommandPalette) show(input string) {
 	cp.visible = true
-	cp.input = ""
-	cp.cursorPos = 0
+	cp.input = input
+	cp.cursorPos = len(input)
 	cp.selected = 0
 	cp.scrollOffset = 0
 	cp.updateFilter()
 }
 
+// SetFiles sets the file index for quick open. An index for a root other
+// than the one quick open was last shown for is ignored.
+func (cp *CommandPalette) SetFiles(root string, files []string) {
+	if root != cp.fileRoot {
+		return
+	}
+	cp.files = files
+	cp.indexing = false
+	cp.updateFilter()
+	cp.selected = min(cp.selected, max(cp.itemCount()-1, 0))
+	cp.ensureVisible()
+}
+
+// FileRoot returns the workspace root of the quick open file index.
+func (cp *CommandPalette) FileRoot() string {
+	return cp.fileRoot
+}
+
+// SetRecentFiles sets the open files, most recently active first, so quick
+// open ranks them higher. Paths are relative to the workspace root.
+func (cp *CommandPalette) SetRecentFiles(paths []string) {
+	cp.recent = make(map[string]int, len(paths))
+	for i, path := range paths {
+		if _, ok := cp.recent[path]; !ok {
+			cp.recent[path] = i
+		}
+	}
+}
+
+// IsFileMode returns true if the palette lists files rather than commands.
+func (cp *CommandPalette) IsFileMode() bool {
+	return !cp.isCommandMode() && !cp.IsSymbolMode()
+}
+
+// IsSymbolMode returns true if the palette lists the symbols of the active
+// file.
+func (cp *CommandPalette) IsSymbolMode() bool {
+	return strings.HasPrefix(cp.input, SymbolPrefix)
+}
+
+// isCommandMode returns true if the palette lists commands.
+func (cp *CommandPalette) isCommandMode() bool {
+	return strings.HasPrefix(cp.input, CommandPrefix)
+}
+
+// itemCount returns the number of listed files, symbols or commands.
+func (cp *CommandPalette) itemCount() int {
+	switch {
+	case cp.IsFileMode():
+		return len(cp.filteredFiles)
+	case cp.IsSymbolMode():
+		return len(cp.filteredSymbols)
+	}
+	return len(cp.filtered)
+}
+
 // Hide hides the command palette.
 func (cp *CommandPalette) Hide() {
 	cp.visible = false
@@ -206,7 +372,7 @@ func (cp *CommandPalette) MoveUp() {
 
 // MoveDown moves selection down.
 func (cp *CommandPalette) MoveDown() {
-	if cp.selected < len(cp.filtered)-1 {
+	if cp.selected < cp.itemCount()-1 {
 		cp.selected++
 		cp.ensureVisible()
 	}
@@ -241,7 +407,7 @@ func (cp *CommandPalette) maxVisibleItems() int {
 
 // Select returns the selected command and hides the palette.
 func (cp *CommandPalette) Select() *Command {
-	if cp.selected >= len(cp.filtered) {
+	if !cp.isCommandMode() || cp.selected >= len(cp.filtered) {
 		return nil
 	}
 	cmd := cp.filtered[cp.selected]
@@ -249,17 +415,48 @@ func (cp *CommandPalette) Select() *Command {
 	return &cmd
 }
 
+// SelectFile returns the selected file in quick open and hides the palette.
+func (cp *CommandPalette) SelectFile() (FileMatch, bool) {
+	if !cp.IsFileMode() || cp.selected >= len(cp.filteredFiles) {
+		return FileMatch{}, false
+	}
+	file := cp.filteredFiles[cp.selected]
+	cp.Hide()
+	return file, true
+}
+
+// SelectSymbol returns the selected symbol and hides the palette.
+func (cp *CommandPalette) SelectSymbol() (SymbolItem, bool) {
+	if !cp.IsSymbolMode() || cp.selected >= len(cp.filteredSymbols) {
+		return SymbolItem{}, false
+	}
+	symbol := cp.filteredSymbols[cp.selected].symbol
+	cp.Hide()
+	return symbol, true
+}
+
 // GetSelectedCommand returns the currently selected command without hiding.
 func (cp *CommandPalette) GetSelectedCommand() *Command {
-	if cp.selected >= len(cp.filtered) {
+	if !cp.isCommandMode() || cp.selected >= len(cp.filtered) {
 		return nil
 	}
 	return &cp.filtered[cp.selected]
 }
 
-// updateFilter updates the filtered command list based on input.
+// updateFilter updates the filtered command, file or symbol list based on
+// input.
 func (cp *CommandPalette) updateFilter() {
-	if cp.input == "" {
+	switch {
+	case cp.IsFileMode():
+		cp.updateFileFilter()
+		return
+	case cp.IsSymbolMode():
+		cp.updateSymbolFilter()
+		return
+	}
+
+	query := strings.TrimSpace(strings.TrimPrefix(cp.input, CommandPrefix))
+	if query == "" {
 		cp.filtered = cp.commands
 		return
 	}
@@ -270,13 +467,122 @@ func (cp *CommandPalette) updateFilter() {
 		labels = append(labels, cmd.Label+" "+cmd.Category)
 	}
 
-	matches := fuzzy.Find(cp.input, labels)
+	matches := fuzzy.Find(query, labels)
 	cp.filtered = make([]Command, len(matches))
 	for i, match := range matches {
 		cp.filtered[i] = cp.commands[match.Index]
 	}
 }
 
+// updateFileFilter ranks the indexed files by fuzzy score, with a bonus for
+// recently active tabs. Without a query the open files come first.
+func (cp *CommandPalette) updateFileFilter() {
+	query, line := parseFileQuery(strings.TrimSpace(cp.input))
+	cp.filteredFiles = cp.filteredFiles[:0]
+
+	if query == "" {
+		for _, path := range cp.files {
+			cp.filteredFiles = append(cp.filteredFiles, FileMatch{Path: path, Line: line})
+		}
+		sort.SliceStable(cp.filteredFiles, func(i, j int) bool {
+			return cp.recentRank(cp.filteredFiles[i].Path) < cp.recentRank(cp.filteredFiles[j].Path)
+		})
+		return
+	}
+
+	matches := fuzzy.Find(query, cp.files)
+
+	// A query matching the file name alone scores that match, so "tab"
+	// ranks tabbar.go above internal/editor/buffer.go
+	if !strings.Contains(query, "/") {
+		names := make([]string, len(cp.files))
+		for i, path := range cp.files {
+			names[i] = path[strings.LastIndex(path, "/")+1:]
+		}
+		byIndex := make(map[int]fuzzy.Match)
+		for _, match := range fuzzy.Find(query, names) {
+			byIndex[match.Index] = match
+		}
+		for i := range matches {
+			name, ok := byIndex[matches[i].Index]
+			if !ok {
+				continue
+			}
+			offset := len(matches[i].Str) - len(name.Str)
+			matches[i].Score = name.Score
+			matches[i].MatchedIndexes = make([]int, len(name.MatchedIndexes))
+			for j, idx := range name.MatchedIndexes {
+				matches[i].MatchedIndexes[j] = idx + offset
+			}
+		}
+	}
+
+	for i := range matches {
+		if rank, ok := cp.recent[matches[i].Str]; ok {
+			matches[i].Score += max(recentFileBonus-5*rank, 5)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	for _, match := range matches {
+		cp.filteredFiles = append(cp.filteredFiles, FileMatch{
+			Path:    match.Str,
+			Line:    line,
+			Indexes: match.MatchedIndexes,
+		})
+	}
+}
+
+// updateSymbolFilter ranks the symbols by fuzzy score. Without a query
+// they stay in the order of the file.
+func (cp *CommandPalette) updateSymbolFilter() {
+	query := strings.TrimSpace(strings.TrimPrefix(cp.input, SymbolPrefix))
+	cp.filteredSymbols = cp.filteredSymbols[:0]
+
+	if query == "" {
+		for _, symbol := range cp.symbols {
+			cp.filteredSymbols = append(cp.filteredSymbols, symbolMatch{symbol: symbol})
+		}
+		return
+	}
+
+	names := make([]string, len(cp.symbols))
+	for i, symbol := range cp.symbols {
+		names[i] = symbol.Name
+	}
+	for _, match := range fuzzy.Find(query, names) {
+		cp.filteredSymbols = append(cp.filteredSymbols, symbolMatch{
+			symbol:  cp.symbols[match.Index],
+			indexes: match.MatchedIndexes,
+		})
+	}
+}
+
+// recentRank returns the recency rank of a file, or len(recent) for files
+// that are not open.
+func (cp *CommandPalette) recentRank(path string) int {
+	if rank, ok := cp.recent[path]; ok {
+		return rank
+	}
+	return len(cp.recent)
+}
+
+// parseFileQuery splits a "path:line" query like the command line does.
+// A trailing colon that is still being typed is dropped.
+func parseFileQuery(query string) (string, int) {
+	if idx := strings.LastIndex(query, ":"); idx > 0 {
+		lineStr := query[idx+1:]
+		if lineStr == "" {
+			return query[:idx], 0
+		}
+		if line, err := strconv.Atoi(lineStr); err == nil && line > 0 {
+			return query[:idx], line
+		}
+	}
+	return query, 0
+}
+
 // View renders the command palette.
 func (cp *CommandPalette) View() string {
 	if !cp.visible {
@@ -295,7 +601,10 @@ func (cp *CommandPalette) View() string {
 	} else {
 		inputLine += "|"
 	}
-	prompt := " > " + inputLine
+	prompt := " " + inputLine
+	if cp.input == "" {
+		prompt += " Go to file (> commands, @ symbols)"
+	}
 	// Pad to exact width
 	promptPadding := contentWidth - lipgloss.Width(prompt)
 	if promptPadding > 0 {
@@ -311,22 +620,36 @@ func (cp *CommandPalette) View() string {
 	separator := strings.Repeat("─", contentWidth)
 	lines = append(lines, separator)
 
-	// Command list
+	// Command or file list
+	count := cp.itemCount()
 	maxVisible := cp.maxVisibleItems()
 	endIdx := cp.scrollOffset + maxVisible
-	if endIdx > len(cp.filtered) {
-		endIdx = len(cp.filtered)
+	if endIdx > count {
+		endIdx = count
 	}
 
 	for i := cp.scrollOffset; i < endIdx; i++ {
-		cmd := cp.filtered[i]
-		line := cp.renderCommandLine(cmd, contentWidth, i == cp.selected)
+		var line string
+		switch {
+		case cp.IsFileMode():
+			line = cp.renderFileLine(cp.filteredFiles[i], contentWidth, i == cp.selected)
+		case cp.IsSymbolMode():
+			line = cp.renderSymbolLine(cp.filteredSymbols[i], contentWidth, i == cp.selected)
+		default:
+			line = cp.renderCommandLine(cp.filtered[i], contentWidth, i == cp.selected)
+		}
 		lines = append(lines, line)
 	}
 
 	// Show "no results" if empty
-	if len(cp.filtered) == 0 {
-		noResults := " Keine Treffer" + strings.Repeat(" ", contentWidth-14)
+	if count == 0 {
+		noResults := " Keine Treffer"
+		if cp.IsFileMode() && cp.indexing {
+			noResults = " Dateien werden indiziert..."
+		} else if cp.IsSymbolMode() && len(cp.symbols) == 0 {
+			noResults = " Keine Symbole"
+		}
+		noResults += strings.Repeat(" ", contentWidth-lipgloss.Width(noResults))
 		lines = append(lines, noResults)
 	}
 
@@ -335,7 +658,7 @@ func (cp *CommandPalette) View() string {
 		up := " ↑ mehr" + strings.Repeat(" ", contentWidth-7)
 		lines = append([]string{up}, lines...)
 	}
-	if endIdx < len(cp.filtered) {
+	if endIdx < count {
 		down := " ↓ mehr" + strings.Repeat(" ", contentWidth-7)
 		lines = append(lines, down)
 	}
@@ -374,11 +697,115 @@ func (cp *CommandPalette) renderCommandLine(cmd Command, width int, selected boo
 		Render(line)
 }
 
-// HandleClick handles a click at the given position.
+// renderFileLine renders a single file of quick open, highlighting the
+// characters that match the query.
+func (cp *CommandPalette) renderFileLine(file FileMatch, width int, selected bool) string {
+	base := lipgloss.NewStyle().
+		Background(lipgloss.Color("237")).
+		Foreground(lipgloss.Color("252"))
+	if selected {
+		base = lipgloss.NewStyle().
+			Background(lipgloss.Color("62")).
+			Foreground(lipgloss.Color("230"))
+	}
+	match := cp.matchStyle.Inherit(base)
+
+	suffix := ""
+	if file.Line > 0 {
+		suffix = ":" + strconv.Itoa(file.Line)
+	}
+
+	matched := make(map[int]bool, len(file.Indexes))
+	for _, idx := range file.Indexes {
+		matched[idx] = true
+	}
+
+	// Long paths lose their leading directories
+	type char struct {
+		r       rune
+		width   int // Display cells
+		matched bool
+	}
+	var chars []char
+	pathWidth := 0
+	for i, r := range file.Path {
+		w := lipgloss.Width(string(r))
+		chars = append(chars, char{r, w, matched[i]})
+		pathWidth += w
+	}
+	innerWidth := max(width-2-lipgloss.Width(suffix), 1)
+	prefix := ""
+	if pathWidth > innerWidth && innerWidth > 1 {
+		prefix = "…"
+		for len(chars) > 0 && pathWidth > innerWidth-lipgloss.Width(prefix) {
+			pathWidth -= chars[0].width
+			chars = chars[1:]
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(base.Render(" " + prefix))
+	for _, c := range chars {
+		if c.matched {
+			sb.WriteString(match.Render(string(c.r)))
+		} else {
+			sb.WriteString(base.Render(string(c.r)))
+		}
+	}
+	padding := innerWidth - pathWidth - lipgloss.Width(prefix)
+	sb.WriteString(base.Render(strings.Repeat(" ", max(padding, 0)) + suffix + " "))
+	return sb.String()
+}
+
+// renderSymbolLine renders a single symbol with its kind and line number,
+// highlighting the characters that match the query.
+func (cp *CommandPalette) renderSymbolLine(m symbolMatch, width int, selected bool) string {
+	base := lipgloss.NewStyle().
+		Background(lipgloss.Color("237")).
+		Foreground(lipgloss.Color("252"))
+	if selected {
+		base = lipgloss.NewStyle().
+			Background(lipgloss.Color("62")).
+			Foreground(lipgloss.Color("230"))
+	}
+	match := cp.matchStyle.Inherit(base)
+	detail := cp.keybindStyle.Inherit(base)
+
+	info := strconv.Itoa(m.symbol.Line + 1)
+	if m.symbol.Kind != "" {
+		info = m.symbol.Kind + " " + info
+	}
+	innerWidth := width - 2
+	name := truncate(m.symbol.Name, max(innerWidth-lipgloss.Width(info)-1, 1))
+
+	matched := make(map[int]bool, len(m.indexes))
+	for _, idx := range m.indexes {
+		matched[idx] = true
+	}
+
+	var sb strings.Builder
+	sb.WriteString(base.Render(" "))
+	for i, r := range name {
+		if matched[i] {
+			sb.WriteString(match.Render(string(r)))
+		} else {
+			sb.WriteString(base.Render(string(r)))
+		}
+	}
+	padding := innerWidth - lipgloss.Width(name) - lipgloss.Width(info)
+	sb.WriteString(base.Render(strings.Repeat(" ", max(padding, 1))))
+	sb.WriteString(detail.Render(info))
+	sb.WriteString(base.Render(" "))
+	return sb.String()
+}
+
+// HandleClick handles a click at the given position. In quick open and the
+// symbol list the clicked entry is selected and nil is returned; use
+// SelectFile or SelectSymbol to open it.
 func (cp *CommandPalette) HandleClick(y int) *Command {
 	// Account for input line and separator
 	idx := cp.scrollOffset + y - 2
-	if idx >= 0 && idx < len(cp.filtered) {
+	if idx >= 0 && idx < cp.itemCount() {
 		cp.selected = idx
 		return cp.Select()
 	}
@@ -0,0 +1,236 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sahilm/fuzzy"
+)
+
+// CompletionItem is a candidate shown in the completion popup.
+type CompletionItem struct {
+	Label  string
+	Detail string
+	Text   string // Inserted when accepted
+}
+
+// completionMatch is an item matching the query.
+type completionMatch struct {
+	item    CompletionItem
+	indexes []int // Byte indexes of the label matching the query
+}
+
+const (
+	completionMaxItems = 8
+	completionMaxWidth = 50
+)
+
+// CompletionPopup lists completion candidates at the cursor, filtered by
+// the word typed so far.
+type CompletionPopup struct {
+	visible      bool
+	items        []CompletionItem
+	query        string
+	filtered     []completionMatch
+	selected     int
+	scrollOffset int
+
+	// Styles
+	popupStyle    lipgloss.Style
+	itemStyle     lipgloss.Style
+	selectedStyle lipgloss.Style
+	detailStyle   lipgloss.Style
+	matchStyle    lipgloss.Style
+}
+
+// NewCompletionPopup creates a new completion popup.
+func NewCompletionPopup() *CompletionPopup {
+	return &CompletionPopup{
+		popupStyle: lipgloss.NewStyle().
+			Background(lipgloss.Color("236")).
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(lipgloss.Color("62")),
+		itemStyle: lipgloss.NewStyle().
+			Background(lipgloss.Color("236")).
+			Foreground(lipgloss.Color("252")),
+		selectedStyle: lipgloss.NewStyle().
+			Background(lipgloss.Color("62")).
+			Foreground(lipgloss.Color("230")),
+		detailStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")),
+		matchStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Bold(true),
+	}
+}
+
+// Show shows the popup.
+func (cp *CompletionPopup) Show() {
+	cp.visible = true
+}
+
+// Hide hides the popup and drops its items.
+func (cp *CompletionPopup) Hide() {
+	cp.visible = false
+	cp.items = nil
+	cp.filtered = nil
+	cp.query = ""
+}
+
+// IsVisible returns whether the popup is visible.
+func (cp *CompletionPopup) IsVisible() bool {
+	return cp.visible
+}
+
+// SetItems sets the candidates and filters them by query, the word typed
+// so far. The first match is selected.
+func (cp *CompletionPopup) SetItems(items []CompletionItem, query string) {
+	cp.items = items
+	cp.query = query
+	cp.filter()
+}
+
+// Count returns the number of candidates matching the query.
+func (cp *CompletionPopup) Count() int {
+	return len(cp.filtered)
+}
+
+// Selected returns the selected candidate.
+func (cp *CompletionPopup) Selected() (CompletionItem, bool) {
+	if cp.selected < 0 || cp.selected >= len(cp.filtered) {
+		return CompletionItem{}, false
+	}
+	return cp.filtered[cp.selected].item, true
+}
+
+// MoveUp selects the previous candidate, wrapping around.
+func (cp *CompletionPopup) MoveUp() {
+	if len(cp.filtered) == 0 {
+		return
+	}
+	cp.selected = (cp.selected - 1 + len(cp.filtered)) % len(cp.filtered)
+	cp.ensureVisible()
+}
+
+// MoveDown selects the next candidate, wrapping around.
+func (cp *CompletionPopup) MoveDown() {
+	if len(cp.filtered) == 0 {
+		return
+	}
+	cp.selected = (cp.selected + 1) % len(cp.filtered)
+	cp.ensureVisible()
+}
+
+// Height returns the number of lines the popup takes, including its
+// border.
+func (cp *CompletionPopup) Height() int {
+	return min(len(cp.filtered), completionMaxItems) + 2
+}
+
+// filter matches the candidates against the query. Without a query they
+// are sorted by label; with one by score, shorter labels first on ties.
+func (cp *CompletionPopup) filter() {
+	sorted := make([]CompletionItem, len(cp.items))
+	copy(sorted, cp.items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Label, sorted[j].Label
+		if cp.query != "" && len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+
+	cp.filtered = cp.filtered[:0]
+	if cp.query == "" {
+		for _, item := range sorted {
+			cp.filtered = append(cp.filtered, completionMatch{item: item})
+		}
+	} else {
+		labels := make([]string, len(sorted))
+		for i, item := range sorted {
+			labels[i] = item.Label
+		}
+		for _, m := range fuzzy.Find(cp.query, labels) {
+			cp.filtered = append(cp.filtered, completionMatch{item: sorted[m.Index], indexes: m.MatchedIndexes})
+		}
+	}
+	cp.selected = 0
+	cp.scrollOffset = 0
+}
+
+// ensureVisible scrolls the selected candidate into view.
+func (cp *CompletionPopup) ensureVisible() {
+	if cp.selected < cp.scrollOffset {
+		cp.scrollOffset = cp.selected
+	}
+	if cp.selected >= cp.scrollOffset+completionMaxItems {
+		cp.scrollOffset = cp.selected - completionMaxItems + 1
+	}
+}
+
+// View renders the popup.
+func (cp *CompletionPopup) View() string {
+	if !cp.visible || len(cp.filtered) == 0 {
+		return ""
+	}
+
+	end := min(cp.scrollOffset+completionMaxItems, len(cp.filtered))
+	visible := cp.filtered[cp.scrollOffset:end]
+
+	// As wide as the widest visible label and detail
+	width := 0
+	for _, m := range visible {
+		w := lipgloss.Width(m.item.Label) + 2
+		if m.item.Detail != "" {
+			w += lipgloss.Width(m.item.Detail) + 2
+		}
+		width = max(width, w)
+	}
+	width = min(width, completionMaxWidth)
+
+	lines := make([]string, len(visible))
+	for i, m := range visible {
+		lines[i] = cp.renderItem(m, width, cp.scrollOffset+i == cp.selected)
+	}
+	return cp.popupStyle.Render(strings.Join(lines, "\n"))
+}
+
+// renderItem renders one candidate with its matched characters
+// highlighted and its detail right-aligned.
+func (cp *CompletionPopup) renderItem(m completionMatch, width int, selected bool) string {
+	style := cp.itemStyle
+	if selected {
+		style = cp.selectedStyle
+	}
+
+	label := truncate(m.item.Label, width-2)
+	detail := ""
+	if m.item.Detail != "" {
+		if room := width - 2 - lipgloss.Width(label) - 2; room > 3 {
+			detail = truncate(m.item.Detail, room)
+		}
+	}
+
+	matched := make(map[int]bool, len(m.indexes))
+	for _, i := range m.indexes {
+		matched[i] = true
+	}
+	var b strings.Builder
+	b.WriteString(style.Render(" "))
+	for i, r := range label {
+		if matched[i] {
+			b.WriteString(cp.matchStyle.Inherit(style).Render(string(r)))
+		} else {
+			b.WriteString(style.Render(string(r)))
+		}
+	}
+
+	padding := width - 1 - lipgloss.Width(label) - lipgloss.Width(detail)
+	b.WriteString(style.Render(strings.Repeat(" ", max(padding-1, 0))))
+	if detail != "" {
+		b.WriteString(cp.detailStyle.Inherit(style).Render(detail))
+	}
+	b.WriteString(style.Render(" "))
+	return b.String()
+}
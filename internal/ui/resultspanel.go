@@ -0,0 +1,495 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ResultsPanelHeight is the height of the results panel including its title.
+const ResultsPanelHeight = 12
+
+// ResultMatch is a match shown in the results panel.
+type ResultMatch struct {
+	Line  int // 0-based line number
+	Start int // Rune column of the match start
+	End   int // Rune column after the match
+	Text  string
+
+	// Replace preview
+	Replacement string
+	Excluded    bool
+}
+
+// ResultFile groups the matches of one file.
+type ResultFile struct {
+	Path      string
+	ID        string // Identifies the file to the caller if Path is only a name
+	Matches   []ResultMatch
+	Collapsed bool
+	Note      string // Shown after the match count, e.g. an error
+}
+
+// included returns the number of matches not excluded from a replace.
+func (f *ResultFile) included() int {
+	n := 0
+	for _, m := range f.Matches {
+		if !m.Excluded {
+			n++
+		}
+	}
+	return n
+}
+
+// ResultsMode is what the results panel lists and what Alt+Enter does with
+// the included files.
+type ResultsMode int
+
+const (
+	ResultsSearch   ResultsMode = iota // Matches of a search
+	ResultsReplace                     // Replace preview, applied by Alt+Enter
+	ResultsRecovery                    // Recovered files, opened by Alt+Enter
+	ResultsCompare                     // Changes against the disk, reloaded by Alt+Enter
+)
+
+// resultRow is a visible row: a file header (match == -1) or a match.
+type resultRow struct {
+	file  int
+	match int
+}
+
+// ResultsPanel lists search results grouped by file below the editor.
+type ResultsPanel struct {
+	visible bool
+	width   int
+
+	title   string
+	status  string
+	root    string // Paths are shown relative to root
+	files   []*ResultFile
+	mode    ResultsMode
+	preview bool // Matches are shown as replacements that can be excluded
+
+	// Preview of whole files, such as recovered buffers, and what Alt+Enter
+	// does with them
+	wholeFiles bool
+	applyLabel string
+
+	// Selection
+	selectedIndex int
+	scrollOffset  int
+
+	// Styles
+	titleStyle    lipgloss.Style
+	fileStyle     lipgloss.Style
+	countStyle    lipgloss.Style
+	lineNumStyle  lipgloss.Style
+	itemStyle     lipgloss.Style
+	matchStyle    lipgloss.Style
+	removedStyle  lipgloss.Style
+	insertedStyle lipgloss.Style
+	excludedStyle lipgloss.Style
+	selectedStyle lipgloss.Style
+	hintStyle     lipgloss.Style
+}
+
+// NewResultsPanel creates a new results panel.
+func NewResultsPanel() *ResultsPanel {
+	return &ResultsPanel{
+		titleStyle: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("252")).
+			Background(lipgloss.Color("238")),
+		fileStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("117")).
+			Bold(true),
+		countStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("245")),
+		lineNumStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")),
+		itemStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("252")),
+		matchStyle: lipgloss.NewStyle().
+			Background(lipgloss.Color("58")).
+			Foreground(lipgloss.Color("230")),
+		removedStyle: lipgloss.NewStyle().
+			Background(lipgloss.Color("52")).
+			Foreground(lipgloss.Color("210")).
+			Strikethrough(true),
+		insertedStyle: lipgloss.NewStyle().
+			Background(lipgloss.Color("22")).
+			Foreground(lipgloss.Color("120")),
+		excludedStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")),
+		selectedStyle: lipgloss.NewStyle().
+			Background(lipgloss.Color("62")).
+			Foreground(lipgloss.Color("230")),
+		hintStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")),
+	}
+}
+
+// SetWidth sets the panel width.
+func (p *ResultsPanel) SetWidth(width int) {
+	p.width = width
+}
+
+// Height returns the panel height (0 when hidden).
+func (p *ResultsPanel) Height() int {
+	if !p.visible {
+		return 0
+	}
+	return ResultsPanelHeight
+}
+
+// Show shows the panel.
+func (p *ResultsPanel) Show() {
+	p.visible = true
+}
+
+// Hide hides the panel.
+func (p *ResultsPanel) Hide() {
+	p.visible = false
+}
+
+// IsVisible returns whether the panel is visible.
+func (p *ResultsPanel) IsVisible() bool {
+	return p.visible
+}
+
+// Clear removes all results and starts a new result list. Except in
+// ResultsSearch mode, matches are shown as replacements that can be
+// excluded; recovered and compared files only as whole files.
+func (p *ResultsPanel) Clear(title, root string, mode ResultsMode) {
+	p.title = title
+	p.root = root
+	p.mode = mode
+	p.preview = mode != ResultsSearch
+	p.wholeFiles = mode == ResultsRecovery || mode == ResultsCompare
+	switch mode {
+	case ResultsRecovery:
+		p.applyLabel = "Recover"
+	case ResultsCompare:
+		p.applyLabel = "Reload"
+	default:
+		p.applyLabel = "Replace"
+	}
+	p.status = ""
+	p.files = nil
+	p.selectedIndex = 0
+	p.scrollOffset = 0
+}
+
+// Mode returns what the panel lists.
+func (p *ResultsPanel) Mode() ResultsMode {
+	return p.mode
+}
+
+// AddFile appends the matches of a file.
+func (p *ResultsPanel) AddFile(file ResultFile) {
+	p.files = append(p.files, &file)
+}
+
+// SetStatus sets the text shown next to the title (e.g. the match count).
+func (p *ResultsPanel) SetStatus(status string) {
+	p.status = status
+}
+
+// MatchCount returns the number of matches and files listed.
+func (p *ResultsPanel) MatchCount() (matches, files int) {
+	for _, f := range p.files {
+		matches += len(f.Matches)
+	}
+	return matches, len(p.files)
+}
+
+// ToggleSelected includes or excludes the selected match from the replace.
+// On a file, all of its matches are toggled together.
+func (p *ResultsPanel) ToggleSelected() {
+	rows := p.rows()
+	if !p.preview || p.selectedIndex < 0 || p.selectedIndex >= len(rows) {
+		return
+	}
+
+	row := rows[p.selectedIndex]
+	file := p.files[row.file]
+	if row.match >= 0 && !p.wholeFiles {
+		file.Matches[row.match].Excluded = !file.Matches[row.match].Excluded
+		return
+	}
+
+	exclude := file.included() > 0
+	for i := range file.Matches {
+		file.Matches[i].Excluded = exclude
+	}
+}
+
+// Included returns the files with the matches that are not excluded.
+// Files without included matches are left out.
+func (p *ResultsPanel) Included() []ResultFile {
+	var files []ResultFile
+	for _, f := range p.files {
+		file := ResultFile{Path: f.Path, ID: f.ID}
+		for _, m := range f.Matches {
+			if !m.Excluded {
+				file.Matches = append(file.Matches, m)
+			}
+		}
+		if len(file.Matches) > 0 {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// rows returns the visible rows.
+func (p *ResultsPanel) rows() []resultRow {
+	var rows []resultRow
+	for i, f := range p.files {
+		rows = append(rows, resultRow{file: i, match: -1})
+		if f.Collapsed {
+			continue
+		}
+		for j := range f.Matches {
+			rows = append(rows, resultRow{file: i, match: j})
+		}
+	}
+	return rows
+}
+
+// contentHeight returns the number of rows below the title.
+func (p *ResultsPanel) contentHeight() int {
+	return ResultsPanelHeight - 2 // Title and hint
+}
+
+// MoveUp moves the selection up.
+func (p *ResultsPanel) MoveUp() {
+	if p.selectedIndex > 0 {
+		p.selectedIndex--
+		p.ensureVisible()
+	}
+}
+
+// MoveDown moves the selection down.
+func (p *ResultsPanel) MoveDown() {
+	if p.selectedIndex < len(p.rows())-1 {
+		p.selectedIndex++
+		p.ensureVisible()
+	}
+}
+
+// ensureVisible ensures the selected row is visible.
+func (p *ResultsPanel) ensureVisible() {
+	height := p.contentHeight()
+	if p.selectedIndex < p.scrollOffset {
+		p.scrollOffset = p.selectedIndex
+	}
+	if p.selectedIndex >= p.scrollOffset+height {
+		p.scrollOffset = p.selectedIndex - height + 1
+	}
+}
+
+// Enter toggles the selected file or returns the selected match.
+// ok is false if a file header was selected.
+func (p *ResultsPanel) Enter() (path string, match ResultMatch, ok bool) {
+	rows := p.rows()
+	if p.selectedIndex < 0 || p.selectedIndex >= len(rows) {
+		return "", match, false
+	}
+
+	row := rows[p.selectedIndex]
+	file := p.files[row.file]
+	if row.match < 0 {
+		file.Collapsed = !file.Collapsed
+		return "", match, false
+	}
+	return file.Path, file.Matches[row.match], true
+}
+
+// SelectedID returns the ID of the file of the selected row.
+func (p *ResultsPanel) SelectedID() string {
+	rows := p.rows()
+	if p.selectedIndex < 0 || p.selectedIndex >= len(rows) {
+		return ""
+	}
+	return p.files[rows[p.selectedIndex].file].ID
+}
+
+// HandleClick selects the row at y (relative to the panel top) and behaves
+// like Enter.
+func (p *ResultsPanel) HandleClick(y int) (path string, match ResultMatch, ok bool) {
+	index := p.scrollOffset + y - 1 // Title row
+	if y < 1 || index >= len(p.rows()) {
+		return "", match, false
+	}
+	p.selectedIndex = index
+	return p.Enter()
+}
+
+// ScrollUp scrolls the panel up.
+func (p *ResultsPanel) ScrollUp(amount int) {
+	p.scrollOffset = max(p.scrollOffset-amount, 0)
+}
+
+// ScrollDown scrolls the panel down.
+func (p *ResultsPanel) ScrollDown(amount int) {
+	maxOffset := max(len(p.rows())-p.contentHeight(), 0)
+	p.scrollOffset = min(p.scrollOffset+amount, maxOffset)
+}
+
+// View renders the panel.
+func (p *ResultsPanel) View() string {
+	if !p.visible {
+		return ""
+	}
+
+	var lines []string
+
+	// Title
+	title := " " + p.title
+	if p.status != "" {
+		title += "  " + p.status
+	}
+	lines = append(lines, p.titleStyle.Width(p.width).Render(truncate(title, p.width)))
+
+	// Results
+	rows := p.rows()
+	height := p.contentHeight()
+	for i := p.scrollOffset; i < len(rows) && i < p.scrollOffset+height; i++ {
+		lines = append(lines, p.renderRow(rows[i], i == p.selectedIndex))
+	}
+	for len(lines) < ResultsPanelHeight-1 {
+		lines = append(lines, "")
+	}
+
+	// Hint at bottom
+	hint := "Enter: Open  Esc: Close"
+	if p.preview {
+		hint = "Space: Include/Exclude  Enter: Open  Alt+Enter: " + p.applyLabel + "  Esc: Close"
+	}
+	lines = append(lines, p.hintStyle.Width(p.width).Render(truncate(hint, p.width)))
+
+	return strings.Join(lines, "\n")
+}
+
+// renderRow renders a file header or a match line.
+func (p *ResultsPanel) renderRow(row resultRow, selected bool) string {
+	file := p.files[row.file]
+
+	if row.match < 0 {
+		icon := "- "
+		if file.Collapsed {
+			icon = "+ "
+		}
+		name := file.Path
+		if rel, err := filepath.Rel(p.root, file.Path); err == nil && p.root != "" {
+			name = rel
+		}
+		count := fmt.Sprintf(" (%d)", len(file.Matches))
+		if p.preview {
+			icon += checkbox(file.included(), len(file.Matches)) + " "
+			count = fmt.Sprintf(" (%d/%d)", file.included(), len(file.Matches))
+		}
+		if file.Note != "" {
+			count += " " + file.Note
+		}
+		name = truncate(icon+name, p.width-len([]rune(count)))
+
+		if selected {
+			return p.selectedStyle.Width(p.width).Render(name + count)
+		}
+		return p.fileStyle.Render(name) + p.countStyle.Render(count)
+	}
+
+	match := file.Matches[row.match]
+	lineNum := fmt.Sprintf("    %4d: ", match.Line+1)
+	if p.preview {
+		included := 1
+		if match.Excluded {
+			included = 0
+		}
+		lineNum = fmt.Sprintf("    %s %4d: ", checkbox(included, 1), match.Line+1)
+	}
+
+	width := p.width - len(lineNum)
+	if p.preview && !match.Excluded {
+		// Leave room for the inserted text next to the removed match
+		width -= len([]rune(match.Replacement))
+	}
+	before, text, after := previewParts(match, width)
+
+	switch {
+	case selected:
+		if p.preview && !match.Excluded {
+			text += match.Replacement
+		}
+		return p.selectedStyle.Width(p.width).Render(lineNum + before + text + after)
+	case p.preview && match.Excluded:
+		return p.excludedStyle.Render(lineNum + before + text + after)
+	case p.preview:
+		return p.lineNumStyle.Render(lineNum) +
+			p.itemStyle.Render(before) +
+			p.removedStyle.Render(text) +
+			p.insertedStyle.Render(match.Replacement) +
+			p.itemStyle.Render(after)
+	}
+	return p.lineNumStyle.Render(lineNum) +
+		p.itemStyle.Render(before) +
+		p.matchStyle.Render(text) +
+		p.itemStyle.Render(after)
+}
+
+// checkbox renders the include state of n matches of which included are
+// part of the replace.
+func checkbox(included, n int) string {
+	switch included {
+	case 0:
+		return "[ ]"
+	case n:
+		return "[x]"
+	default:
+		return "[-]"
+	}
+}
+
+// previewParts splits a match line into the text before, of and after the
+// match, trimmed to width with the match kept in view.
+func previewParts(match ResultMatch, width int) (before, text, after string) {
+	runes := []rune(strings.ReplaceAll(match.Text, "\t", " "))
+	start := min(match.Start, len(runes))
+	end := min(match.End, len(runes))
+
+	// Skip indentation, and scroll long lines so the match is visible
+	from := 0
+	for from < start && runes[from] == ' ' {
+		from++
+	}
+	if width > 0 && end-from > width {
+		from = max(end-width, from)
+		if from > start {
+			from = start
+		}
+	}
+
+	to := len(runes)
+	if width > 0 && to-from > width {
+		to = from + width
+	}
+	end = min(end, to)
+
+	return string(runes[from:start]), string(runes[start:end]), string(runes[end:to])
+}
+
+// truncate shortens s to at most width runes.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if width < 0 || len(runes) <= width {
+		return s
+	}
+	if width > 3 {
+		return string(runes[:width-3]) + "..."
+	}
+	return string(runes[:width])
+}
@@ -16,6 +16,8 @@ const (
 	SearchModeGoToLine
 	SearchModeSaveAs
 	SearchModeOpen
+	SearchModeFindInFiles
+	SearchModeReplaceInFiles
 )
 
 // SearchBar provides find and replace functionality.
@@ -35,6 +37,7 @@ type SearchBar struct {
 	// Match info
 	currentMatch int
 	totalMatches int
+	errMsg       string // Invalid search pattern
 
 	width int
 
@@ -46,6 +49,7 @@ type SearchBar struct {
 	buttonStyle   lipgloss.Style
 	activeStyle   lipgloss.Style
 	inactiveStyle lipgloss.Style
+	errorStyle    lipgloss.Style
 }
 
 // NewSearchBar creates a new search bar.
@@ -78,6 +82,9 @@ func NewSearchBar() *SearchBar {
 			Background(lipgloss.Color("239")).
 			Foreground(lipgloss.Color("245")).
 			Padding(0, 1),
+		errorStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("196")).
+			PaddingLeft(1),
 	}
 }
 
@@ -118,6 +125,22 @@ func (s *SearchBar) ShowSaveAs(currentPath string) {
 	s.cursorPos = len(currentPath)
 }
 
+// ShowFindInFiles shows the search bar in find-in-files mode.
+func (s *SearchBar) ShowFindInFiles() {
+	s.visible = true
+	s.mode = SearchModeFindInFiles
+	s.focusReplace = false
+	s.cursorPos = len(s.searchInput)
+}
+
+// ShowReplaceInFiles shows the search bar in replace-in-files mode.
+func (s *SearchBar) ShowReplaceInFiles() {
+	s.visible = true
+	s.mode = SearchModeReplaceInFiles
+	s.focusReplace = false
+	s.cursorPos = len(s.searchInput)
+}
+
 // ShowOpen shows the search bar in open-file mode.
 func (s *SearchBar) ShowOpen() {
 	s.visible = true
@@ -198,9 +221,14 @@ func (s *SearchBar) currentInput() string {
 	return s.searchInput
 }
 
+// hasReplaceField returns true in the modes with a replace field.
+func (s *SearchBar) hasReplaceField() bool {
+	return s.mode == SearchModeReplace || s.mode == SearchModeReplaceInFiles
+}
+
 // Tab switches focus between search and replace fields.
 func (s *SearchBar) Tab() {
-	if s.mode == SearchModeReplace {
+	if s.hasReplaceField() {
 		s.focusReplace = !s.focusReplace
 		if s.focusReplace {
 			s.cursorPos = len(s.replaceInput)
@@ -238,6 +266,37 @@ func (s *SearchBar) ToggleCaseSensitive() {
 	s.caseSensitive = !s.caseSensitive
 }
 
+// IsWholeWord returns whether only whole words match.
+func (s *SearchBar) IsWholeWord() bool {
+	return s.wholeWord
+}
+
+// ToggleWholeWord toggles whole-word matching.
+func (s *SearchBar) ToggleWholeWord() {
+	s.wholeWord = !s.wholeWord
+}
+
+// IsRegex returns whether the search text is a regular expression.
+func (s *SearchBar) IsRegex() bool {
+	return s.regex
+}
+
+// ToggleRegex toggles regular expression search.
+func (s *SearchBar) ToggleRegex() {
+	s.regex = !s.regex
+}
+
+// IsReplaceFocused returns whether the replace field has focus.
+func (s *SearchBar) IsReplaceFocused() bool {
+	return s.hasReplaceField() && s.focusReplace
+}
+
+// SetError shows an error (e.g. an invalid pattern) instead of the match
+// count. An empty message clears it.
+func (s *SearchBar) SetError(msg string) {
+	s.errMsg = msg
+}
+
 // SetMatchInfo sets the current match information.
 func (s *SearchBar) SetMatchInfo(current, total int) {
 	s.currentMatch = current
@@ -271,12 +330,14 @@ func (s *SearchBar) View() string {
 	switch s.mode {
 	case SearchModeGoToLine:
 		return s.renderGoToLine()
-	case SearchModeReplace:
+	case SearchModeReplace, SearchModeReplaceInFiles:
 		return s.renderReplace()
 	case SearchModeSaveAs:
 		return s.renderSaveAs()
 	case SearchModeOpen:
 		return s.renderOpen()
+	case SearchModeFindInFiles:
+		return s.renderFindInFiles()
 	default:
 		return s.renderFind()
 	}
@@ -298,22 +359,10 @@ func (s *SearchBar) renderFind() string {
 	parts = append(parts, s.inputStyle.Width(inputWidth).Render(input))
 
 	// Match count
-	matchInfo := ""
-	if s.searchInput != "" {
-		if s.totalMatches > 0 {
-			matchInfo = fmt.Sprintf("%d of %d", s.currentMatch, s.totalMatches)
-		} else {
-			matchInfo = "No results"
-		}
-	}
-	parts = append(parts, s.matchStyle.Render(matchInfo))
+	parts = append(parts, s.renderMatchInfo())
 
 	// Options
-	caseBtnStyle := s.inactiveStyle
-	if s.caseSensitive {
-		caseBtnStyle = s.activeStyle
-	}
-	parts = append(parts, caseBtnStyle.Render("Aa"))
+	parts = append(parts, s.renderOptions()...)
 
 	// Hints
 	parts = append(parts, s.labelStyle.Render("  Enter: Next  Shift+Enter: Prev  Esc: Close"))
@@ -322,13 +371,41 @@ func (s *SearchBar) renderFind() string {
 	return s.barStyle.Width(s.width).Render(content)
 }
 
-// renderReplace renders the find and replace bar.
+// renderFindInFiles renders the find-in-files bar.
+func (s *SearchBar) renderFindInFiles() string {
+	var parts []string
+
+	parts = append(parts, s.labelStyle.Render("Find in Files:"))
+
+	input := s.searchInput
+	if s.cursorPos <= len(input) {
+		input = input[:s.cursorPos] + "|" + input[s.cursorPos:]
+	}
+	parts = append(parts, s.inputStyle.Width(30).Render(input))
+
+	if s.errMsg != "" {
+		parts = append(parts, s.errorStyle.Render(s.errMsg))
+	}
+	parts = append(parts, s.renderOptions()...)
+	parts = append(parts, s.labelStyle.Render("  Enter: Search  Esc: Close"))
+
+	content := strings.Join(parts, " ")
+	return s.barStyle.Width(s.width).Render(content)
+}
+
+// renderReplace renders the find and replace bar, also used for replace
+// in files.
 func (s *SearchBar) renderReplace() string {
 	var lines []string
+	inFiles := s.mode == SearchModeReplaceInFiles
 
 	// First line: Find
 	var findParts []string
-	findParts = append(findParts, s.labelStyle.Render("Find:   "))
+	if inFiles {
+		findParts = append(findParts, s.labelStyle.Render("Find in Files:   "))
+	} else {
+		findParts = append(findParts, s.labelStyle.Render("Find:   "))
+	}
 
 	searchInput := s.searchInput
 	if !s.focusReplace && s.cursorPos <= len(searchInput) {
@@ -340,22 +417,23 @@ func (s *SearchBar) renderReplace() string {
 	}
 	findParts = append(findParts, inputStyle.Width(30).Render(searchInput))
 
-	// Match info
-	matchInfo := ""
-	if s.searchInput != "" {
-		if s.totalMatches > 0 {
-			matchInfo = fmt.Sprintf("%d of %d", s.currentMatch, s.totalMatches)
-		} else {
-			matchInfo = "No results"
-		}
+	// Match info and options
+	if !inFiles {
+		findParts = append(findParts, s.renderMatchInfo())
+	} else if s.errMsg != "" {
+		findParts = append(findParts, s.errorStyle.Render(s.errMsg))
 	}
-	findParts = append(findParts, s.matchStyle.Render(matchInfo))
+	findParts = append(findParts, s.renderOptions()...)
 
 	lines = append(lines, strings.Join(findParts, " "))
 
 	// Second line: Replace
 	var replaceParts []string
-	replaceParts = append(replaceParts, s.labelStyle.Render("Replace:"))
+	if inFiles {
+		replaceParts = append(replaceParts, s.labelStyle.Render("Replace in Files:"))
+	} else {
+		replaceParts = append(replaceParts, s.labelStyle.Render("Replace:"))
+	}
 
 	replaceInput := s.replaceInput
 	if s.focusReplace && s.cursorPos <= len(replaceInput) {
@@ -368,8 +446,12 @@ func (s *SearchBar) renderReplace() string {
 	replaceParts = append(replaceParts, replaceStyle.Width(30).Render(replaceInput))
 
 	// Buttons
-	replaceParts = append(replaceParts, s.buttonStyle.Render("Replace"))
-	replaceParts = append(replaceParts, s.buttonStyle.Render("Replace All"))
+	if inFiles {
+		replaceParts = append(replaceParts, s.buttonStyle.Render("Enter: Preview"))
+	} else {
+		replaceParts = append(replaceParts, s.buttonStyle.Render("Enter: Replace"))
+		replaceParts = append(replaceParts, s.buttonStyle.Render("Alt+Enter: Replace All"))
+	}
 
 	lines = append(lines, strings.Join(replaceParts, " "))
 
@@ -377,6 +459,42 @@ func (s *SearchBar) renderReplace() string {
 	return s.barStyle.Width(s.width).Render(content)
 }
 
+// renderMatchInfo renders the match count or the pattern error.
+func (s *SearchBar) renderMatchInfo() string {
+	if s.errMsg != "" {
+		return s.errorStyle.Render(s.errMsg)
+	}
+
+	matchInfo := ""
+	if s.searchInput != "" {
+		if s.totalMatches > 0 && s.currentMatch == 0 {
+			// The cursor has moved away from the matches
+			matchInfo = fmt.Sprintf("? of %d", s.totalMatches)
+		} else if s.totalMatches > 0 {
+			matchInfo = fmt.Sprintf("%d of %d", s.currentMatch, s.totalMatches)
+		} else {
+			matchInfo = "No results"
+		}
+	}
+	return s.matchStyle.Render(matchInfo)
+}
+
+// renderOptions renders the case, whole-word and regex toggles
+// (Alt+C, Alt+W, Alt+R).
+func (s *SearchBar) renderOptions() []string {
+	toggle := func(label string, on bool) string {
+		if on {
+			return s.activeStyle.Render(label)
+		}
+		return s.inactiveStyle.Render(label)
+	}
+	return []string{
+		toggle("Aa", s.caseSensitive),
+		toggle("ab", s.wholeWord),
+		toggle(".*", s.regex),
+	}
+}
+
 // renderGoToLine renders the go-to-line bar.
 func (s *SearchBar) renderGoToLine() string {
 	var parts []string
@@ -436,7 +554,7 @@ func (s *SearchBar) Height() int {
 	if !s.visible {
 		return 0
 	}
-	if s.mode == SearchModeReplace {
+	if s.hasReplaceField() {
 		return 2
 	}
 	return 1
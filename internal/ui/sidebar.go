@@ -1,7 +1,11 @@
 package ui
 
 import (
+	"fmt"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -21,12 +25,50 @@ type OpenTabInfo struct {
 	Active   bool
 }
 
+// SidebarMode selects what the sidebar shows.
+type SidebarMode int
+
+const (
+	SidebarExplorer SidebarMode = iota
+	SidebarProblems
+	SidebarUndoTree
+)
+
+// ProblemInfo is a diagnostic listed in the Problems view.
+type ProblemInfo struct {
+	Path     string
+	Line     int // 0-indexed
+	Column   int
+	Severity MessageType
+	Message  string
+	Source   string
+}
+
+// UndoStateInfo is a state listed in the Undo Tree view.
+type UndoStateInfo struct {
+	Seq     int       // Creation order, 0 for the original text
+	Parent  int       // Seq of the state it was made from, -1 for the original text
+	Time    time.Time // Zero for the original text
+	Label   string
+	Current bool
+	Saved   bool
+}
+
+// problemRow is a line of the Problems view: a file header (problem -1)
+// or a problem.
+type problemRow struct {
+	path    string
+	count   int // Problems of the file, for headers
+	problem int
+}
+
 // Sidebar represents the file explorer sidebar.
 type Sidebar struct {
 	fileTree *FileTree
 	width    int
 	height   int
 	visible  bool
+	mode     SidebarMode
 
 	// Selection
 	selectedIndex int
@@ -40,6 +82,17 @@ type Sidebar struct {
 	// Modified files tracking
 	modifiedPaths map[string]bool
 
+	// Problems view, grouped by file
+	problems      []ProblemInfo
+	problemRows   []problemRow
+	problemRow    int // Selected row
+	problemScroll int
+
+	// Undo Tree view, newest state first
+	undoStates []UndoStateInfo
+	undoRow    int // Selected row
+	undoScroll int
+
 	// Styles
 	titleStyle    lipgloss.Style
 	itemStyle     lipgloss.Style
@@ -88,6 +141,37 @@ func (s *Sidebar) LoadDirectory(path string) error {
 	return s.fileTree.LoadDirectory(path)
 }
 
+// RootPath returns the directory loaded into the file tree, or "" if none.
+func (s *Sidebar) RootPath() string {
+	if s.fileTree.Root == nil {
+		return ""
+	}
+	return s.fileTree.Root.Path
+}
+
+// ExpandedPaths returns the expanded directories of the file tree, sorted.
+func (s *Sidebar) ExpandedPaths() []string {
+	paths := make([]string, 0, len(s.fileTree.Expanded))
+	for path := range s.fileTree.Expanded {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// SetExpandedPaths expands the given directories of the file tree. Paths
+// that are no longer directories in the tree are ignored.
+func (s *Sidebar) SetExpandedPaths(paths []string) {
+	// Parents first, so their children are loaded when a child is expanded
+	sorted := append([]string(nil), paths...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) < len(sorted[j]) })
+	for _, path := range sorted {
+		if node := s.fileTree.FindNode(path); node != nil && node.IsDir {
+			s.fileTree.Expand(path)
+		}
+	}
+}
+
 // SetSize sets the sidebar dimensions.
 func (s *Sidebar) SetSize(width, height int) {
 	if width >= MinSidebarWidth {
@@ -145,8 +229,151 @@ func (s *Sidebar) IsVisible() bool {
 	return s.visible
 }
 
+// SetMode switches between the file explorer, the Problems view and the
+// Undo Tree view.
+func (s *Sidebar) SetMode(mode SidebarMode) {
+	s.mode = mode
+}
+
+// Mode returns what the sidebar shows.
+func (s *Sidebar) Mode() SidebarMode {
+	return s.mode
+}
+
+// SetProblems sets the problems of the Problems view, sorted by file. The
+// selection stays on the same row where possible.
+func (s *Sidebar) SetProblems(problems []ProblemInfo) {
+	s.problems = problems
+	s.problemRows = s.problemRows[:0]
+	for i, p := range problems {
+		if i == 0 || problems[i-1].Path != p.Path {
+			s.problemRows = append(s.problemRows, problemRow{path: p.Path, problem: -1})
+		}
+		s.problemRows[len(s.problemRows)-1].count++
+		s.problemRows = append(s.problemRows, problemRow{path: p.Path, problem: i})
+	}
+	s.problemRow = min(s.problemRow, max(len(s.problemRows)-1, 0))
+	s.problemScroll = min(s.problemScroll, max(len(s.problemRows)-1, 0))
+	if len(s.problemRows) > 0 && s.problemRows[s.problemRow].problem < 0 {
+		s.problemRow++ // Headers are not selectable
+	}
+}
+
+// SelectedProblem returns the selected problem in the Problems view.
+func (s *Sidebar) SelectedProblem() (ProblemInfo, bool) {
+	if s.problemRow >= len(s.problemRows) {
+		return ProblemInfo{}, false
+	}
+	row := s.problemRows[s.problemRow]
+	if row.problem < 0 {
+		return ProblemInfo{}, false
+	}
+	return s.problems[row.problem], true
+}
+
+// ClickProblem selects the problem at a y position of the Problems view
+// and returns it.
+func (s *Sidebar) ClickProblem(y int) (ProblemInfo, bool) {
+	row := s.problemScroll + y - 1 // Below the title
+	if y == 0 || row >= len(s.problemRows) || s.problemRows[row].problem < 0 {
+		return ProblemInfo{}, false
+	}
+	s.problemRow = row
+	return s.SelectedProblem()
+}
+
+// SetUndoStates sets the states of the Undo Tree view, oldest first. When
+// the current state or the number of states changed, the current state is
+// selected.
+func (s *Sidebar) SetUndoStates(states []UndoStateInfo) {
+	prevCurrent := -1
+	for _, st := range s.undoStates {
+		if st.Current {
+			prevCurrent = st.Seq
+		}
+	}
+	changed := len(states) != len(s.undoStates)
+
+	s.undoStates = s.undoStates[:0]
+	for i := len(states) - 1; i >= 0; i-- {
+		s.undoStates = append(s.undoStates, states[i])
+	}
+	for i, st := range s.undoStates {
+		if st.Current && (changed || st.Seq != prevCurrent) {
+			s.undoRow = i
+			s.moveUndoState(0)
+		}
+	}
+	s.undoRow = min(s.undoRow, max(len(s.undoStates)-1, 0))
+}
+
+// SelectedUndoState returns the selected state in the Undo Tree view.
+func (s *Sidebar) SelectedUndoState() (UndoStateInfo, bool) {
+	if s.undoRow >= len(s.undoStates) {
+		return UndoStateInfo{}, false
+	}
+	return s.undoStates[s.undoRow], true
+}
+
+// ClickUndoState selects the state at a y position of the Undo Tree view
+// and returns it.
+func (s *Sidebar) ClickUndoState(y int) (UndoStateInfo, bool) {
+	row := s.undoScroll + y - 1 // Below the title
+	if y == 0 || row >= len(s.undoStates) {
+		return UndoStateInfo{}, false
+	}
+	s.undoRow = row
+	return s.SelectedUndoState()
+}
+
+// moveUndoState moves the Undo Tree view selection by delta rows and keeps
+// it in view.
+func (s *Sidebar) moveUndoState(delta int) {
+	s.undoRow = max(min(s.undoRow+delta, len(s.undoStates)-1), 0)
+
+	contentHeight := max(s.height-2, 1)
+	if s.undoRow < s.undoScroll {
+		s.undoScroll = s.undoRow
+	}
+	if s.undoRow >= s.undoScroll+contentHeight {
+		s.undoScroll = s.undoRow - contentHeight + 1
+	}
+}
+
+// moveProblem moves the Problems view selection by delta problems,
+// skipping file headers.
+func (s *Sidebar) moveProblem(delta int) {
+	for row := s.problemRow + delta; row >= 0 && row < len(s.problemRows); row += delta {
+		if s.problemRows[row].problem >= 0 {
+			s.problemRow = row
+			break
+		}
+	}
+
+	// Keep the selection and its file header in view
+	contentHeight := max(s.height-2, 1)
+	top := s.problemRow
+	if top > 0 && s.problemRows[top-1].problem < 0 {
+		top--
+	}
+	if top < s.problemScroll {
+		s.problemScroll = top
+	}
+	if s.problemRow >= s.problemScroll+contentHeight {
+		s.problemScroll = s.problemRow - contentHeight + 1
+	}
+}
+
 // MoveUp moves selection up.
 func (s *Sidebar) MoveUp() {
+	switch s.mode {
+	case SidebarProblems:
+		s.moveProblem(-1)
+		return
+	case SidebarUndoTree:
+		s.moveUndoState(-1)
+		return
+	}
 	if s.selectedIndex > 0 {
 		s.selectedIndex--
 		s.ensureVisible()
@@ -155,6 +382,14 @@ func (s *Sidebar) MoveUp() {
 
 // MoveDown moves selection down.
 func (s *Sidebar) MoveDown() {
+	switch s.mode {
+	case SidebarProblems:
+		s.moveProblem(1)
+		return
+	case SidebarUndoTree:
+		s.moveUndoState(1)
+		return
+	}
 	nodes := s.fileTree.GetVisibleNodes()
 	if s.selectedIndex < len(nodes)-1 {
 		s.selectedIndex++
@@ -251,11 +486,18 @@ func (s *Sidebar) View() string {
 	}
 
 	var lines []string
-	contentWidth := s.width - 2 // Account for border
+	contentWidth := s.width - 1 // Account for the right border
 	if contentWidth < 10 {
 		contentWidth = 10
 	}
 
+	switch s.mode {
+	case SidebarProblems:
+		return s.borderStyle.Render(s.problemsView(contentWidth))
+	case SidebarUndoTree:
+		return s.borderStyle.Render(s.undoTreeView(contentWidth))
+	}
+
 	// Title
 	title := s.titleStyle.Width(contentWidth).Render("EXPLORER")
 	lines = append(lines, title)
@@ -380,18 +622,108 @@ func (s *Sidebar) View() string {
 		lines = append(lines, strings.Repeat(" ", contentWidth))
 	}
 
-	// Hint at bottom
-	hint := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("241")).
-		Width(contentWidth).
-		Render("(Ctrl+B)")
-	lines = append(lines, hint)
+	lines = append(lines, s.hint(contentWidth))
 
 	// Join and add border
 	content := strings.Join(lines, "\n")
 	return s.borderStyle.Render(content)
 }
 
+// hint renders the hint line at the bottom.
+func (s *Sidebar) hint(width int) string {
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color("241")).
+		Width(width).
+		Render("(Ctrl+B)")
+}
+
+// problemsView renders the Problems view: each file with its problems
+// below it, marked by severity.
+func (s *Sidebar) problemsView(width int) string {
+	lines := []string{s.titleStyle.Width(width).Render(fmt.Sprintf("PROBLEMS (%d)", len(s.problems)))}
+	if len(s.problems) == 0 {
+		lines = append(lines, s.itemStyle.Width(width).Render("Keine Probleme"))
+	}
+
+	contentHeight := s.height - 2
+	for i := s.problemScroll; i < len(s.problemRows) && i < s.problemScroll+contentHeight; i++ {
+		row := s.problemRows[i]
+		if row.problem < 0 {
+			line := truncate(fmt.Sprintf("%s (%d)", filepath.Base(row.path), row.count), width)
+			lines = append(lines, s.dirStyle.Width(width).Render(line))
+			continue
+		}
+
+		p := s.problems[row.problem]
+		text := fmt.Sprintf(" %d:%d %s", p.Line+1, p.Column+1, strings.Join(strings.Fields(p.Message), " "))
+		text = truncate(text, width-2)
+		text += strings.Repeat(" ", max(width-2-len([]rune(text)), 0))
+		if i == s.problemRow {
+			lines = append(lines, s.selectedStyle.Render(" ●"+text))
+			continue
+		}
+		marker := lipgloss.NewStyle().Foreground(problemColors[p.Severity]).Render(" ●")
+		lines = append(lines, marker+s.itemStyle.Render(text))
+	}
+
+	for len(lines) < s.height-1 {
+		lines = append(lines, strings.Repeat(" ", width))
+	}
+	lines = append(lines, s.hint(width))
+	return strings.Join(lines, "\n")
+}
+
+// undoTreeView renders the Undo Tree view: the states newest first, with
+// the time of their edit. ● marks the current state, ✓ the saved one; a
+// state made from another than the one before it notes where it branched.
+func (s *Sidebar) undoTreeView(width int) string {
+	lines := []string{s.titleStyle.Width(width).Render(fmt.Sprintf("UNDO TREE (%d)", max(len(s.undoStates)-1, 0)))}
+
+	contentHeight := s.height - 2
+	for i := s.undoScroll; i < len(s.undoStates) && i < s.undoScroll+contentHeight; i++ {
+		st := s.undoStates[i]
+		marker := "  "
+		if st.Current {
+			marker = " ●"
+		}
+		saved := " "
+		if st.Saved {
+			saved = "✓"
+		}
+		clock := "        "
+		if !st.Time.IsZero() {
+			clock = st.Time.Format("15:04:05")
+		}
+		text := fmt.Sprintf("%s%3d%s %s %s", marker, st.Seq, saved, clock, st.Label)
+		if st.Parent >= 0 && st.Parent != st.Seq-1 {
+			text += fmt.Sprintf(" (von %d)", st.Parent)
+		}
+		text = truncate(text, width)
+		text += strings.Repeat(" ", max(width-len([]rune(text)), 0))
+		switch {
+		case i == s.undoRow:
+			lines = append(lines, s.selectedStyle.Render(text))
+		case st.Current:
+			lines = append(lines, s.modifiedStyle.Render(text))
+		default:
+			lines = append(lines, s.itemStyle.Render(text))
+		}
+	}
+
+	for len(lines) < s.height-1 {
+		lines = append(lines, strings.Repeat(" ", width))
+	}
+	lines = append(lines, s.hint(width))
+	return strings.Join(lines, "\n")
+}
+
+// problemColors are the marker colors of problem severities.
+var problemColors = map[MessageType]lipgloss.Color{
+	MessageError:   lipgloss.Color("196"),
+	MessageWarning: lipgloss.Color("214"),
+	MessageInfo:    lipgloss.Color("39"),
+}
+
 // HandleClick handles a mouse click at the given y position.
 func (s *Sidebar) HandleClick(y int) string {
 	if y == 0 {
@@ -413,6 +745,14 @@ func (s *Sidebar) HandleClick(y int) string {
 
 // ScrollUp scrolls the sidebar up.
 func (s *Sidebar) ScrollUp(amount int) {
+	switch s.mode {
+	case SidebarProblems:
+		s.problemScroll = max(s.problemScroll-amount, 0)
+		return
+	case SidebarUndoTree:
+		s.undoScroll = max(s.undoScroll-amount, 0)
+		return
+	}
 	s.scrollOffset -= amount
 	if s.scrollOffset < 0 {
 		s.scrollOffset = 0
@@ -421,6 +761,14 @@ func (s *Sidebar) ScrollUp(amount int) {
 
 // ScrollDown scrolls the sidebar down.
 func (s *Sidebar) ScrollDown(amount int) {
+	switch s.mode {
+	case SidebarProblems:
+		s.problemScroll = max(min(s.problemScroll+amount, len(s.problemRows)-(s.height-2)), 0)
+		return
+	case SidebarUndoTree:
+		s.undoScroll = max(min(s.undoScroll+amount, len(s.undoStates)-(s.height-2)), 0)
+		return
+	}
 	nodes := s.fileTree.GetVisibleNodes()
 	maxOffset := len(nodes) - (s.height - 2)
 	if maxOffset < 0 {
@@ -12,14 +12,24 @@ type StatusBar struct {
 	width int
 
 	// Editor info
-	line       int
-	column     int
-	totalLines int
-	language   string
-	encoding   string
-	lineEnding string
-	tabWidth   int
-	version    string
+	line         int
+	column       int
+	totalLines   int
+	language     string
+	encoding     string
+	lineEnding   string
+	tabWidth     int
+	insertSpaces bool
+	version      string
+
+	// Keys typed so far of a pending chord
+	pendingKeys string
+
+	// Problem counts and the diagnostic on the cursor's line
+	errors         int
+	warnings       int
+	diagnostic     string
+	diagnosticType MessageType
 
 	// Message
 	message     string
@@ -44,10 +54,11 @@ const (
 // NewStatusBar creates a new status bar.
 func NewStatusBar() *StatusBar {
 	return &StatusBar{
-		encoding:   "UTF-8",
-		lineEnding: "LF",
-		tabWidth:   4,
-		language:   "plain",
+		encoding:     "UTF-8",
+		lineEnding:   "LF",
+		tabWidth:     4,
+		insertSpaces: true,
+		language:     "plain",
 
 		style: lipgloss.NewStyle().
 			Background(lipgloss.Color("237")).
@@ -86,11 +97,14 @@ func (s *StatusBar) SetEncoding(encoding string) {
 	s.encoding = encoding
 }
 
-// SetLineEnding sets the line ending indicator (LF or CRLF).
+// SetLineEnding sets the line ending indicator (LF, CRLF or CR).
 func (s *StatusBar) SetLineEnding(lineEnding string) {
-	if lineEnding == "\r\n" {
+	switch lineEnding {
+	case "\r\n":
 		s.lineEnding = "CRLF"
-	} else {
+	case "\r":
+		s.lineEnding = "CR"
+	default:
 		s.lineEnding = "LF"
 	}
 }
@@ -100,11 +114,34 @@ func (s *StatusBar) SetTabWidth(tabWidth int) {
 	s.tabWidth = tabWidth
 }
 
+// SetInsertSpaces sets whether the indentation indicator shows spaces or tabs.
+func (s *StatusBar) SetInsertSpaces(insertSpaces bool) {
+	s.insertSpaces = insertSpaces
+}
+
 // SetVersion sets the version string to display.
 func (s *StatusBar) SetVersion(version string) {
 	s.version = version
 }
 
+// SetPendingKeys sets the keys of a pending chord ("" when none is pending).
+func (s *StatusBar) SetPendingKeys(keys string) {
+	s.pendingKeys = keys
+}
+
+// SetProblemCounts sets the number of errors and warnings in all files.
+func (s *StatusBar) SetProblemCounts(errors, warnings int) {
+	s.errors = errors
+	s.warnings = warnings
+}
+
+// SetDiagnostic sets the diagnostic message of the cursor's line ("" when
+// there is none). msgType gives its severity.
+func (s *StatusBar) SetDiagnostic(message string, msgType MessageType) {
+	s.diagnostic = message
+	s.diagnosticType = msgType
+}
+
 // SetMessage sets a temporary message to display.
 func (s *StatusBar) SetMessage(message string, msgType MessageType) {
 	s.message = message
@@ -119,31 +156,68 @@ func (s *StatusBar) ClearMessage() {
 
 // View renders the status bar.
 func (s *StatusBar) View() string {
+	// A pending chord waits for its next key
+	if s.pendingKeys != "" {
+		msg := fmt.Sprintf(" (%s) gedrückt, warte auf nächste Taste...", s.pendingKeys)
+		return s.infoStyle.Width(s.width).Render(msg)
+	}
+
 	// If there's a message, show it
 	if s.message != "" {
 		return s.renderMessage()
 	}
 
-	// Build left side: position info
+	// Build left side: position info and problem counts
 	position := fmt.Sprintf(" Ln %d, Col %d", s.line+1, s.column+1)
+	left := s.style.Render(position)
+	if s.errors > 0 || s.warnings > 0 {
+		left += s.style.Render(" | ") +
+			s.severityStyle(MessageError).Render(fmt.Sprintf("E %d", s.errors)) +
+			s.style.Render(" ") +
+			s.severityStyle(MessageWarning).Render(fmt.Sprintf("W %d", s.warnings))
+	}
 
 	// Build right side: language, encoding, line ending, tab width, version
 	versionStr := ""
 	if s.version != "" {
 		versionStr = " | vex " + s.version
 	}
-	right := fmt.Sprintf("%s | %s | %s | Spaces: %d%s ",
-		s.language, s.encoding, s.lineEnding, s.tabWidth, versionStr)
+	indent := "Spaces"
+	if !s.insertSpaces {
+		indent = "Tab Size"
+	}
+	right := fmt.Sprintf("%s | %s | %s | %s: %d%s ",
+		s.language, s.encoding, s.lineEnding, indent, s.tabWidth, versionStr)
+
+	// The diagnostic of the cursor's line fills the space in between
+	free := s.width - lipgloss.Width(left) - lipgloss.Width(right)
+	middle := ""
+	if s.diagnostic != "" && free > 8 {
+		text := "  " + truncate(strings.Join(strings.Fields(s.diagnostic), " "), free-4)
+		middle = s.severityStyle(s.diagnosticType).Render(text)
+	}
 
 	// Calculate spacing
-	spacing := s.width - lipgloss.Width(position) - lipgloss.Width(right)
+	spacing := free - lipgloss.Width(middle)
 	if spacing < 0 {
 		spacing = 0
 	}
 
-	content := position + strings.Repeat(" ", spacing) + right
+	return left + middle + s.style.Render(strings.Repeat(" ", spacing)+right)
+}
 
-	return s.style.Width(s.width).Render(content)
+// severityStyle returns the status bar style with the text color of a
+// message type.
+func (s *StatusBar) severityStyle(msgType MessageType) lipgloss.Style {
+	switch msgType {
+	case MessageError:
+		return s.style.Foreground(s.errorStyle.GetBackground())
+	case MessageWarning:
+		return s.style.Foreground(s.warningStyle.GetBackground())
+	case MessageInfo:
+		return s.style.Foreground(s.infoStyle.GetBackground())
+	}
+	return s.style
 }
 
 // renderMessage renders the message display.
@@ -0,0 +1,163 @@
+package workspace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/DDZ-DO/vex/internal/editor"
+)
+
+// binarySniffLen is how many leading bytes are checked for NUL bytes to
+// detect binary files, as git does.
+const binarySniffLen = 8000
+
+// maxSearchFileSize skips files too large to be source code.
+const maxSearchFileSize = 8 << 20
+
+// errLimitReached stops a search once the result cap is hit.
+var errLimitReached = errors.New("result limit reached")
+
+// LineMatch is a search match within one line of a file.
+type LineMatch struct {
+	Line  int    // 0-based line number
+	Start int    // Rune column of the match start
+	End   int    // Rune column after the match
+	Text  string // The full line, for previews
+
+	// Replacement is the expanded replacement text when searching with
+	// FindOptions.Replace.
+	Replacement string
+}
+
+// FindOptions controls a find or replace in files.
+type FindOptions struct {
+	// Limit caps the number of matches reported (no limit if <= 0).
+	Limit int
+
+	// Buffers holds the text of open buffers by absolute path. They are
+	// searched instead of the files on disk.
+	Buffers map[string]string
+
+	// Replace expands Replacement for every match, for a replace preview.
+	Replace     bool
+	Replacement string
+}
+
+// FileResult holds the matches found in one file.
+type FileResult struct {
+	Path    string
+	Matches []LineMatch
+
+	// Truncated is set on the last result if matches past the result cap
+	// were left out; the search stops there.
+	Truncated bool
+}
+
+// FindInFiles searches every non-ignored text file below root and streams
+// one FileResult per file with matches. The channel is closed when the
+// search is done, the result limit is reached or ctx is cancelled.
+//
+// Matching is done line by line, so patterns cannot span lines.
+func FindInFiles(ctx context.Context, root string, search *editor.Search, opts FindOptions) <-chan FileResult {
+	results := make(chan FileResult, 16)
+
+	go func() {
+		defer close(results)
+
+		send := func(result FileResult) error {
+			select {
+			case results <- result:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		// full holds the result that reached the limit exactly until it is
+		// known whether more matches follow
+		var full *FileResult
+		total := 0
+		WalkFiles(ctx, root, func(path string) error {
+			content, ok := opts.Buffers[path]
+			if !ok {
+				content, ok = readTextFile(path)
+			}
+			if !ok {
+				return nil
+			}
+			matches := findInText(content, search, opts)
+			if len(matches) == 0 {
+				return nil
+			}
+			if full != nil {
+				full.Truncated = true
+				return errLimitReached
+			}
+
+			result := FileResult{Path: path, Matches: matches}
+			if opts.Limit > 0 && total+len(matches) > opts.Limit {
+				result.Matches = matches[:opts.Limit-total]
+				result.Truncated = true
+			}
+			total += len(result.Matches)
+			if opts.Limit > 0 && total == opts.Limit && !result.Truncated {
+				full = &result
+				return nil
+			}
+
+			if err := send(result); err != nil {
+				return err
+			}
+			if result.Truncated {
+				return errLimitReached
+			}
+			return nil
+		})
+		if full != nil {
+			send(*full)
+		}
+	}()
+
+	return results
+}
+
+// findInText returns the matches in a file's text.
+func findInText(content string, search *editor.Search, opts FindOptions) []LineMatch {
+	var matches []LineMatch
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		for _, m := range search.Matches(line) {
+			match := LineMatch{Line: i, Start: m.Start, End: m.End, Text: line}
+			if opts.Replace {
+				match.Replacement = search.Expand(m, line, opts.Replacement)
+			}
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// readTextFile reads a file if it looks like text. Binary, oversized and
+// unreadable files are skipped.
+func readTextFile(path string) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxSearchFileSize {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || isBinary(data) {
+		return "", false
+	}
+	return string(data), true
+}
+
+// isBinary reports whether data contains a NUL byte near its start.
+func isBinary(data []byte) bool {
+	if len(data) > binarySniffLen {
+		data = data[:binarySniffLen]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
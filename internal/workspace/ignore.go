@@ -0,0 +1,124 @@
+package workspace
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// ignoreRule is a single pattern from a .gitignore file.
+type ignoreRule struct {
+	pattern  string
+	base     string // Directory of the .gitignore, relative to the root
+	negate   bool   // "!pattern" re-includes a path
+	dirOnly  bool   // "pattern/" only matches directories
+	anchored bool   // Patterns containing a slash are relative to base
+}
+
+// ignoreRules is the stack of .gitignore rules that apply to a directory.
+// Later rules take precedence over earlier ones.
+type ignoreRules []ignoreRule
+
+// readIgnoreFile appends the rules of a .gitignore file. base is the
+// slash-separated directory of the file relative to the walk root.
+// A missing file adds nothing.
+func (rules ignoreRules) readIgnoreFile(file, base string) ignoreRules {
+	f, err := os.Open(file)
+	if err != nil {
+		return rules
+	}
+	defer f.Close()
+
+	// Copy so sibling directories don't share appended rules
+	rules = rules[:len(rules):len(rules)]
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, "\\")
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignored reports whether the slash-separated path rel (relative to the walk
+// root) is excluded. The last matching rule decides.
+func (rules ignoreRules) ignored(rel string, isDir bool) bool {
+	for i := len(rules) - 1; i >= 0; i-- {
+		rule := rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.match(rel) {
+			return !rule.negate
+		}
+	}
+	return false
+}
+
+// match checks the rule against a path relative to the walk root.
+func (r ignoreRule) match(rel string) bool {
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	if r.anchored {
+		return matchGlob(r.pattern, rel)
+	}
+	return matchGlob(r.pattern, path.Base(rel))
+}
+
+// matchGlob matches a slash-separated path against a gitignore glob, where
+// "**" matches any number of directories.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range name {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
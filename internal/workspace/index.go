@@ -0,0 +1,38 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+)
+
+// vendorDirs are dependency directories left out of the file index.
+var vendorDirs = map[string]bool{
+	"vendor":       true,
+	"node_modules": true,
+}
+
+// errIndexFull stops the walk once the index limit is reached.
+var errIndexFull = errors.New("index full")
+
+// IndexFiles lists the files below root for quick open, as slash-separated
+// paths relative to root in sorted order. Gitignored files and vendor
+// directories are skipped. At most limit files are listed (0 means no
+// limit); truncated reports whether files were left out.
+func IndexFiles(ctx context.Context, root string, limit int) (files []string, truncated bool, err error) {
+	err = walkFiles(ctx, root, vendorDirs, func(path string) error {
+		if limit > 0 && len(files) >= limit {
+			return errIndexFull
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if errors.Is(err, errIndexFull) {
+		return files, true, nil
+	}
+	return files, false, err
+}
@@ -0,0 +1,84 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DDZ-DO/vex/internal/atomicfile"
+)
+
+// ErrFileChanged is returned when a file no longer has the lines a replace
+// preview was made from.
+var ErrFileChanged = errors.New("file changed since the search")
+
+// ReplaceInFile replaces the given matches of a file on disk with their
+// Replacement text. Every matched line must still read as it did in the
+// preview, otherwise the file is left alone and ErrFileChanged is returned.
+// The file is written atomically.
+func ReplaceInFile(path string, matches []LineMatch) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for _, m := range matches {
+		if m.Line >= len(lines) || strings.TrimSuffix(lines[m.Line], "\r") != m.Text {
+			return ErrFileChanged
+		}
+	}
+
+	for _, m := range Replaced(matches) {
+		if strings.HasSuffix(lines[m.Line], "\r") {
+			lines[m.Line] = m.Text + "\r"
+		} else {
+			lines[m.Line] = m.Text
+		}
+	}
+
+	return atomicfile.Write(path, []byte(strings.Join(lines, "\n")), info.Mode().Perm())
+}
+
+// Replaced returns the matches as they read after replacing them: Text is
+// the new line and Start/End cover the inserted Replacement. Matches on the
+// same line are replaced together.
+func Replaced(matches []LineMatch) []LineMatch {
+	sorted := append([]LineMatch(nil), matches...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Line != sorted[j].Line {
+			return sorted[i].Line < sorted[j].Line
+		}
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	for i := 0; i < len(sorted); {
+		// Rebuild the line of the matches [i, j)
+		j := i
+		runes := []rune(sorted[i].Text)
+		var text strings.Builder
+		pos, col := 0, 0
+		for ; j < len(sorted) && sorted[j].Line == sorted[i].Line; j++ {
+			m := &sorted[j]
+			text.WriteString(string(runes[pos:m.Start]))
+			col += m.Start - pos
+			text.WriteString(m.Replacement)
+			pos = m.End
+			m.Start = col
+			col += utf8.RuneCountInString(m.Replacement)
+			m.End = col
+		}
+		text.WriteString(string(runes[pos:]))
+		for k := i; k < j; k++ {
+			sorted[k].Text = text.String()
+		}
+		i = j
+	}
+	return sorted
+}
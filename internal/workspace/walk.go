@@ -0,0 +1,72 @@
+package workspace
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// WalkFiles calls fn for every file below root that is not excluded by a
+// .gitignore file (or .git/info/exclude). The .git directory itself is
+// always skipped. Files are visited in sorted order, directory by directory.
+// Walking stops early if ctx is cancelled or fn returns an error.
+func WalkFiles(ctx context.Context, root string, fn func(path string) error) error {
+	return walkFiles(ctx, root, nil, fn)
+}
+
+// walkFiles is WalkFiles, additionally skipping directories named in skip.
+func walkFiles(ctx context.Context, root string, skip map[string]bool, fn func(path string) error) error {
+	rules := ignoreRules(nil).readIgnoreFile(filepath.Join(root, ".git", "info", "exclude"), "")
+	return walkDir(ctx, root, "", rules, skip, fn)
+}
+
+// walkDir walks the directory dir, whose slash-separated path relative to
+// the root is rel.
+func walkDir(ctx context.Context, dir, rel string, rules ignoreRules, skip map[string]bool, fn func(path string) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		// Unreadable directories are skipped
+		return nil
+	}
+	rules = rules.readIgnoreFile(filepath.Join(dir, ".gitignore"), rel)
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == ".git" || (skip[name] && entry.IsDir()) {
+			continue
+		}
+
+		childRel := name
+		if rel != "" {
+			childRel = rel + "/" + name
+		}
+		isDir := entry.IsDir()
+		if rules.ignored(childRel, isDir) {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		if isDir {
+			if err := walkDir(ctx, path, childRel, rules, skip, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if err := fn(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}